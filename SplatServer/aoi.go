@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	interestRadius    = 1536.0                 // distance within which players get every update, 24 blocks
+	interestRefresh   = 250 * time.Millisecond // time between working out who is in each player's interest
+	farUpdateInterval = 500 * time.Millisecond // time between updates about players outside it
+)
+
+// sentUpdate is the last position update a client was sent about a player
+type sentUpdate struct {
+	X, Y, Angle float64
+	Health      int
+	Dead        bool
+}
+
+// changedFrom reports whether a player has moved or changed since the update
+func (u sentUpdate) changedFrom(player *ArenaPlayer) bool {
+	return u != sentUpdate{player.X, player.Y, player.Angle, player.Health, player.Dead}
+}
+
+// playerView is what a player's client has been told about the others in its arena
+type playerView struct {
+	interest map[int]bool       // players within the interest radius and in sight
+	sent     map[int]sentUpdate // last update sent about each player
+	checkAt  time.Time          // when interest is next worked out
+	farAt    time.Time          // when players outside it are next updated
+}
+
+// viewLocked returns the player's view, creating it the first time; the caller must
+// hold a.mu
+func (p *ArenaPlayer) viewLocked() *playerView {
+	if p.view == nil {
+		p.view = &playerView{interest: make(map[int]bool), sent: make(map[int]sentUpdate)}
+	}
+	return p.view
+}
+
+// inInterestLocked reports whether a player is close enough to an observer, and in
+// their line of sight, to get every update; the caller must hold a.mu
+func (a *Arena) inInterestLocked(observer, player *ArenaPlayer) bool {
+	if math.Hypot(player.X-observer.X, player.Y-observer.Y) > interestRadius {
+		return false
+	}
+	if a.Geometry != nil {
+		if _, blocked := a.Geometry.TraceSegment(observer.X, observer.Y, player.X, player.Y); blocked {
+			return false
+		}
+	}
+	return true
+}
+
+// refreshInterestLocked works out which players are in an observer's interest, telling
+// their client about players entering and leaving it; the caller must hold a.mu
+func (a *Arena) refreshInterestLocked(observer *ArenaPlayer, view *playerView) {
+	current := make(map[int]bool)
+	for _, player := range a.playersNearLocked(observer.X, observer.Y, interestRadius) {
+		if player != observer && a.inInterestLocked(observer, player) {
+			current[player.PlayerID] = true
+		}
+	}
+
+	for id := range view.interest {
+		if !current[id] {
+			delete(view.interest, id)
+			observer.Conn.Write(BuildInterestPacket(a.ID, id, false).Serialize())
+		}
+	}
+	for id := range current {
+		if !view.interest[id] {
+			view.interest[id] = true
+			delete(view.sent, id)
+			observer.Conn.Write(BuildInterestPacket(a.ID, id, true).Serialize())
+		}
+	}
+	for id := range view.sent {
+		if _, exists := a.Players[id]; !exists {
+			delete(view.sent, id)
+		}
+	}
+}
+
+// sendUpdatesLocked sends each connected player updates about the others that have moved
+// or changed: every tick for those in their interest, and every farUpdateInterval for
+// the rest; the caller must hold a.mu
+func (a *Arena) sendUpdatesLocked(now time.Time) {
+	for _, observer := range a.Players {
+		if observer.Conn == nil {
+			continue
+		}
+		view := observer.viewLocked()
+		if !now.Before(view.checkAt) {
+			a.refreshInterestLocked(observer, view)
+			view.checkAt = now.Add(interestRefresh)
+		}
+
+		send := func(player *ArenaPlayer) {
+			if sent, exists := view.sent[player.PlayerID]; exists && !sent.changedFrom(player) {
+				return
+			}
+			view.sent[player.PlayerID] = sentUpdate{player.X, player.Y, player.Angle, player.Health, player.Dead}
+			observer.Conn.Write(BuildArenaUpdatePacket(a.ID, player).Serialize())
+		}
+		for id := range view.interest {
+			if player, exists := a.Players[id]; exists {
+				send(player)
+			}
+		}
+		if now.Before(view.farAt) {
+			continue
+		}
+		view.farAt = now.Add(farUpdateInterval)
+		for _, player := range a.Players {
+			if player != observer && !view.interest[player.PlayerID] {
+				send(player)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// interestUpdates reads the packets written to a connection since the last call,
+// returning the IDs of players whose updates were sent and interest changes by player
+func interestUpdates(t *testing.T, conn *captureConn) ([]int, map[int]bool) {
+	t.Helper()
+	var updated []int
+	changes := make(map[int]bool)
+	for conn.written.Len() > 0 {
+		packet, err := DeserializePacket(&conn.written)
+		if err != nil {
+			t.Fatalf("DeserializePacket failed: %v", err)
+		}
+		var header struct {
+			ArenaID  int32
+			PlayerID int32
+		}
+		r := bytes.NewReader(packet.Data)
+		binary.Read(r, binary.LittleEndian, &header)
+		switch packet.Type {
+		case PacketArenaUpdate:
+			updated = append(updated, int(header.PlayerID))
+		case PacketInterest:
+			var entered bool
+			binary.Read(r, binary.LittleEndian, &entered)
+			changes[int(header.PlayerID)] = entered
+		}
+	}
+	return updated, changes
+}
+
+// newInterestArena returns an arena with an observer at (100, 100) watching a player
+// nearby, one beyond the interest radius and one close but behind a plane at x=250
+func newInterestArena() (*Arena, *captureConn) {
+	arena := newTestArena(1)
+	arena.Geometry = blockAtX(250)
+	positions := map[int][2]float64{1: {100, 100}, 2: {100, 400}, 3: {100, 3000}, 4: {400, 100}}
+	for id, pos := range positions {
+		arena.AddPlayer(id, TeamChaos)
+		arena.movePlayerLocked(arena.Players[id], pos[0], pos[1])
+	}
+	conn := &captureConn{}
+	arena.SetPlayerConn(1, conn)
+	return arena, conn
+}
+
+func TestInterestUpdateRates(t *testing.T) {
+	arena, conn := newInterestArena()
+	now := time.Now()
+	arena.sendUpdatesLocked(now)
+	updated, changes := interestUpdates(t, conn)
+	if len(changes) != 1 || !changes[2] {
+		t.Errorf("Expected only player 2 to enter interest, got %v", changes)
+	}
+	if len(updated) != 3 {
+		t.Errorf("Expected a first update about every other player, got %v", updated)
+	}
+
+	// Nothing is resent until someone moves
+	arena.sendUpdatesLocked(now.Add(16 * time.Millisecond))
+	if updated, _ := interestUpdates(t, conn); len(updated) != 0 {
+		t.Errorf("Expected no updates without movement, got %v", updated)
+	}
+
+	// The nearby player is updated at once, the distant ones only after the interval
+	arena.movePlayerLocked(arena.Players[2], 110, 400)
+	arena.movePlayerLocked(arena.Players[3], 110, 3000)
+	arena.movePlayerLocked(arena.Players[4], 400, 110)
+	arena.sendUpdatesLocked(now.Add(32 * time.Millisecond))
+	if updated, _ := interestUpdates(t, conn); len(updated) != 1 || updated[0] != 2 {
+		t.Errorf("Expected an update about player 2 alone, got %v", updated)
+	}
+	arena.sendUpdatesLocked(now.Add(farUpdateInterval))
+	if updated, _ := interestUpdates(t, conn); len(updated) != 2 {
+		t.Errorf("Expected updates about the distant players, got %v", updated)
+	}
+
+	// Players without a connection are never sent anything
+	if arena.Players[2].view != nil {
+		t.Error("Expected no view for a player without a connection")
+	}
+}
+
+func TestInterestEnterAndLeave(t *testing.T) {
+	arena, conn := newInterestArena()
+	now := time.Now()
+	arena.sendUpdatesLocked(now)
+	interestUpdates(t, conn)
+
+	// Player 3 walks into range and player 2 out of it; the change is noticed at the
+	// next refresh
+	arena.movePlayerLocked(arena.Players[3], 100, 600)
+	arena.movePlayerLocked(arena.Players[2], 100, 2000)
+	arena.sendUpdatesLocked(now.Add(interestRefresh))
+	updated, changes := interestUpdates(t, conn)
+	if len(changes) != 2 || !changes[3] || changes[2] {
+		t.Errorf("Expected player 3 to enter and player 2 to leave, got %v", changes)
+	}
+	if len(updated) != 1 || updated[0] != 3 {
+		t.Errorf("Expected a full update about the entering player, got %v", updated)
+	}
+
+	// A player leaving the arena leaves interest
+	arena.RemovePlayer(3)
+	arena.sendUpdatesLocked(now.Add(2 * interestRefresh))
+	if _, changes := interestUpdates(t, conn); len(changes) != 1 || changes[3] {
+		t.Errorf("Expected player 3 to leave, got %v", changes)
+	}
+
+	// A new connection starts afresh
+	conn = &captureConn{}
+	arena.SetPlayerConn(1, conn)
+	arena.sendUpdatesLocked(now.Add(3 * interestRefresh))
+	if updated, _ := interestUpdates(t, conn); len(updated) != 2 {
+		t.Errorf("Expected the new connection to be sent every player, got %v", updated)
+	}
+}
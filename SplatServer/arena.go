@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,12 +12,45 @@ import (
 type Arena struct {
 	ID          int
 	Name        string
+	ShortName   string // abbreviated name from Arenas.dat
+	Description string // description from Arenas.dat, may be empty
 	MaxPlayers  int
 	Players     map[int]*ArenaPlayer
 	State       ArenaState
 	StartTime   time.Time
 	EndTime     time.Time
 	GridID      int
+	Walls       map[int64]*Wall
+	Runes       map[int64]*Rune
+	Geometry    Geometry // level geometry for line traces, nil for an open arena
+	ExpBonus    float64  // added to the server experience multiplier
+	World       *World   // team spawn and raise points, nil to raise players where they fell
+	Grid        *Grid    // block map from Grid.dat, nil when the grid has none
+	Triggers    map[int]*Trigger // doors, elevators and teleporters from Trigger.dat, by ID
+	Thins       map[int]*ArenaThin // destructible thins from Misc.dat, by ID
+	Ruleset     Ruleset  // match type and rules
+	DisabledTeam Team    // the team sitting out under the TwoTeams rule
+	RespawnDelay time.Duration // time a dead player waits to respawn, 0 for the default
+	Orbs         map[Team]*CTFOrb // team orbs in a capture-the-flag match
+	Captures     map[Team]int     // orbs each team has captured this match
+	CaptureLimit int              // captures needed to win, 0 for the default
+	Shrines      map[Team]*Shrine // team shrines for this match, from World.dat
+	Pools        []*Pool          // earthblood pools for this match, from World.dat
+	TimeLimit     time.Duration // match length, 0 for no limit
+	ScoreLimit    int           // team score, or player score without teams, that wins the match; 0 for none
+	Countdown     time.Duration // warm-up before the match starts, 0 for the default
+	CountdownEnds time.Time     // when a counting down match starts
+	ResetDelay    time.Duration // time between a match ending and the arena reopening, 0 for the default
+	ReturnPlayers bool          // send players back to the lobby when the arena reopens instead of keeping them
+
+	nextObjectiveTick time.Time    // when standing players next bias shrines and pools
+	nextGuildStandings time.Time   // when a guild rules match next announces its guild points
+	warnedOneMinute   bool         // the one minute warning has been given this match
+	result            *MatchResult // the outcome of the current or last match
+	resultSaved       bool         // the result has been handed out for saving
+	nextRegen         time.Time    // when living players next regenerate health
+	startingTeams     map[Team]bool // the teams with players when the match started
+	index             *SpatialIndex // players, walls and runes by location
 	mu          sync.RWMutex
 }
 
@@ -26,6 +61,7 @@ const (
 	ArenaStateWaiting ArenaState = iota
 	ArenaStateActive
 	ArenaStateEnded
+	ArenaStateCountdown // enough players have joined and the match is about to start
 )
 
 // ArenaPlayer represents a player in an arena
@@ -33,8 +69,21 @@ type ArenaPlayer struct {
 	PlayerID int
 	Team     Team
 	X, Y     float64
+	Angle    float64 // facing in radians
 	Health   int
+	MaxHealth int
+	Level    int
 	Score    int
+	PendingExp int    // experience earned but not yet credited to the character
+	Statistics StatisticSheet // combat counters for this match
+	Dead      bool      // killed and waiting to respawn or be raised
+	RespawnAt time.Time // when a dead player returns at their team's raise point
+	BiasReadyAt time.Time // when the player can next bias a shrine or pool directly
+	Conn     net.Conn // connection for arena broadcasts, nil for players without one
+	Bot      bool     // driven by the server; bots' statistics are not saved
+
+	statisticsRecorded bool        // set once the match statistics have been handed out for saving
+	view               *playerView // what this player's client has been told about the others
 }
 
 // Team represents a team in the arena
@@ -47,6 +96,32 @@ const (
 	TeamOrder
 )
 
+// teamNames maps teams to their names
+var teamNames = map[Team]string{
+	TeamNone:    "no team",
+	TeamChaos:   "Chaos",
+	TeamBalance: "Balance",
+	TeamOrder:   "Order",
+}
+
+// String returns the team's name
+func (t Team) String() string {
+	if name, ok := teamNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("team %d", int(t))
+}
+
+// ParseTeam parses a team name
+func ParseTeam(name string) (Team, error) {
+	for team, teamName := range teamNames {
+		if strings.EqualFold(strings.TrimSpace(name), teamName) {
+			return team, nil
+		}
+	}
+	return TeamNone, fmt.Errorf("unknown team %q", name)
+}
+
 // ArenaManager manages all arenas
 type ArenaManager struct {
 	Arenas map[int]*Arena
@@ -60,8 +135,13 @@ func NewArenaManager() *ArenaManager {
 	}
 }
 
-// CreateArena creates a new arena
+// CreateArena creates a new arena with the normal ruleset
 func (am *ArenaManager) CreateArena(id int, name string, maxPlayers int, gridID int) *Arena {
+	return am.CreateArenaWithRuleset(id, name, maxPlayers, gridID, NewRuleset(ArenaModeNormal))
+}
+
+// CreateArenaWithRuleset creates a new arena that plays by a ruleset
+func (am *ArenaManager) CreateArenaWithRuleset(id int, name string, maxPlayers int, gridID int, ruleset Ruleset) *Arena {
 	arena := &Arena{
 		ID:         id,
 		Name:       name,
@@ -69,7 +149,9 @@ func (am *ArenaManager) CreateArena(id int, name string, maxPlayers int, gridID
 		Players:    make(map[int]*ArenaPlayer),
 		State:      ArenaStateWaiting,
 		GridID:     gridID,
+		TimeLimit:  defaultTimeLimit,
 	}
+	arena.applyRulesetLocked(ruleset)
 
 	am.mu.Lock()
 	am.Arenas[id] = arena
@@ -85,7 +167,21 @@ func (am *ArenaManager) GetArena(id int) *Arena {
 	return am.Arenas[id]
 }
 
-// AddPlayer adds a player to an arena
+// FindPlayerArena returns the arena a player is currently in, or nil
+func (am *ArenaManager) FindPlayerArena(playerID int) *Arena {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	for _, arena := range am.Arenas {
+		if arena.GetPlayer(playerID) != nil {
+			return arena
+		}
+	}
+	return nil
+}
+
+// AddPlayer adds a player to an arena at their team's start position. Under the
+// NoTeams rule every player joins without a team.
 func (a *Arena) AddPlayer(playerID int, team Team) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -98,16 +194,29 @@ func (a *Arena) AddPlayer(playerID int, team Team) error {
 		return fmt.Errorf("player already in arena")
 	}
 
+	if a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		team = TeamNone
+	}
+	if !a.teamAllowedLocked(team) {
+		return fmt.Errorf("%s is not playing in this arena", team)
+	}
+
 	arenaPlayer := &ArenaPlayer{
 		PlayerID: playerID,
 		Team:     team,
 		X:        0,
 		Y:        0,
 		Health:   100,
+		MaxHealth: 100,
+		Level:    1,
 		Score:    0,
 	}
+	if spawn, ok := a.spawnLocked(team); ok {
+		arenaPlayer.X, arenaPlayer.Y, arenaPlayer.Angle = spawn.Start.X, spawn.Start.Y, spawn.Start.Angle
+	}
 
 	a.Players[playerID] = arenaPlayer
+	a.movePlayerLocked(arenaPlayer, arenaPlayer.X, arenaPlayer.Y)
 	return nil
 }
 
@@ -115,7 +224,9 @@ func (a *Arena) AddPlayer(playerID int, team Team) error {
 func (a *Arena) RemovePlayer(playerID int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.returnOrbLocked(playerID)
 	delete(a.Players, playerID)
+	a.spatialLocked().Remove(EntityKey{EntityPlayer, int64(playerID)})
 }
 
 // GetPlayer gets a player from the arena
@@ -125,41 +236,128 @@ func (a *Arena) GetPlayer(playerID int) *ArenaPlayer {
 	return a.Players[playerID]
 }
 
-// UpdatePlayerPosition updates a player's position in the arena
-func (a *Arena) UpdatePlayerPosition(playerID int, x, y float64) {
+// UpdatePlayerPosition updates a player's position in the arena, teleporting them if
+// they step onto a teleporter pad.
+// It returns false when the move is blocked by level geometry, a door or a wall, or the player is dead.
+func (a *Arena) UpdatePlayerPosition(playerID int, x, y float64) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if player, exists := a.Players[playerID]; exists {
-		player.X = x
-		player.Y = y
+	player, exists := a.Players[playerID]
+	if !exists || player.Dead {
+		return false
+	}
+
+	if a.Geometry != nil {
+		if _, blocked := a.Geometry.SweepCircle(player.X, player.Y, x, y, playerRadius); blocked {
+			return false
+		}
 	}
+
+	// Players already overlapping a wall may still walk out of it
+	solidWalls := !a.Ruleset.Rules.Has(ArenaRuleNoSolidWalls)
+	if solidWalls && a.wallAtLocked(x, y, playerRadius) != nil && a.wallAtLocked(player.X, player.Y, playerRadius) == nil {
+		return false
+	}
+
+	fromX, fromY := player.X, player.Y
+	a.movePlayerLocked(player, x, y)
+	a.enterTeleportersLocked(player, fromX, fromY)
+	return true
 }
 
 // StartArena starts the arena game
 func (a *Arena) StartArena() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.startLocked()
+}
 
-	if a.State != ArenaStateWaiting {
+// startLocked moves a waiting or counting down arena to active; the caller must hold a.mu
+func (a *Arena) startLocked() {
+	if a.State != ArenaStateWaiting && a.State != ArenaStateCountdown {
 		return
 	}
 
 	a.State = ArenaStateActive
 	a.StartTime = time.Now()
+	a.startingTeams = a.teamsPresentLocked()
+	a.placeObjectivesLocked()
+	a.placeOrbsLocked()
 }
 
-// EndArena ends the arena game
-func (a *Arena) EndArena() {
+// EndArena ends the arena game, broadcasts the final scoreboard and result and returns
+// the scoreboard. A match ended before CheckMatchEnd found a winner is decided by the
+// standings. The returned statistics are handed over for saving, so they are not taken
+// again on leave.
+func (a *Arena) EndArena() []ScoreEntry {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	if a.State != ArenaStateActive {
-		return
+		return nil
 	}
 
 	a.State = ArenaStateEnded
 	a.EndTime = time.Now()
+	if a.result == nil {
+		result := a.standingsResultLocked(matchEndedManually)
+		a.result = &result
+	}
+	a.result.EndedAt = a.EndTime
+	a.resultSaved = false
+
+	scoreboard := a.scoreboardLocked()
+	for _, player := range a.Players {
+		player.statisticsRecorded = true
+	}
+	a.broadcastLocked(BuildScoreboardPacket(a.ID, scoreboard))
+	a.broadcastLocked(BuildChatPacket(a.result.Describe()))
+	return scoreboard
+}
+
+// SetPlayerConn records the connection used to send arena events to a player
+func (a *Arena) SetPlayerConn(playerID int, conn net.Conn) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if player, exists := a.Players[playerID]; exists {
+		player.Conn = conn
+		player.view = nil // a new client has been told nothing yet
+	}
+}
+
+// SetPlayerProgression records a player's level and max health; health is topped up
+// by any increase in max health unless they are dead
+func (a *Arena) SetPlayerProgression(playerID, level, maxHealth int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if player, exists := a.Players[playerID]; exists {
+		if maxHealth > player.MaxHealth && !player.Dead {
+			player.Health += maxHealth - player.MaxHealth
+		}
+		player.Level = level
+		player.MaxHealth = maxHealth
+		player.Health = min(player.Health, maxHealth)
+	}
+}
+
+// Broadcast sends a packet to every connected player in the arena
+func (a *Arena) Broadcast(packet *Packet) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	a.broadcastLocked(packet)
+}
+
+// broadcastLocked sends a packet to every connected player; the caller must hold a.mu
+func (a *Arena) broadcastLocked(packet *Packet) {
+	data := packet.Serialize()
+	for _, player := range a.Players {
+		if player.Conn != nil {
+			player.Conn.Write(data)
+		}
+	}
 }
 
 // GetPlayerCount returns the number of players in the arena
@@ -169,6 +367,13 @@ func (a *Arena) GetPlayerCount() int {
 	return len(a.Players)
 }
 
+// GetMatchLimits returns the arena's match time limit and experience bonus
+func (a *Arena) GetMatchLimits() (time.Duration, float64) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.TimeLimit, a.ExpBonus
+}
+
 // IsFull checks if the arena is full
 func (a *Arena) IsFull() bool {
 	a.mu.RLock()
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArenaTemplate describes an arena from Arenas.dat
+type ArenaTemplate struct {
+	ID            int // numbered from 1, so [arena00] is arena 1
+	Name          string
+	ShortName     string
+	Description   string
+	GridID        int // the grid00..gridNN folder under Content/Grids
+	MaxPlayers    int
+	TimeLimit     time.Duration
+	ExpBonus      float64
+	ReturnPlayers bool   // send players back to the lobby when a match's arena reopens
+	Midi          string // background music played by the client
+	Type          int    // arena type, 1 for every shipped arena
+	Enabled       bool
+}
+
+// defaultArenaTemplates are created when Arenas.dat is unavailable
+var defaultArenaTemplates = []ArenaTemplate{
+	{ID: 1, Name: "Chaos Arena", ShortName: "Chaos", GridID: 1, MaxPlayers: 8, TimeLimit: defaultTimeLimit, Enabled: true},
+	{ID: 2, Name: "Balance Arena", ShortName: "Balance", GridID: 2, MaxPlayers: 8, TimeLimit: defaultTimeLimit, Enabled: true},
+	{ID: 3, Name: "Order Arena", ShortName: "Order", GridID: 3, MaxPlayers: 8, TimeLimit: defaultTimeLimit, Enabled: true},
+}
+
+// LoadArenaFile parses arena templates from an Arenas.dat file
+func LoadArenaFile(path string) ([]ArenaTemplate, error) {
+	ini, err := LoadIniFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseArenaDefs(ini)
+}
+
+// parseArenaDefs builds templates from the [arenadefs] and [arenaNN] sections
+func parseArenaDefs(ini *IniFile) ([]ArenaTemplate, error) {
+	count := ini.Int("arenadefs", "numarenas", 0)
+	if count <= 0 {
+		return nil, fmt.Errorf("no arenas defined in [arenadefs]")
+	}
+
+	templates := make([]ArenaTemplate, 0, count)
+	for index := 0; index < count; index++ {
+		section := fmt.Sprintf("arena%02d", index)
+		if !ini.HasSection(section) {
+			return nil, fmt.Errorf("missing section [%s]", section)
+		}
+		template, err := parseArenaTemplate(ini, section, index+1)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// parseArenaTemplate converts one [arenaNN] section into an ArenaTemplate
+func parseArenaTemplate(ini *IniFile, section string, id int) (ArenaTemplate, error) {
+	grid := ini.String(section, "grid", "")
+	gridID, err := parseGridName(grid)
+	if err != nil {
+		return ArenaTemplate{}, fmt.Errorf("[%s] %v", section, err)
+	}
+
+	template := ArenaTemplate{
+		ID:            id,
+		Name:          ini.String(section, "name", fmt.Sprintf("Arena %d", id)),
+		ShortName:     ini.String(section, "short_name", ""),
+		Description:   ini.String(section, "description", ""),
+		GridID:        gridID,
+		MaxPlayers:    ini.Int(section, "maxplayers", 0),
+		TimeLimit:     time.Duration(ini.Int(section, "timelimit", 0)) * time.Second,
+		ExpBonus:      ini.Float(section, "expbonus", 0),
+		ReturnPlayers: ini.Bool(section, "returnplayers", false),
+		Midi:          ini.String(section, "midi", ""),
+		Type:          ini.Int(section, "type", 1),
+		Enabled:       ini.Int(section, "enabled", 1) != 0,
+	}
+	if template.MaxPlayers <= 0 {
+		return ArenaTemplate{}, fmt.Errorf("[%s] maxplayers must be positive, got %d", section, template.MaxPlayers)
+	}
+	if template.ShortName == "" {
+		template.ShortName = template.Name
+	}
+	return template, nil
+}
+
+// parseGridName returns the ID of a grid named like grid04
+func parseGridName(name string) (int, error) {
+	digits := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "grid")
+	id, err := strconv.Atoi(digits)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid grid %q", name)
+	}
+	return id, nil
+}
+
+// CreateArenaFromTemplate creates an arena with a template's name, grid, capacity, limits
+// and reopening behaviour
+func (am *ArenaManager) CreateArenaFromTemplate(template ArenaTemplate) *Arena {
+	arena := am.CreateArena(template.ID, template.Name, template.MaxPlayers, template.GridID)
+
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+	arena.ShortName = template.ShortName
+	arena.Description = template.Description
+	arena.TimeLimit = template.TimeLimit
+	arena.ExpBonus = template.ExpBonus
+	arena.ReturnPlayers = template.ReturnPlayers
+	return arena
+}
+
+// loadArenaTemplates reads Arenas.dat, falling back to the built-in arenas when it is
+// unavailable
+func loadArenaTemplates() []ArenaTemplate {
+	path, err := findContentFile(contentDir(), "Arenas.dat")
+	if err != nil {
+		fmt.Printf("SplatServer: Arenas.dat not found, using built-in arenas: %v\n", err)
+		return defaultArenaTemplates
+	}
+
+	templates, err := LoadArenaFile(path)
+	if err != nil {
+		fmt.Printf("SplatServer: Failed to load %s, using built-in arenas: %v\n", path, err)
+		return defaultArenaTemplates
+	}
+	return templates
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadArenaFile(t *testing.T) {
+	path, err := findContentFile(contentDir(), "Arenas.dat")
+	if err != nil {
+		t.Fatalf("Arenas.dat not found: %v", err)
+	}
+	templates, err := LoadArenaFile(path)
+	if err != nil {
+		t.Fatalf("LoadArenaFile failed: %v", err)
+	}
+	if len(templates) != 10 {
+		t.Fatalf("Expected 10 arenas, got %d", len(templates))
+	}
+
+	// [arena03] Thunder Keep, grid03, maxplayers=99, timelimit=3600, expbonus=0.15
+	keep := templates[3]
+	if keep.ID != 4 || keep.Name != "Thunder Keep" || keep.ShortName != "Keep" || keep.GridID != 3 {
+		t.Errorf("Unexpected arena %+v", keep)
+	}
+	if keep.MaxPlayers != 99 || keep.TimeLimit != time.Hour || keep.ExpBonus != 0.15 || !keep.Enabled {
+		t.Errorf("Unexpected limits for %s: %+v", keep.Name, keep)
+	}
+	if keep.Description != "A medium sized arena set in a Medieval Keep." || keep.Midi != "keep.mid" || keep.Type != 1 {
+		t.Errorf("Unexpected metadata for %s: %+v", keep.Name, keep)
+	}
+	if lake := templates[9]; lake.Description != "" || lake.GridID != 9 || lake.ReturnPlayers {
+		t.Errorf("Unexpected arena %+v", lake)
+	}
+}
+
+func TestParseArenaDefsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no arenas", "[arenadefs]\nnumarenas=0\n"},
+		{"missing section", "[arenadefs]\nnumarenas=2\n[arena00]\ngrid=grid00\nmaxplayers=8\n"},
+		{"bad grid", "[arenadefs]\nnumarenas=1\n[arena00]\ngrid=keep\nmaxplayers=8\n"},
+		{"no capacity", "[arenadefs]\nnumarenas=1\n[arena00]\ngrid=grid00\n"},
+	}
+
+	for _, tt := range tests {
+		ini, err := ParseIni(strings.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: ParseIni failed: %v", tt.name, err)
+		}
+		if _, err := parseArenaDefs(ini); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestInitializeArenasFromTemplates(t *testing.T) {
+	data := "[arenadefs]\nnumarenas=2\n" +
+		"[arena00]\nenabled=1\nname=Splat Park\nshort_name=Park\ndescription=A park.\ngrid=grid08\nmaxplayers=12\ntimelimit=1800\nexpbonus=0.25\nreturnplayers=1\n" +
+		"[arena01]\nenabled=0\nname=Closed\ngrid=grid01\nmaxplayers=8\n"
+	ini, err := ParseIni(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseIni failed: %v", err)
+	}
+	templates, err := parseArenaDefs(ini)
+	if err != nil {
+		t.Fatalf("parseArenaDefs failed: %v", err)
+	}
+
+	gs := NewGameState()
+	for _, template := range templates {
+		if template.Enabled {
+			gs.ArenaManager.CreateArenaFromTemplate(template)
+		}
+	}
+	if gs.ArenaManager.GetArena(2) != nil {
+		t.Error("Expected the disabled arena not to be created")
+	}
+	park := gs.ArenaManager.GetArena(1)
+	if park == nil || park.MaxPlayers != 12 || park.GridID != 8 || park.TimeLimit != 30*time.Minute || park.ExpBonus != 0.25 || !park.ReturnPlayers {
+		t.Fatalf("Unexpected arena %+v", park)
+	}
+
+	player := addTestCaster(gs, 1)
+	conn := player.Conn.(*captureConn)
+	handleArenaList(&Message{Type: MsgArenaList}, player, gs)
+	reply := conn.written.String()
+	if !strings.Contains(reply, "Splat Park (Park) (ID: 1, Players: 0/12, Grid: 8, Time limit: 30 min, Exp bonus: +25%)") ||
+		!strings.Contains(reply, "  A park.\n") {
+		t.Errorf("Unexpected arena list %q", reply)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+)
+
+// defaultBoltRange is used for bolt spells that do not define a range
+const defaultBoltRange = 350.0
+
+// BoltResult describes where a bolt's line trace ended and what it hit
+type BoltResult struct {
+	CasterID       int
+	SpellID        int
+	StartX, StartY float64
+	EndX, EndY     float64
+	TargetID       int   // player hit, 0 for none
+	WallID         int64 // wall hit, 0 for none
+	ThinID         int   // destructible thin hit, 0 for none
+}
+
+// FireBolt traces a bolt from an origin along angle to the spell's range and
+// resolves whatever it hits first: solid geometry, a destructible thin, a wall or a player
+func (a *Arena) FireBolt(casterID int, spell *Spell, x, y, angle float64) BoltResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	boltRange := spell.Range
+	if boltRange <= 0 {
+		boltRange = defaultBoltRange
+	}
+	endX := x + boltRange*math.Cos(angle)
+	endY := y + boltRange*math.Sin(angle)
+
+	result := BoltResult{CasterID: casterID, SpellID: spell.ID, StartX: x, StartY: y}
+
+	nearestT := 1.0
+	if a.Geometry != nil {
+		if t, hit := a.Geometry.TraceSegment(x, y, endX, endY); hit {
+			nearestT = t
+		}
+	}
+
+	thin, thinT := a.firstThinOnSegmentLocked(x, y, endX, endY)
+	if thin != nil && thinT <= nearestT {
+		nearestT = thinT
+	} else {
+		thin = nil
+	}
+
+	wall, wallT := a.firstWallOnSegmentLocked(x, y, endX, endY)
+	if wall != nil && wallT < nearestT {
+		nearestT, thin = wallT, nil
+	} else {
+		wall = nil
+	}
+
+	var target *ArenaPlayer
+	for _, player := range a.playersAlongLocked(x, y, endX, endY, playerRadius) {
+		if player.PlayerID == casterID || player.Dead {
+			continue
+		}
+		if t, ok := segmentCircleHit(x, y, endX, endY, player.X, player.Y, playerRadius); ok && t < nearestT {
+			target, nearestT, thin = player, t, nil
+		}
+	}
+
+	result.EndX = x + (endX-x)*nearestT
+	result.EndY = y + (endY-y)*nearestT
+
+	switch {
+	case target != nil:
+		result.TargetID = target.PlayerID
+		a.applySpellLocked(casterID, target, spell)
+	case wall != nil:
+		result.WallID = wall.ID
+		a.damageWallLocked(wall.ID, spell, spell.Damage)
+	case thin != nil:
+		result.ThinID = thin.Thin.ID
+		a.damageThinLocked(thin, casterID, spell.Damage)
+	}
+
+	return result
+}
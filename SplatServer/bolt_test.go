@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// captureConn records everything written to it
+type captureConn struct {
+	mockConn
+	written bytes.Buffer
+}
+
+func (c *captureConn) Write(b []byte) (int, error) { return c.written.Write(b) }
+
+// blockAtX is level geometry with a solid plane at a fixed x
+type blockAtX float64
+
+func (g blockAtX) TraceSegment(x1, y1, x2, y2 float64) (float64, bool) {
+	x := float64(g)
+	if (x1 < x) == (x2 < x) {
+		return 0, false
+	}
+	return (x - x1) / (x2 - x1), true
+}
+
+func testBoltSpell() *Spell {
+	return &Spell{
+		ID:             300,
+		Name:           "Test Bolt",
+		Type:           SpellTypeBolt,
+		ProjectileType: SpellProjectileBolt,
+		Damage:         25,
+		Range:          350,
+	}
+}
+
+func TestFireBoltHitsNearestPlayer(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamOrder)
+	arena.UpdatePlayerPosition(2, 200, 0)
+	arena.UpdatePlayerPosition(3, 100, 0)
+
+	result := arena.FireBolt(1, testBoltSpell(), 0, 0, 0)
+
+	if result.TargetID != 3 {
+		t.Fatalf("Expected nearest player 3 to be hit, got %d", result.TargetID)
+	}
+	if math.Abs(result.EndX-(100-playerRadius)) > 1e-6 {
+		t.Errorf("Expected bolt to end at the target's edge, got %.2f", result.EndX)
+	}
+	if arena.GetPlayer(3).Health != 75 || arena.GetPlayer(2).Health != 100 {
+		t.Error("Expected only the first player on the line to take damage")
+	}
+}
+
+func TestFireBoltStopsAtGeometryAndWalls(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 200, 0)
+
+	arena.Geometry = blockAtX(150)
+	result := arena.FireBolt(1, testBoltSpell(), 0, 0, 0)
+	if result.TargetID != 0 || math.Abs(result.EndX-150) > 1e-6 {
+		t.Errorf("Expected bolt to stop at geometry, got %+v", result)
+	}
+
+	arena.Geometry = nil
+	arena.AddWall(NewWall(testWallSpell(), 2, TeamOrder, 0, 0, 0))
+	result = arena.FireBolt(1, testBoltSpell(), 0, 0, 0)
+	if result.WallID == 0 || result.TargetID != 0 {
+		t.Errorf("Expected bolt to hit the wall, got %+v", result)
+	}
+	if arena.GetPlayer(2).Health != 100 {
+		t.Error("Expected player behind geometry and wall to be unharmed")
+	}
+}
+
+func TestFireBoltOutOfRange(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 500, 0)
+
+	result := arena.FireBolt(1, testBoltSpell(), 0, 0, 0)
+	if result.TargetID != 0 || math.Abs(result.EndX-350) > 1e-6 {
+		t.Errorf("Expected bolt to end at max range, got %+v", result)
+	}
+}
+
+func TestCastBoltBroadcastsEvent(t *testing.T) {
+	gs := NewGameState()
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{testBoltSpell()})
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 100, 0)
+	addTestCaster(gs, 1, 300)
+
+	conn := &captureConn{}
+	arena.SetPlayerConn(2, conn)
+
+	if _, err := gs.CastSpell(1, 300, 100, 0, 2); err != nil {
+		t.Fatalf("Failed to cast bolt: %v", err)
+	}
+	if arena.GetPlayer(2).Health != 75 {
+		t.Errorf("Expected the bolt to resolve immediately, health %d", arena.GetPlayer(2).Health)
+	}
+	if len(gs.SpellSystem.GetActiveSpells()) != 0 {
+		t.Error("Expected no lingering spell instance for a bolt")
+	}
+
+	packet, err := DeserializePacket(&conn.written)
+	if err != nil {
+		t.Fatalf("Failed to read bolt packet: %v", err)
+	}
+	if packet.Type != PacketBolt || len(packet.Data) != 48 {
+		t.Errorf("Unexpected bolt packet type %d length %d", packet.Type, len(packet.Data))
+	}
+}
+
+func (g blockAtX) IsSolid(x, y, radius float64) bool {
+	return math.Abs(x-float64(g)) <= radius
+}
+
+func (g blockAtX) SweepCircle(x1, y1, x2, y2, radius float64) (float64, bool) {
+	if x2 < x1 {
+		radius = -radius
+	}
+	return blockAtX(float64(g)-radius).TraceSegment(x1, y1, x2, y2)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	botThinkInterval = 100 * time.Millisecond // how often bots decide what to do and move
+	defaultBotLevel  = 10
+)
+
+// Bot is a player driven by the server. It has a character and spellbook like a connected
+// player, without a connection, and plays through the same spell casting and movement
+// checks, run by the behaviour tree in bottree.go
+type Bot struct {
+	Player  *Player
+	ArenaID int
+
+	lastThink   time.Time
+	targetID    int        // enemy being fought, 0 for none
+	path        []Waypoint // waypoints still to reach toward the goal
+	goalX       float64
+	goalY       float64
+	hasGoal     bool
+	unreachable bool      // no path was found to the goal when it was planned
+	plannedAt   time.Time // when the path to the goal was planned
+	blocked     int       // moves refused in a row
+	waitSince   time.Time // when the bot began waiting for a door or elevator, zero when not waiting
+	roamX       float64
+	roamY       float64
+	roaming     bool
+	movedX      float64
+	movedY      float64
+	moved       bool      // the bot moved to movedX, movedY on its last think
+	biasedAt    time.Time // when the bot last biased a shrine or pool
+	calledRaise bool      // the bot has called for a raise since it died
+}
+
+// BotManager adds bots to arenas, by admin command or to fill empty slots, and runs them
+type BotManager struct {
+	Level       int         // character level of new bots
+	DefaultFill int         // players every arena is filled up to with bots, 0 for none
+	fill        map[int]int // fill targets set for single arenas, by arena ID
+	bots        map[int]*Bot
+	mu          sync.Mutex
+}
+
+// NewBotManager creates a bot manager configured from BOT_LEVEL and BOT_FILL
+func NewBotManager() *BotManager {
+	return &BotManager{
+		Level:       loadBotSetting("BOT_LEVEL", defaultBotLevel),
+		DefaultFill: loadBotSetting("BOT_FILL", 0),
+		fill:        make(map[int]int),
+		bots:        make(map[int]*Bot),
+	}
+}
+
+// loadBotSetting reads a non-negative bot setting from the environment
+func loadBotSetting(key string, fallback int) int {
+	value := getEnv(key, strconv.Itoa(fallback))
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		fmt.Printf("Invalid %s %q, using %d\n", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// RunBots has the bots think and move until the server stops. They run beside the game
+// loop, as connected players' messages do, since casting takes the game state's lock
+func RunBots(gs *GameState) {
+	ticker := time.NewTicker(botThinkInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		gs.Bots.Update(gs, now)
+	}
+}
+
+// AddBot creates a bot and puts it in an arena, on the smallest team playing when team
+// is TeamNone
+func (bm *BotManager) AddBot(gs *GameState, arena *Arena, team Team) (*Bot, error) {
+	if team == TeamNone {
+		team = arena.botTeam()
+	}
+
+	id := int(atomic.AddInt64(&playerIDCounter, 1))
+	player := &Player{
+		ID:       id,
+		Name:     fmt.Sprintf("Bot%d", id),
+		Level:    max(1, bm.Level),
+		LastSeen: time.Now(),
+	}
+	player.Spellbook = newBotSpellbook(gs.SpellSystem.SpellManager, PlayerClass(id%len(playerClassNames)), player.Level)
+	player.Health, player.Power = player.MaxHealth(), player.MaxPower()
+
+	if err := arena.AddBot(id, team); err != nil {
+		return nil, err
+	}
+	arena.SetPlayerProgression(id, player.Level, player.MaxHealth())
+	gs.AddPlayer(player)
+
+	bot := &Bot{Player: player, ArenaID: arena.ID}
+	bm.mu.Lock()
+	bm.bots[id] = bot
+	bm.mu.Unlock()
+	fmt.Printf("Bot %s joined arena %d as %s\n", player.Name, arena.ID, team)
+	return bot, nil
+}
+
+// RemoveBot takes a bot out of its arena and the game, reporting whether it was a bot
+func (bm *BotManager) RemoveBot(gs *GameState, id int) bool {
+	bm.mu.Lock()
+	bot, exists := bm.bots[id]
+	delete(bm.bots, id)
+	bm.mu.Unlock()
+	if !exists {
+		return false
+	}
+
+	if arena := gs.ArenaManager.GetArena(bot.ArenaID); arena != nil {
+		arena.RemovePlayer(id)
+	}
+	gs.RemovePlayer(id)
+	return true
+}
+
+// RemoveBots takes up to count bots out of an arena, the newest first, and returns how
+// many were removed; a count below 1 removes them all
+func (bm *BotManager) RemoveBots(gs *GameState, arenaID, count int) int {
+	bots := bm.ArenaBots(arenaID)
+	if count < 1 || count > len(bots) {
+		count = len(bots)
+	}
+	for _, bot := range bots[len(bots)-count:] {
+		bm.RemoveBot(gs, bot.Player.ID)
+	}
+	return count
+}
+
+// ArenaBots returns the bots in an arena, oldest first
+func (bm *BotManager) ArenaBots(arenaID int) []*Bot {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	var bots []*Bot
+	for _, bot := range bm.bots {
+		if bot.ArenaID == arenaID {
+			bots = append(bots, bot)
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool { return bots[i].Player.ID < bots[j].Player.ID })
+	return bots
+}
+
+// IsBot reports whether a player is a bot
+func (bm *BotManager) IsBot(id int) bool {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	_, exists := bm.bots[id]
+	return exists
+}
+
+// SetFill sets the number of players an arena is filled up to with bots, 0 for none
+func (bm *BotManager) SetFill(arenaID, count int) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.fill[arenaID] = max(0, count)
+}
+
+// Fill returns the number of players an arena is filled up to with bots
+func (bm *BotManager) Fill(arenaID int) int {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if count, set := bm.fill[arenaID]; set {
+		return count
+	}
+	return bm.DefaultFill
+}
+
+// MakeRoom removes a bot from a full arena so a player can join, reporting whether the
+// arena has room
+func (bm *BotManager) MakeRoom(gs *GameState, arena *Arena) bool {
+	if !arena.IsFull() {
+		return true
+	}
+	return bm.RemoveBots(gs, arena.ID, 1) == 1
+}
+
+// Update fills arenas with bots, drops bots no longer in their arena, as when an arena
+// sends its players back to the lobby, and has the rest think
+func (bm *BotManager) Update(gs *GameState, now time.Time) {
+	gs.ArenaManager.mu.RLock()
+	arenas := make([]*Arena, 0, len(gs.ArenaManager.Arenas))
+	for _, arena := range gs.ArenaManager.Arenas {
+		arenas = append(arenas, arena)
+	}
+	gs.ArenaManager.mu.RUnlock()
+
+	for _, arena := range arenas {
+		bm.fillArena(gs, arena)
+	}
+
+	bm.mu.Lock()
+	bots := make([]*Bot, 0, len(bm.bots))
+	for _, bot := range bm.bots {
+		bots = append(bots, bot)
+	}
+	bm.mu.Unlock()
+
+	for _, bot := range bots {
+		arena := gs.ArenaManager.GetArena(bot.ArenaID)
+		if arena == nil || arena.GetPlayer(bot.Player.ID) == nil {
+			bm.RemoveBot(gs, bot.Player.ID)
+			continue
+		}
+		bot.think(gs, arena, now)
+	}
+}
+
+// fillArena adds bots until an arena with a fill target has that many players, and
+// removes them again as players join, without ever filling it past its maximum
+func (bm *BotManager) fillArena(gs *GameState, arena *Arena) {
+	target := min(bm.Fill(arena.ID), arena.MaxPlayers)
+	bots := len(bm.ArenaBots(arena.ID))
+	if target <= 0 && bots == 0 {
+		return
+	}
+
+	players := arena.GetPlayerCount()
+	for ; players < target; players++ {
+		if _, err := bm.AddBot(gs, arena, TeamNone); err != nil {
+			fmt.Printf("Failed to add a bot to arena %d: %v\n", arena.ID, err)
+			return
+		}
+	}
+	if excess := players - target; excess > 0 && bots > 0 && bm.Fill(arena.ID) > 0 {
+		bm.RemoveBots(gs, arena.ID, min(excess, bots))
+	}
+}
+
+// AddBot adds a bot to the arena as AddPlayer does, marking it as a bot
+func (a *Arena) AddBot(playerID int, team Team) error {
+	if err := a.AddPlayer(playerID, team); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if player, exists := a.Players[playerID]; exists {
+		player.Bot = true
+	}
+	return nil
+}
+
+// botTeam returns the team playing in the arena with the fewest players, for a bot to join
+func (a *Arena) botTeam() Team {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		return TeamNone
+	}
+	return a.smallestTeamLocked()
+}
+
+// newBotSpellbook returns a bot's spellbook: its points are spread over its class's spell
+// lists a level at a time, and the unlocked spells bots know how to use are equipped
+func newBotSpellbook(sm *SpellManager, class PlayerClass, level int) *Spellbook {
+	book := NewSpellbook(class)
+	trees := sm.GetTrees()
+	treeIDs := trees.ClassTrees[class]
+	for spent := 0; spent < level && len(treeIDs) > 0; spent++ {
+		book.Train(trees, strconv.Itoa(treeIDs[spent%len(treeIDs)]), level)
+	}
+	for _, spellID := range trees.UnlockedSpells(class, level, book.TreeLevels) {
+		if spell := sm.GetSpell(spellID); spell != nil && botSpellRoleOf(sm, spell) != botSpellUnused {
+			book.Learn(trees, spellID, level)
+		}
+	}
+	return book
+}
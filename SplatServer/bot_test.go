@@ -0,0 +1,178 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBotGame returns a game with the shipped spells and an empty open arena, with bots
+// configured the same whatever the environment
+func newBotGame(t *testing.T) (*GameState, *Arena) {
+	gs := NewGameState()
+	gs.SpellSystem.SpellManager = loadTestSpellManager(t)
+	gs.Bots.Level, gs.Bots.DefaultFill = defaultBotLevel, 0
+	arena := newTestArena(1)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	return gs, arena
+}
+
+func TestAddRemoveBots(t *testing.T) {
+	gs, arena := newBotGame(t)
+
+	first, err := gs.Bots.AddBot(gs, arena, TeamNone)
+	if err != nil {
+		t.Fatalf("AddBot failed: %v", err)
+	}
+	second, err := gs.Bots.AddBot(gs, arena, TeamNone)
+	if err != nil {
+		t.Fatalf("AddBot failed: %v", err)
+	}
+	if player := arena.GetPlayer(first.Player.ID); player == nil || !player.Bot || player.Conn != nil {
+		t.Fatalf("Expected the bot in the arena without a connection, got %+v", player)
+	}
+	if arena.GetPlayer(first.Player.ID).Team == arena.GetPlayer(second.Player.ID).Team {
+		t.Error("Expected bots to even out the teams")
+	}
+	if player, exists := gs.GetPlayer(first.Player.ID); !exists || player.Level != defaultBotLevel || len(player.Spellbook.GetSpells()) == 0 {
+		t.Errorf("Expected a level %d character with spells, got %+v", defaultBotLevel, player)
+	}
+	if player := arena.GetPlayer(first.Player.ID); player.MaxHealth != first.Player.MaxHealth() || player.Health != player.MaxHealth {
+		t.Errorf("Expected the bot at its character's full health, got %d of %d", player.Health, player.MaxHealth)
+	}
+	for _, entry := range arena.Scoreboard() {
+		if !entry.Bot {
+			t.Errorf("Expected player %d to be marked as a bot on the scoreboard", entry.PlayerID)
+		}
+	}
+
+	if gs.Bots.RemoveBot(gs, 999) {
+		t.Error("Expected removing a player who is not a bot to fail")
+	}
+	if removed := gs.Bots.RemoveBots(gs, arena.ID, 1); removed != 1 || arena.GetPlayer(second.Player.ID) != nil {
+		t.Errorf("Expected the newest bot removed, removed %d", removed)
+	}
+	if _, exists := gs.GetPlayer(second.Player.ID); exists || gs.Bots.IsBot(second.Player.ID) {
+		t.Error("Expected the removed bot to leave the game")
+	}
+	if !gs.Bots.IsBot(first.Player.ID) {
+		t.Error("Expected the other bot to stay")
+	}
+}
+
+func TestBotSpellbook(t *testing.T) {
+	sm := loadTestSpellManager(t)
+	for class := ClassMagician; class <= ClassCleric; class++ {
+		book := newBotSpellbook(sm, class, 10)
+		if book.PointsSpent() != 10 {
+			t.Errorf("%s: expected every point spent, got %d", class, book.PointsSpent())
+		}
+		roles := make(map[botSpellRole]int)
+		for _, id := range book.Spells {
+			roles[botSpellRoleOf(sm, sm.GetSpell(id))]++
+		}
+		if roles[botSpellAttack] == 0 || roles[botSpellUnused] != 0 {
+			t.Errorf("%s: expected attacks and only spells bots use, got %v", class, roles)
+		}
+		if class == ClassCleric && roles[botSpellRaise] == 0 {
+			t.Error("Expected a cleric bot to learn a raise")
+		}
+	}
+}
+
+func TestBotFill(t *testing.T) {
+	gs, arena := newBotGame(t)
+	arena.MaxPlayers = 4
+	arena.AddPlayer(1, TeamChaos)
+	now := time.Now()
+
+	gs.Bots.SetFill(arena.ID, 3)
+	gs.Bots.Update(gs, now)
+	if count, bots := arena.GetPlayerCount(), len(gs.Bots.ArenaBots(arena.ID)); count != 3 || bots != 2 {
+		t.Fatalf("Expected 2 bots to fill the arena to 3, got %d players and %d bots", count, bots)
+	}
+	gs.Bots.SetFill(arena.ID, 10)
+	gs.Bots.Update(gs, now)
+	if count := arena.GetPlayerCount(); count != 4 {
+		t.Fatalf("Expected the arena filled to its maximum of 4, got %d", count)
+	}
+
+	// A player joining a full arena takes a bot's place
+	if !gs.Bots.MakeRoom(gs, arena) || arena.GetPlayerCount() != 3 {
+		t.Fatal("Expected a bot to make room")
+	}
+	arena.AddPlayer(2, TeamOrder)
+	gs.Bots.SetFill(arena.ID, 2)
+	gs.Bots.Update(gs, now)
+	if count, bots := arena.GetPlayerCount(), len(gs.Bots.ArenaBots(arena.ID)); count != 2 || bots != 0 {
+		t.Errorf("Expected bots to leave as players fill the arena, got %d players and %d bots", count, bots)
+	}
+	if !gs.Bots.MakeRoom(gs, arena) {
+		t.Error("Expected an arena with open slots to have room")
+	}
+	arena.AddPlayer(3, TeamOrder)
+	arena.AddPlayer(4, TeamOrder)
+	if gs.Bots.MakeRoom(gs, arena) {
+		t.Error("Expected a full arena without bots to have no room")
+	}
+
+	// Bots added by hand stay when the arena has no fill target
+	other := newTestArena(2)
+	gs.ArenaManager.Arenas[other.ID] = other
+	gs.Bots.AddBot(gs, other, TeamNone)
+	gs.Bots.Update(gs, now)
+	if len(gs.Bots.ArenaBots(other.ID)) != 1 {
+		t.Error("Expected the added bot to stay")
+	}
+
+	// Bots sent out of their arena leave the game
+	bot := gs.Bots.ArenaBots(other.ID)[0]
+	other.RemovePlayer(bot.Player.ID)
+	gs.Bots.Update(gs, now)
+	if gs.Bots.IsBot(bot.Player.ID) {
+		t.Error("Expected a bot no longer in its arena to be dropped")
+	}
+}
+
+func TestBotsCommand(t *testing.T) {
+	gs, arena := newBotGame(t)
+	player := addTestCaster(gs, 1)
+
+	t.Setenv("ADMIN_PASSWORD", "")
+	if reply := commandAdmin([]string{""}, player, gs); player.Admin {
+		t.Errorf("Expected no admins without ADMIN_PASSWORD, got %q", reply)
+	}
+	if reply := commandBots([]string{"add", "1"}, player, gs); !strings.Contains(reply, "Only admins") {
+		t.Errorf("Expected bots to be for admins only, got %q", reply)
+	}
+
+	t.Setenv("ADMIN_PASSWORD", "hunter2")
+	if commandAdmin([]string{"hunter3"}, player, gs); player.Admin {
+		t.Error("Expected the wrong password to be refused")
+	}
+	if reply := commandAdmin([]string{"hunter2"}, player, gs); !player.Admin {
+		t.Fatalf("Expected the password to sign in, got %q", reply)
+	}
+	if reply := commandBots([]string{"add", "1", "2", "order"}, player, gs); reply != "Added 2 bots to Test Arena" {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+	for _, bot := range gs.Bots.ArenaBots(arena.ID) {
+		if team := arena.GetPlayer(bot.Player.ID).Team; team != TeamOrder {
+			t.Errorf("Expected bots on order, got %s", team)
+		}
+	}
+	if reply := commandBots(nil, player, gs); !strings.Contains(reply, "1 Test Arena: 2 bots (fill 0)") {
+		t.Errorf("Expected the bots listed, got %q", reply)
+	}
+	if reply := commandBots([]string{"fill", "1", "6"}, player, gs); gs.Bots.Fill(arena.ID) != 6 {
+		t.Errorf("Expected the fill target set, got %q", reply)
+	}
+	if reply := commandBots([]string{"remove", "1"}, player, gs); reply != "Removed 2 bots from Test Arena" {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+	for _, args := range [][]string{{"add"}, {"add", "9"}, {"add", "1", "x"}, {"add", "1", "1", "purple"}, {"fill", "1"}, {"kick", "1"}} {
+		if reply := commandBots(args, player, gs); strings.HasPrefix(reply, "Added") || strings.HasPrefix(reply, "Removed") {
+			t.Errorf("Expected %v to be refused, got %q", args, reply)
+		}
+	}
+}
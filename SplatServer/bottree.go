@@ -0,0 +1,620 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	botMoveSpeed      = 240.0           // world units a bot walks a second
+	botSightRange     = interestRadius  // distance within which bots notice enemies
+	botRetreatHealth  = 0.3             // share of max health below which bots heal or fall back
+	botRepathInterval = 2 * time.Second // how long a path is followed before it is planned again
+	botTriggerWait    = 5 * time.Second // how long a bot waits on a door or elevator before planning again
+	botStuckMoves     = 5               // blocked moves in a row before a bot plans again
+	botRoamDistance   = 512.0           // how far bots wander at a time in arenas without a grid
+	botArriveDistance = 8.0             // distance at which a bot has reached a waypoint
+)
+
+// btStatus is the result of ticking a behaviour tree node
+type btStatus int
+
+const (
+	btSuccess btStatus = iota
+	btFailure
+	btRunning // the node is still at work and is ticked again on the next think
+)
+
+// btNode is a node of a bot's behaviour tree
+type btNode interface {
+	tick(c *botContext) btStatus
+}
+
+// btSelector ticks its children in order until one succeeds or is running
+type btSelector []btNode
+
+func (s btSelector) tick(c *botContext) btStatus {
+	for _, child := range s {
+		if status := child.tick(c); status != btFailure {
+			return status
+		}
+	}
+	return btFailure
+}
+
+// btSequence ticks its children in order until one fails or is running
+type btSequence []btNode
+
+func (s btSequence) tick(c *botContext) btStatus {
+	for _, child := range s {
+		if status := child.tick(c); status != btSuccess {
+			return status
+		}
+	}
+	return btSuccess
+}
+
+// btCondition succeeds when its test holds
+type btCondition func(c *botContext) bool
+
+func (f btCondition) tick(c *botContext) btStatus {
+	if f(c) {
+		return btSuccess
+	}
+	return btFailure
+}
+
+// btAction is a leaf that does something
+type btAction func(c *botContext) btStatus
+
+func (f btAction) tick(c *botContext) btStatus { return f(c) }
+
+// botBrain is the behaviour tree every bot runs. The dead wait to be raised. During a
+// match the wounded heal themselves or fall back to their start, then bots raise fallen
+// allies, fight the nearest enemy they can see and go after the arena's objectives.
+// Otherwise they wander
+var botBrain btNode = btSelector{
+	btSequence{btCondition(botDead), btAction(botAwaitRaise)},
+	btSequence{btCondition(botInMatch), btSelector{
+		btSequence{btCondition(botWounded), btSelector{btAction(botHealSelf), btAction(botRetreat)}},
+		btAction(botRaiseAlly),
+		btSequence{btAction(botPickTarget), btSelector{btAction(botAttack), btAction(botChase)}},
+		btAction(botPursueObjective),
+	}},
+	btAction(botRoam),
+}
+
+// botSpellRole is what a bot uses a spell for
+type botSpellRole int
+
+const (
+	botSpellUnused botSpellRole = iota
+	botSpellAttack              // bolts, projectiles and targeted spells that damage enemies
+	botSpellHeal                // targeted spells that heal the caster
+	botSpellRaise               // targeted spells that raise dead allies
+)
+
+// botSpellRoleOf returns what bots use a spell for, judged by how CastSpell delivers it
+func botSpellRoleOf(sm *SpellManager, spell *Spell) botSpellRole {
+	switch spell.Type {
+	case SpellTypeTarget:
+		effect := sm.linkedSpell(spell.TargetSpellID, spell)
+		switch {
+		case spell.FriendlyType == SpellFriendlyEnemy && effect.Damage > 0:
+			return botSpellAttack
+		case (spell.FriendlyType == SpellFriendlyAlly || spell.FriendlyType == SpellFriendlySelf) && effect.Healing > 0:
+			return botSpellHeal
+		case spell.FriendlyType == SpellFriendlyDead && effect.EffectType == SpellEffectResurrect:
+			return botSpellRaise
+		}
+	case SpellTypeWall, SpellTypeRune, SpellTypeTeleport, SpellTypeDispell:
+	default:
+		if spell.Damage > 0 && (spell.ProjectileType == SpellProjectileBolt || spell.Speed > 0) {
+			return botSpellAttack
+		}
+	}
+	return botSpellUnused
+}
+
+// botSpellRange returns how far a spell reaches for a bot's purposes
+func botSpellRange(spell *Spell) float64 {
+	switch {
+	case spell.Range > 0:
+		return spell.Range
+	case spell.ProjectileType == SpellProjectileBolt:
+		return defaultBoltRange
+	case spell.Type == SpellTypeTarget:
+		return math.Inf(1)
+	}
+	return botSightRange
+}
+
+// botContext is what a bot knows while it thinks: a copy of its arena taken under the
+// arena's lock, so the tree can look around without holding it
+type botContext struct {
+	gs       *GameState
+	arena    *Arena
+	bot      *Bot
+	now      time.Time
+	dt       float64 // seconds since the bot last thought
+	self     ArenaPlayer
+	others   []ArenaPlayer // everyone else in the arena, by player ID
+	state    ArenaState
+	rules    ArenaRule
+	grid     *Grid
+	spawn    TeamSpawn
+	hasSpawn bool
+	orbs     []CTFOrb
+	shrines  []Shrine
+	pools    []Pool
+	target   *ArenaPlayer // the enemy being fought this think
+}
+
+// newBotContext copies what a bot can see of its arena, and false when it is no longer in it
+func newBotContext(gs *GameState, arena *Arena, bot *Bot, now time.Time, dt float64) (*botContext, bool) {
+	arena.mu.RLock()
+	defer arena.mu.RUnlock()
+
+	self, exists := arena.Players[bot.Player.ID]
+	if !exists {
+		return nil, false
+	}
+	c := &botContext{
+		gs:    gs,
+		arena: arena,
+		bot:   bot,
+		now:   now,
+		dt:    dt,
+		self:  *self,
+		state: arena.State,
+		rules: arena.Ruleset.Rules,
+		grid:  arena.Grid,
+	}
+	c.spawn, c.hasSpawn = arena.spawnLocked(self.Team)
+	for _, player := range arena.Players {
+		if player != self {
+			c.others = append(c.others, *player)
+		}
+	}
+	sort.Slice(c.others, func(i, j int) bool { return c.others[i].PlayerID < c.others[j].PlayerID })
+	for _, orb := range arena.Orbs {
+		c.orbs = append(c.orbs, *orb)
+	}
+	sort.Slice(c.orbs, func(i, j int) bool { return c.orbs[i].Team < c.orbs[j].Team })
+	for _, shrine := range arena.Shrines {
+		c.shrines = append(c.shrines, *shrine)
+	}
+	sort.Slice(c.shrines, func(i, j int) bool { return c.shrines[i].ID < c.shrines[j].ID })
+	for _, pool := range arena.Pools {
+		c.pools = append(c.pools, *pool)
+	}
+	return c, true
+}
+
+// think runs the bot's behaviour tree once
+func (b *Bot) think(gs *GameState, arena *Arena, now time.Time) {
+	dt := botThinkInterval.Seconds()
+	if !b.lastThink.IsZero() {
+		dt = math.Min(now.Sub(b.lastThink).Seconds(), 0.5)
+	}
+	b.lastThink = now
+
+	c, ok := newBotContext(gs, arena, b, now, dt)
+	if !ok {
+		return
+	}
+	// Raised, respawned or sent through a teleporter since the last move
+	if b.moved && math.Hypot(c.self.X-b.movedX, c.self.Y-b.movedY) > blockSize {
+		b.clearPath()
+	}
+	b.moved = false
+	if !c.self.Dead {
+		b.calledRaise = false
+	}
+	botBrain.tick(c)
+}
+
+func botDead(c *botContext) bool { return c.self.Dead }
+
+func botInMatch(c *botContext) bool { return c.state == ArenaStateActive }
+
+func botWounded(c *botContext) bool {
+	return float64(c.self.Health) < float64(c.self.maxHealth())*botRetreatHealth
+}
+
+// botAwaitRaise has a dead bot call for a raise once and wait
+func botAwaitRaise(c *botContext) btStatus {
+	c.bot.clearPath()
+	if !c.bot.calledRaise && c.self.Team != TeamNone && !c.rules.Has(ArenaRuleNoRaiseCall) {
+		c.arena.CallForRaise(c.self.PlayerID)
+		c.bot.calledRaise = true
+	}
+	return btRunning
+}
+
+// botHealSelf casts the bot's strongest ready healing spell on itself
+func botHealSelf(c *botContext) btStatus {
+	for _, spell := range c.spells(botSpellHeal) {
+		if c.cast(spell, c.self.X, c.self.Y, c.self.PlayerID) {
+			return btSuccess
+		}
+	}
+	return btFailure
+}
+
+// botRetreat falls back to the bot's start point, or away from the nearest enemy in an
+// arena without one, failing once there is nowhere further to go
+func botRetreat(c *botContext) btStatus {
+	if c.hasSpawn {
+		return c.moveTo(c.spawn.Start.X, c.spawn.Start.Y, blockSize)
+	}
+	enemy := c.nearestEnemy(false)
+	if enemy == nil {
+		return btFailure
+	}
+	angle := math.Atan2(c.self.Y-enemy.Y, c.self.X-enemy.X)
+	return c.step(c.self.X+math.Cos(angle)*botMoveSpeed, c.self.Y+math.Sin(angle)*botMoveSpeed)
+}
+
+// botRaiseAlly goes to the nearest dead ally and raises them, when the bot has a raise
+// spell ready
+func botRaiseAlly(c *botContext) btStatus {
+	spells := c.spells(botSpellRaise)
+	if len(spells) == 0 || c.rules.Has(ArenaRuleNoRaiseCall) {
+		return btFailure
+	}
+	var fallen *ArenaPlayer
+	for i := range c.others {
+		other := &c.others[i]
+		if other.Dead && c.allied(other) && (fallen == nil || c.distance(other.X, other.Y) < c.distance(fallen.X, fallen.Y)) {
+			fallen = other
+		}
+	}
+	if fallen == nil {
+		return btFailure
+	}
+	for _, spell := range spells {
+		if inRange(spell, c.self.X, c.self.Y, fallen.X, fallen.Y) && c.cast(spell, fallen.X, fallen.Y, fallen.PlayerID) {
+			return btSuccess
+		}
+	}
+	return c.moveTo(fallen.X, fallen.Y, botArriveDistance)
+}
+
+// botPickTarget chooses the enemy to fight: the one the bot is already fighting while it
+// stays in sight, or else the nearest visible enemy
+func botPickTarget(c *botContext) btStatus {
+	for i := range c.others {
+		if other := &c.others[i]; other.PlayerID == c.bot.targetID && c.canFight(other) {
+			c.target = other
+			return btSuccess
+		}
+	}
+	c.target = c.nearestEnemy(true)
+	if c.target == nil {
+		c.bot.targetID = 0
+		return btFailure
+	}
+	c.bot.targetID = c.target.PlayerID
+	return btSuccess
+}
+
+// botAttack casts the most damaging ready spell that reaches the target. It holds
+// position while the spells in reach cool down, and fails when none reach
+func botAttack(c *botContext) btStatus {
+	distance := c.distance(c.target.X, c.target.Y)
+	inReach := false
+	for _, spell := range c.knownSpells(botSpellAttack) {
+		if distance > botSpellRange(spell) {
+			continue
+		}
+		inReach = true
+		if c.gs.SpellSystem.canCastSpell(c.self.PlayerID, spell.ID) && c.cast(spell, c.target.X, c.target.Y, c.target.PlayerID) {
+			return btSuccess
+		}
+	}
+	if inReach {
+		c.bot.clearPath()
+		return btRunning
+	}
+	return btFailure
+}
+
+// botChase walks toward the target until a spell reaches it
+func botChase(c *botContext) btStatus {
+	return c.moveTo(c.target.X, c.target.Y, botArriveDistance)
+}
+
+// botPursueObjective goes after the arena's objectives: in capture the flag the bot
+// carries an enemy orb home, returns its own dropped orb or takes an enemy orb; otherwise
+// it goes to the nearest enemy shrine or pool its team does not hold and biases it
+func botPursueObjective(c *botContext) btStatus {
+	if c.self.Team == TeamNone {
+		return btFailure
+	}
+	if c.rules.Has(ArenaRuleCaptureTheFlag) {
+		return c.pursueOrbs()
+	}
+
+	x, y, best := 0.0, 0.0, math.Inf(1)
+	shrineID, poolID := 0, 0
+	if !c.rules.Has(ArenaRuleNoShrineBiasing) {
+		for _, shrine := range c.shrines {
+			if shrine.Team != c.self.Team && !shrine.IsDead() && !shrine.IsIndestructible() && c.distance(shrine.X, shrine.Y) < best {
+				x, y, best, shrineID, poolID = shrine.X, shrine.Y, c.distance(shrine.X, shrine.Y), shrine.ID, 0
+			}
+		}
+	}
+	if !c.rules.Has(ArenaRuleNoPoolBiasing) {
+		for _, pool := range c.pools {
+			held := pool.Team == c.self.Team && pool.IsFullyBiased()
+			if pool.HasPosition && !held && c.distance(pool.X, pool.Y) < best {
+				x, y, best, shrineID, poolID = pool.X, pool.Y, c.distance(pool.X, pool.Y), 0, pool.ID
+			}
+		}
+	}
+	if math.IsInf(best, 1) {
+		return btFailure
+	}
+	if status := c.moveTo(x, y, objectiveRadius/2); status != btSuccess {
+		return status
+	}
+
+	// Standing there biases it too; casting bias on top is what players do
+	if c.now.Sub(c.bot.biasedAt) >= biasCooldown {
+		c.bot.biasedAt = c.now
+		if shrineID != 0 {
+			c.arena.BiasShrine(c.self.PlayerID, shrineID)
+		} else {
+			c.arena.BiasPool(c.self.PlayerID, poolID)
+		}
+	}
+	return btRunning
+}
+
+// pursueOrbs moves toward whichever orb matters most to the bot
+func (c *botContext) pursueOrbs() btStatus {
+	own := c.orb(c.self.Team)
+	var enemy *CTFOrb
+	for i := range c.orbs {
+		orb := &c.orbs[i]
+		switch {
+		case orb.Team == c.self.Team:
+		case orb.CarrierID == c.self.PlayerID:
+			// Captures only count once the bot's own orb is home, so a dropped one is returned first
+			if own == nil {
+				return btFailure
+			}
+			if own.State == OrbOnGround {
+				return c.moveTo(own.X, own.Y, orbTouchRadius/2)
+			}
+			return c.moveTo(own.HomeX, own.HomeY, orbTouchRadius/2)
+		case orb.State != OrbOnEnemyPlayer && (enemy == nil || c.distance(orb.X, orb.Y) < c.distance(enemy.X, enemy.Y)):
+			enemy = orb
+		}
+	}
+	if own != nil && own.State == OrbOnGround {
+		return c.moveTo(own.X, own.Y, orbTouchRadius/2)
+	}
+	if enemy != nil {
+		return c.moveTo(enemy.X, enemy.Y, orbTouchRadius/2)
+	}
+	return btFailure
+}
+
+// orb returns a team's orb
+func (c *botContext) orb(team Team) *CTFOrb {
+	for i := range c.orbs {
+		if c.orbs[i].Team == team {
+			return &c.orbs[i]
+		}
+	}
+	return nil
+}
+
+// botRoam wanders to random places the bot can walk to
+func botRoam(c *botContext) btStatus {
+	b := c.bot
+	if !b.roaming || c.distance(b.roamX, b.roamY) <= botArriveDistance {
+		x, y, ok := c.roamPoint()
+		if !ok {
+			return btFailure
+		}
+		b.roamX, b.roamY, b.roaming = x, y, true
+	}
+	if status := c.moveTo(b.roamX, b.roamY, botArriveDistance); status != btRunning {
+		b.roaming = false
+	}
+	return btRunning
+}
+
+// roamPoint picks somewhere for the bot to wander to: a walkable block on a grid, or a
+// nearby point in an open arena
+func (c *botContext) roamPoint() (float64, float64, bool) {
+	if c.grid == nil {
+		angle := rand.Float64() * 2 * math.Pi
+		return c.self.X + math.Cos(angle)*botRoamDistance, c.self.Y + math.Sin(angle)*botRoamDistance, true
+	}
+	graph := c.grid.NavGraph()
+	for try := 0; try < 20; try++ {
+		node := rand.Intn(gridBlockCount)
+		if graph.walkable[node] {
+			return float64(node%gridWidth)*blockSize + blockSize/2, float64(node/gridWidth)*blockSize + blockSize/2, true
+		}
+	}
+	return 0, 0, false
+}
+
+// distance returns how far a point is from the bot
+func (c *botContext) distance(x, y float64) float64 {
+	return math.Hypot(x-c.self.X, y-c.self.Y)
+}
+
+// allied reports whether another player is on the bot's team
+func (c *botContext) allied(other *ArenaPlayer) bool {
+	return !c.rules.Has(ArenaRuleNoTeams) && c.self.Team != TeamNone && other.Team == c.self.Team
+}
+
+// canFight reports whether another player is a living enemy the bot can see
+func (c *botContext) canFight(other *ArenaPlayer) bool {
+	return !other.Dead && !c.allied(other) && c.distance(other.X, other.Y) <= botSightRange &&
+		c.arena.HasLineOfSight(c.self.X, c.self.Y, other.X, other.Y)
+}
+
+// nearestEnemy returns the nearest living enemy, only those the bot can see when visible is set
+func (c *botContext) nearestEnemy(visible bool) *ArenaPlayer {
+	var nearest *ArenaPlayer
+	for i := range c.others {
+		other := &c.others[i]
+		if other.Dead || c.allied(other) || (nearest != nil && c.distance(other.X, other.Y) >= c.distance(nearest.X, nearest.Y)) {
+			continue
+		}
+		if visible && !c.canFight(other) {
+			continue
+		}
+		nearest = other
+	}
+	return nearest
+}
+
+// knownSpells returns the spells in the bot's spellbook used for a role, strongest first
+func (c *botContext) knownSpells(role botSpellRole) []*Spell {
+	sm := c.gs.SpellSystem.SpellManager
+	var spells []*Spell
+	for _, id := range c.bot.Player.Spellbook.GetSpells() {
+		if spell := sm.GetSpell(id); spell != nil && botSpellRoleOf(sm, spell) == role {
+			spells = append(spells, spell)
+		}
+	}
+	power := func(spell *Spell) int {
+		effect := sm.linkedSpell(spell.TargetSpellID, spell)
+		return effect.Damage + effect.Healing + effect.RaisePercent
+	}
+	sort.SliceStable(spells, func(i, j int) bool { return power(spells[i]) > power(spells[j]) })
+	return spells
+}
+
+// spells returns the bot's spells for a role that are off cooldown, strongest first
+func (c *botContext) spells(role botSpellRole) []*Spell {
+	var ready []*Spell
+	for _, spell := range c.knownSpells(role) {
+		if c.gs.SpellSystem.canCastSpell(c.self.PlayerID, spell.ID) {
+			ready = append(ready, spell)
+		}
+	}
+	return ready
+}
+
+// cast casts a spell the way a player's cast packet does, reporting whether it went off
+func (c *botContext) cast(spell *Spell, x, y float64, targetID int) bool {
+	_, err := c.gs.CastSpell(c.self.PlayerID, spell.ID, x, y, targetID)
+	return err == nil
+}
+
+// moveTo walks the bot toward a point along a path through the arena, succeeding once it
+// is within reach and failing when there is no way there
+func (c *botContext) moveTo(x, y, reach float64) btStatus {
+	b := c.bot
+	if c.distance(x, y) <= reach {
+		b.clearPath()
+		return btSuccess
+	}
+	stale := b.waitSince.IsZero() && c.now.Sub(b.plannedAt) >= botRepathInterval
+	if !b.hasGoal || math.Hypot(x-b.goalX, y-b.goalY) > blockSize || (len(b.path) == 0 && !b.unreachable) || stale {
+		c.plan(x, y)
+	}
+	if b.unreachable {
+		return btFailure
+	}
+	return c.followPath()
+}
+
+// plan finds a path to a point, straight there in an arena without a grid
+func (c *botContext) plan(x, y float64) {
+	b := c.bot
+	b.clearPath()
+	b.goalX, b.goalY, b.hasGoal, b.plannedAt = x, y, true, c.now
+	if c.grid == nil {
+		b.path = []Waypoint{{X: x, Y: y}}
+		return
+	}
+	path, ok := c.arena.FindPath(c.self.X, c.self.Y, x, y)
+	if !ok {
+		b.unreachable = true
+		return
+	}
+	// The path ends at the goal's block centre; finish at the goal itself
+	b.path = append(path, Waypoint{X: x, Y: y})
+}
+
+// followPath steps the bot along its path, setting off doors and elevators and waiting
+// for them to get where the path needs them
+func (c *botContext) followPath() btStatus {
+	b := c.bot
+	if len(b.path) == 0 {
+		return btFailure
+	}
+	waypoint := b.path[0]
+	if waypoint.TriggerID != 0 {
+		on, moving, exists := c.arena.TriggerState(waypoint.TriggerID)
+		if exists && (on != waypoint.TriggerOn || moving) {
+			if b.waitSince.IsZero() {
+				b.waitSince = c.now
+			}
+			if c.now.Sub(b.waitSince) > botTriggerWait {
+				b.clearPath()
+				return btRunning
+			}
+			if on != waypoint.TriggerOn {
+				c.arena.ActivateTrigger(c.self.PlayerID, waypoint.TriggerID)
+			}
+			return btRunning
+		}
+		b.waitSince = time.Time{}
+	}
+
+	if status := c.step(waypoint.X, waypoint.Y); status != btSuccess {
+		return status
+	}
+	b.path = b.path[1:]
+	if waypoint.Teleport {
+		// The pad sends the bot on from one of its destinations
+		b.clearPath()
+	}
+	return btRunning
+}
+
+// step moves the bot as far toward a point as it walks in one think, through the same
+// movement checks as players, succeeding when it gets there
+func (c *botContext) step(x, y float64) btStatus {
+	b := c.bot
+	distance := c.distance(x, y)
+	if travel := botMoveSpeed * c.dt; distance > travel {
+		x = c.self.X + (x-c.self.X)/distance*travel
+		y = c.self.Y + (y-c.self.Y)/distance*travel
+	}
+	if !c.arena.UpdatePlayerPosition(c.self.PlayerID, x, y) {
+		if b.blocked++; b.blocked >= botStuckMoves {
+			b.clearPath()
+		}
+		return btRunning
+	}
+	b.blocked = 0
+	b.movedX, b.movedY, b.moved = x, y, true
+	c.self.X, c.self.Y = x, y
+	if distance <= botMoveSpeed*c.dt {
+		return btSuccess
+	}
+	return btRunning
+}
+
+// clearPath forgets the bot's goal so the next move plans afresh
+func (b *Bot) clearPath() {
+	b.path = nil
+	b.hasGoal = false
+	b.unreachable = false
+	b.blocked = 0
+	b.waitSince = time.Time{}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBehaviourTreeNodes(t *testing.T) {
+	var ticked []string
+	leaf := func(name string, status btStatus) btNode {
+		return btAction(func(c *botContext) btStatus {
+			ticked = append(ticked, name)
+			return status
+		})
+	}
+	tests := []struct {
+		name   string
+		node   btNode
+		status btStatus
+		ticked string
+	}{
+		{"selector stops at success", btSelector{leaf("a", btFailure), leaf("b", btSuccess), leaf("c", btSuccess)}, btSuccess, "ab"},
+		{"selector stops while running", btSelector{leaf("a", btRunning), leaf("b", btSuccess)}, btRunning, "a"},
+		{"selector fails when all fail", btSelector{leaf("a", btFailure), leaf("b", btFailure)}, btFailure, "ab"},
+		{"sequence stops at failure", btSequence{leaf("a", btSuccess), leaf("b", btFailure), leaf("c", btSuccess)}, btFailure, "ab"},
+		{"sequence stops while running", btSequence{leaf("a", btRunning), leaf("b", btSuccess)}, btRunning, "a"},
+		{"sequence succeeds when all succeed", btSequence{leaf("a", btSuccess), leaf("b", btSuccess)}, btSuccess, "ab"},
+		{"condition guards", btSequence{btCondition(func(*botContext) bool { return false }), leaf("a", btSuccess)}, btFailure, ""},
+	}
+	for _, tt := range tests {
+		ticked = nil
+		if status := tt.node.tick(&botContext{}); status != tt.status || strings.Join(ticked, "") != tt.ticked {
+			t.Errorf("%s: expected %d after ticking %q, got %d after %q", tt.name, tt.status, tt.ticked, status, strings.Join(ticked, ""))
+		}
+	}
+}
+
+func TestBotSpellRoles(t *testing.T) {
+	sm := NewSpellManager()
+	sm.LoadSpells([]*Spell{
+		{ID: 900, Type: SpellTypeEffect, Damage: 20},
+		{ID: 901, Type: SpellTypeEffect, Healing: 40},
+		{ID: 902, Type: SpellTypeEffect, EffectType: SpellEffectResurrect, RaisePercent: 30},
+	})
+	tests := []struct {
+		name  string
+		spell *Spell
+		role  botSpellRole
+	}{
+		{"bolt", &Spell{Type: SpellTypeBolt, ProjectileType: SpellProjectileBolt, Damage: 10}, botSpellAttack},
+		{"projectile", &Spell{Type: SpellTypeProjectile, Speed: 400, Damage: 10}, botSpellAttack},
+		{"harmless projectile", &Spell{Type: SpellTypeProjectile, Speed: 400}, botSpellUnused},
+		{"targeted damage", &Spell{Type: SpellTypeTarget, FriendlyType: SpellFriendlyEnemy, TargetSpellID: 900}, botSpellAttack},
+		{"targeted heal", &Spell{Type: SpellTypeTarget, FriendlyType: SpellFriendlyAlly, TargetSpellID: 901}, botSpellHeal},
+		{"self heal", &Spell{Type: SpellTypeTarget, FriendlyType: SpellFriendlySelf, Healing: 15}, botSpellHeal},
+		{"raise", &Spell{Type: SpellTypeTarget, FriendlyType: SpellFriendlyDead, TargetSpellID: 902}, botSpellRaise},
+		{"wall", &Spell{Type: SpellTypeWall, Damage: 10, Speed: 1}, botSpellUnused},
+		{"teleport", &Spell{Type: SpellTypeTeleport}, botSpellUnused},
+	}
+	for _, tt := range tests {
+		if role := botSpellRoleOf(sm, tt.spell); role != tt.role {
+			t.Errorf("%s: expected role %d, got %d", tt.name, tt.role, role)
+		}
+	}
+}
+
+// newBotDuel returns an active open arena with a bot on chaos at the origin knowing the
+// given spells and a player on order at a distance along x
+func newBotDuel(t *testing.T, distance float64, spells []*Spell) (*GameState, *Arena, *Bot) {
+	gs, arena := newBotGame(t)
+	gs.SpellSystem.SpellManager.LoadSpells(spells)
+	bot, err := gs.Bots.AddBot(gs, arena, TeamChaos)
+	if err != nil {
+		t.Fatalf("AddBot failed: %v", err)
+	}
+	bot.Player.Spellbook.Spells = nil
+	for _, spell := range spells {
+		bot.Player.Spellbook.Spells = append(bot.Player.Spellbook.Spells, spell.ID)
+	}
+	addTestCaster(gs, 1)
+	arena.AddPlayer(1, TeamOrder)
+	arena.UpdatePlayerPosition(1, distance, 0)
+	arena.StartArena()
+	return gs, arena, bot
+}
+
+func TestBotAttacks(t *testing.T) {
+	bolt := &Spell{ID: 900, Name: "Test Bolt", Type: SpellTypeBolt, ProjectileType: SpellProjectileBolt, Damage: 10, Range: 500, Cooldown: time.Minute}
+	gs, arena, bot := newBotDuel(t, 200, []*Spell{bolt})
+	now := time.Now()
+
+	bot.think(gs, arena, now)
+	if health := arena.GetPlayer(1).Health; health != 90 || bot.targetID != 1 {
+		t.Fatalf("Expected the bot to bolt the nearby enemy, got health %d and target %d", health, bot.targetID)
+	}
+	// While the bolt cools down the bot holds its ground
+	bot.think(gs, arena, now.Add(botThinkInterval))
+	if player := arena.GetPlayer(bot.Player.ID); player.X != 0 || player.Y != 0 {
+		t.Errorf("Expected the bot to hold position, got (%.0f, %.0f)", player.X, player.Y)
+	}
+
+	// Enemies out of reach are chased
+	arena.UpdatePlayerPosition(1, 1000, 0)
+	bot.think(gs, arena, now.Add(2*botThinkInterval))
+	if player := arena.GetPlayer(bot.Player.ID); player.X <= 0 {
+		t.Errorf("Expected the bot to close in, got (%.0f, %.0f)", player.X, player.Y)
+	}
+
+	// Allies are left alone
+	arena.mu.Lock()
+	arena.setTeamLocked(arena.Players[1], TeamChaos)
+	arena.mu.Unlock()
+	bot.targetID = 0
+	if c, _ := newBotContext(gs, arena, bot, now, 0.1); botPickTarget(c) != btFailure {
+		t.Error("Expected no target among allies")
+	}
+}
+
+func TestBotHealsWhenWounded(t *testing.T) {
+	heal := &Spell{ID: 901, Name: "Test Heal", Type: SpellTypeTarget, FriendlyType: SpellFriendlyAlly, Range: 300, Cooldown: time.Minute, TargetSpellID: 902}
+	effect := &Spell{ID: 902, Name: "Test Heal Effect", Type: SpellTypeEffect, Healing: 40}
+	bolt := &Spell{ID: 900, Name: "Test Bolt", Type: SpellTypeBolt, ProjectileType: SpellProjectileBolt, Damage: 10, Range: 500}
+	gs, arena, bot := newBotDuel(t, 200, []*Spell{heal, effect, bolt})
+
+	arena.mu.Lock()
+	self := arena.Players[bot.Player.ID]
+	self.Health = self.MaxHealth / 10
+	wounded := self.Health
+	arena.mu.Unlock()
+
+	now := time.Now()
+	bot.think(gs, arena, now)
+	if health := arena.GetPlayer(bot.Player.ID).Health; health != wounded+40 {
+		t.Errorf("Expected the bot to heal itself by 40 from %d, got %d", wounded, health)
+	}
+	if arena.GetPlayer(1).Health != 100 {
+		t.Error("Expected the bot to heal before fighting")
+	}
+
+	// With its heal cooling down and no start point it falls back from the enemy
+	arena.mu.Lock()
+	self.Health = self.MaxHealth / 10
+	arena.mu.Unlock()
+	bot.think(gs, arena, now.Add(botThinkInterval))
+	if player := arena.GetPlayer(bot.Player.ID); player.X >= 0 {
+		t.Errorf("Expected the bot to back away, got (%.0f, %.0f)", player.X, player.Y)
+	}
+}
+
+func TestDeadBotCallsForRaise(t *testing.T) {
+	gs, arena := newBotGame(t)
+	bot, _ := gs.Bots.AddBot(gs, arena, TeamChaos)
+	arena.AddPlayer(1, TeamChaos)
+	teammate := &captureConn{}
+	arena.SetPlayerConn(1, teammate)
+	arena.AddPlayer(2, TeamOrder)
+	arena.StartArena()
+
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[bot.Player.ID], 2)
+	arena.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		bot.think(gs, arena, now.Add(time.Duration(i)*botThinkInterval))
+	}
+	if calls := strings.Count(teammate.written.String(), "calls for a raise"); calls != 1 {
+		t.Errorf("Expected one call for a raise, got %d", calls)
+	}
+	if player := arena.GetPlayer(bot.Player.ID); !player.Dead || player.X != 0 || player.Y != 0 {
+		t.Errorf("Expected the dead bot to stay where it fell, got %+v", player)
+	}
+}
+
+func TestBotWalksThroughDoor(t *testing.T) {
+	gs := NewGameState()
+	gs.Bots.Level = defaultBotLevel
+	arena := newNavArena(t)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	arena.RemovePlayer(1)
+	bot, err := gs.Bots.AddBot(gs, arena, TeamChaos)
+	if err != nil {
+		t.Fatalf("AddBot failed: %v", err)
+	}
+	arena.mu.Lock()
+	arena.movePlayerLocked(arena.Players[bot.Player.ID], 300, 96)
+	arena.mu.Unlock()
+
+	// The door in the wall along column 10 is closed; the bot opens it and walks through
+	now := time.Now()
+	for i := 0; i < 200; i++ {
+		c, _ := newBotContext(gs, arena, bot, now, botThinkInterval.Seconds())
+		if c.moveTo(800, 96, botArriveDistance) == btSuccess {
+			break
+		}
+		now = now.Add(botThinkInterval)
+		arena.mu.Lock()
+		arena.updateTriggersLocked(now)
+		arena.mu.Unlock()
+	}
+	if player := arena.GetPlayer(bot.Player.ID); player.X < 790 {
+		t.Fatalf("Expected the bot to get through the door, got to (%.0f, %.0f)", player.X, player.Y)
+	}
+	if on, _, _ := arena.TriggerState(1); !on {
+		t.Error("Expected the bot to have opened the door")
+	}
+
+	// Behind the standing thin there is no way through, and the bot gives up
+	c, _ := newBotContext(gs, arena, bot, now, botThinkInterval.Seconds())
+	if status := c.moveTo(2000, 96, botArriveDistance); status != btFailure || !bot.unreachable {
+		t.Errorf("Expected no way past the thin, got %d", status)
+	}
+}
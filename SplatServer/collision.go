@@ -0,0 +1,376 @@
+package main
+
+import (
+	"math"
+)
+
+// Player body dimensions for grid collision. The server keeps players on the floor, so
+// a player is an upright box from maxStepHeight to playerHeight above the floor they
+// stand on: anything lower can be stepped onto and anything higher is headroom
+const (
+	playerHeight  = 80.0    // headroom a player needs
+	maxStepHeight = 64.0    // highest floor a player can step up onto
+	eyeHeight     = 72.0    // height above the floor that sight lines and bolts travel at
+	gridBottomZ   = -1024.0 // bottom of every low box, as in MageServer
+)
+
+// Vec3 is a point or direction in world units, with Z up
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// Add returns the sum of two vectors
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
+}
+
+// Sub returns the difference of two vectors
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z}
+}
+
+// Scale returns the vector multiplied by s
+func (v Vec3) Scale(s float64) Vec3 {
+	return Vec3{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Length returns the length of the vector
+func (v Vec3) Length() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// OrientedBox is a box turned about the vertical axis, as in MageServer's
+// OrientedBoundingBox
+type OrientedBox struct {
+	Center  Vec3
+	Extents Vec3    // half the size along each of the box's axes
+	Angle   float64 // turn about the vertical axis in radians
+}
+
+// NewOrientedBox returns a box with its lowest corner at location before it is turned
+// about its centre, as MageServer builds them
+func NewOrientedBox(location, size Vec3, angle float64) OrientedBox {
+	extents := size.Scale(0.5)
+	return OrientedBox{Center: location.Add(extents), Extents: extents, Angle: angle}
+}
+
+// toLocal converts a world point into the box's frame, relative to its centre
+func (b OrientedBox) toLocal(p Vec3) Vec3 {
+	d := p.Sub(b.Center)
+	if b.Angle == 0 {
+		return d
+	}
+	cos, sin := math.Cos(b.Angle), math.Sin(b.Angle)
+	return Vec3{d.X*cos + d.Y*sin, -d.X*sin + d.Y*cos, d.Z}
+}
+
+// Expand returns the box grown by half sizes along its own axes. Sweeping a box that is
+// turned the same way reduces to a segment against the expanded box
+func (b OrientedBox) Expand(half Vec3) OrientedBox {
+	b.Extents = b.Extents.Add(half)
+	return b
+}
+
+// ContainsPoint reports whether a point lies strictly inside the box
+func (b OrientedBox) ContainsPoint(p Vec3) bool {
+	l := b.toLocal(p)
+	return math.Abs(l.X) < b.Extents.X && math.Abs(l.Y) < b.Extents.Y && math.Abs(l.Z) < b.Extents.Z
+}
+
+// IntersectSegment returns the fraction along a segment where it first enters the box,
+// 0 when it starts inside
+func (b OrientedBox) IntersectSegment(p1, p2 Vec3) (float64, bool) {
+	l1, l2 := b.toLocal(p1), b.toLocal(p2)
+
+	tMin, tMax := 0.0, 1.0
+	slabs := [3][3]float64{
+		{l1.X, l2.X - l1.X, b.Extents.X},
+		{l1.Y, l2.Y - l1.Y, b.Extents.Y},
+		{l1.Z, l2.Z - l1.Z, b.Extents.Z},
+	}
+	for _, s := range slabs {
+		origin, delta, half := s[0], s[1], s[2]
+		if delta == 0 {
+			if math.Abs(origin) >= half {
+				return 0, false
+			}
+			continue
+		}
+		t1 := (-half - origin) / delta
+		t2 := (half - origin) / delta
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin >= tMax {
+			return 0, false
+		}
+	}
+	return tMin, true
+}
+
+// IntersectRay returns the distance along a ray to where it first enters the box,
+// looking no further than maxDist
+func (b OrientedBox) IntersectRay(origin, direction Vec3, maxDist float64) (float64, bool) {
+	length := direction.Length()
+	if length == 0 {
+		return 0, false
+	}
+	t, hit := b.IntersectSegment(origin, origin.Add(direction.Scale(maxDist/length)))
+	return t * maxDist, hit
+}
+
+// axes returns the box's horizontal axes as unit vectors
+func (b OrientedBox) axes() [2][2]float64 {
+	cos, sin := math.Cos(b.Angle), math.Sin(b.Angle)
+	return [2][2]float64{{cos, sin}, {-sin, cos}}
+}
+
+// Overlaps reports whether two boxes intersect, by separating axes: the vertical axis and
+// the horizontal axes of both boxes
+func (b OrientedBox) Overlaps(o OrientedBox) bool {
+	if math.Abs(b.Center.Z-o.Center.Z) >= b.Extents.Z+o.Extents.Z {
+		return false
+	}
+
+	dx, dy := o.Center.X-b.Center.X, o.Center.Y-b.Center.Y
+	bAxes, oAxes := b.axes(), o.axes()
+	project := func(axes [2][2]float64, extents Vec3, axis [2]float64) float64 {
+		return extents.X*math.Abs(axes[0][0]*axis[0]+axes[0][1]*axis[1]) +
+			extents.Y*math.Abs(axes[1][0]*axis[0]+axes[1][1]*axis[1])
+	}
+	for _, axis := range [4][2]float64{bAxes[0], bAxes[1], oAxes[0], oAxes[1]} {
+		distance := math.Abs(dx*axis[0] + dy*axis[1])
+		if distance >= project(bAxes, b.Extents, axis)+project(oAxes, o.Extents, axis) {
+			return false
+		}
+	}
+	return true
+}
+
+// LowBox returns the block's floor, from the bottom of the world up to its floor height
+func (b *GridBlock) LowBox() OrientedBox {
+	return blockBox(b.Column, b.Row, gridBottomZ, float64(b.FloorZ()))
+}
+
+// MidBox returns the block's ceiling, from its underside up to the sky, or false for a
+// block open to the sky. Everything above a ceiling counts as solid since players never
+// leave the floor
+func (b *GridBlock) MidBox() (OrientedBox, bool) {
+	if b.MidBoxBottomZ >= gridSkyZ {
+		return OrientedBox{}, false
+	}
+	return blockBox(b.Column, b.Row, float64(b.MidBoxBottomZ), gridSkyZ), true
+}
+
+// blockBox returns the box filling a grid cell between two heights
+func blockBox(column, row int, bottom, top float64) OrientedBox {
+	x, y := float64(column)*blockSize, float64(row)*blockSize
+	return NewOrientedBox(Vec3{x, y, bottom}, Vec3{blockSize, blockSize, top - bottom}, 0)
+}
+
+// cellBoxes returns the solid boxes in a grid cell, with floors and ceilings where any
+// elevator has moved them. Cells outside the grid are solid from the bottom of the world
+// to the sky
+func (g *Grid) cellBoxes(column, row int) ([2]OrientedBox, int) {
+	var boxes [2]OrientedBox
+	block := g.Block(column, row)
+	if block == nil {
+		boxes[0] = blockBox(column, row, gridBottomZ, gridSkyZ)
+		return boxes, 1
+	}
+
+	boxes[0] = blockBox(column, row, gridBottomZ, g.floorZ(block))
+	if ceiling := g.ceilingZ(block); ceiling < gridSkyZ {
+		boxes[1] = blockBox(column, row, ceiling, gridSkyZ)
+		return boxes, 2
+	}
+	return boxes, 1
+}
+
+// standingZ returns the floor height under a point, or 0 outside the grid
+func (g *Grid) standingZ(x, y float64) float64 {
+	z, _ := g.FloorHeight(x, y)
+	return z
+}
+
+// PointSolid reports whether a point lies inside solid geometry, a prop, a door or
+// outside the grid
+func (g *Grid) PointSolid(p Vec3) bool {
+	column, row := int(math.Floor(p.X/blockSize)), int(math.Floor(p.Y/blockSize))
+	boxes, n := g.cellBoxes(column, row)
+	for _, box := range append(append(boxes[:n], g.propsIn(column, row)...), g.obstacles...) {
+		if box.ContainsPoint(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceSegment3D returns the fraction along a segment where it first enters solid
+// geometry, a prop, a door or a thin. Boxes the segment starts inside are ignored so traces can leave them
+func (g *Grid) TraceSegment3D(p1, p2 Vec3) (float64, bool) {
+	best, hit := g.traceCells(p1, p2)
+	for _, box := range g.obstacles {
+		if box.ContainsPoint(p1) {
+			continue
+		}
+		if t, ok := box.IntersectSegment(p1, p2); ok && (!hit || t < best) {
+			best, hit = t, true
+		}
+	}
+	return best, hit
+}
+
+// traceCells returns the fraction along a segment where it first enters a block or prop,
+// walking the cells it crosses in order
+func (g *Grid) traceCells(p1, p2 Vec3) (float64, bool) {
+	column, row := int(math.Floor(p1.X/blockSize)), int(math.Floor(p1.Y/blockSize))
+	endColumn, endRow := int(math.Floor(p2.X/blockSize)), int(math.Floor(p2.Y/blockSize))
+	dx, dy := p2.X-p1.X, p2.Y-p1.Y
+
+	// Fractions along the segment at which it next crosses a column or row boundary
+	stepColumn, nextX, deltaX := 0, math.Inf(1), math.Inf(1)
+	if dx != 0 {
+		stepColumn = 1
+		boundary := float64(column+1) * blockSize
+		if dx < 0 {
+			stepColumn, boundary = -1, float64(column)*blockSize
+		}
+		nextX, deltaX = (boundary-p1.X)/dx, blockSize/math.Abs(dx)
+	}
+	stepRow, nextY, deltaY := 0, math.Inf(1), math.Inf(1)
+	if dy != 0 {
+		stepRow = 1
+		boundary := float64(row+1) * blockSize
+		if dy < 0 {
+			stepRow, boundary = -1, float64(row)*blockSize
+		}
+		nextY, deltaY = (boundary-p1.Y)/dy, blockSize/math.Abs(dy)
+	}
+
+	for {
+		best, hit := 1.0, false
+		boxes, n := g.cellBoxes(column, row)
+		for _, box := range append(boxes[:n], g.propsIn(column, row)...) {
+			if box.ContainsPoint(p1) {
+				continue
+			}
+			if t, ok := box.IntersectSegment(p1, p2); ok && t <= best {
+				best, hit = t, true
+			}
+		}
+		if hit {
+			return best, true
+		}
+		if (column == endColumn && row == endRow) || math.Min(nextX, nextY) > 1 {
+			return 0, false
+		}
+		if nextX < nextY {
+			column, nextX = column+stepColumn, nextX+deltaX
+		} else {
+			row, nextY = row+stepRow, nextY+deltaY
+		}
+	}
+}
+
+// Raycast returns the first point of solid geometry along a ray, looking no further
+// than maxDist
+func (g *Grid) Raycast(origin, direction Vec3, maxDist float64) (Vec3, bool) {
+	length := direction.Length()
+	if length == 0 {
+		return origin, false
+	}
+	end := origin.Add(direction.Scale(maxDist / length))
+	t, hit := g.TraceSegment3D(origin, end)
+	if !hit {
+		return end, false
+	}
+	return origin.Add(end.Sub(origin).Scale(t)), true
+}
+
+// boxesNear calls fn with every solid block and prop in the cells an upright box of the
+// given half sizes touches while moving from p1 to p2, and with every door and thin
+func (g *Grid) boxesNear(p1, p2, half Vec3, fn func(OrientedBox)) {
+	minColumn := int(math.Floor((math.Min(p1.X, p2.X) - half.X) / blockSize))
+	maxColumn := int(math.Floor((math.Max(p1.X, p2.X) + half.X) / blockSize))
+	minRow := int(math.Floor((math.Min(p1.Y, p2.Y) - half.Y) / blockSize))
+	maxRow := int(math.Floor((math.Max(p1.Y, p2.Y) + half.Y) / blockSize))
+	for row := minRow; row <= maxRow; row++ {
+		for column := minColumn; column <= maxColumn; column++ {
+			boxes, n := g.cellBoxes(column, row)
+			for _, box := range append(boxes[:n], g.propsIn(column, row)...) {
+				fn(box)
+			}
+		}
+	}
+	for _, box := range g.obstacles {
+		fn(box)
+	}
+}
+
+// BoxSolid reports whether an upright box overlaps solid geometry
+func (g *Grid) BoxSolid(box OrientedBox) bool {
+	solid := false
+	g.boxesNear(box.Center, box.Center, box.Extents, func(block OrientedBox) {
+		solid = solid || block.Overlaps(box)
+	})
+	return solid
+}
+
+// SweepBox returns the fraction along a move where an upright, unturned box of the given
+// half sizes is first blocked. Boxes it already overlaps at the start are ignored so it
+// can move out of them
+func (g *Grid) SweepBox(p1, p2, half Vec3) (float64, bool) {
+	best, hit := 1.0, false
+	g.boxesNear(p1, p2, half, func(block OrientedBox) {
+		expanded := block.Expand(half)
+		if expanded.ContainsPoint(p1) {
+			return
+		}
+		if t, ok := expanded.IntersectSegment(p1, p2); ok && t <= best {
+			best, hit = t, true
+		}
+	})
+	return best, hit
+}
+
+// playerBody returns the upright box a player of the given radius occupies when
+// standing on the floor at z
+func playerBody(x, y, z, radius float64) OrientedBox {
+	return OrientedBox{
+		Center:  Vec3{x, y, z + (maxStepHeight+playerHeight)/2},
+		Extents: Vec3{radius, radius, (playerHeight - maxStepHeight) / 2},
+	}
+}
+
+// TraceSegment traces a sight line or bolt between two points at eye height above the
+// floor under each, implementing Geometry
+func (g *Grid) TraceSegment(x1, y1, x2, y2 float64) (float64, bool) {
+	return g.TraceSegment3D(g.sightLine(x1, y1, x2, y2))
+}
+
+// sightLine returns the ends of a sight line between two points at eye height above the
+// floor at each
+func (g *Grid) sightLine(x1, y1, x2, y2 float64) (Vec3, Vec3) {
+	return Vec3{x1, y1, g.standingZ(x1, y1) + eyeHeight}, Vec3{x2, y2, g.standingZ(x2, y2) + eyeHeight}
+}
+
+// IsSolid reports whether a player of the given radius cannot stand at a point: a
+// block they overlap rises more than a step above the floor there, or its ceiling leaves
+// too little headroom. It implements Geometry
+func (g *Grid) IsSolid(x, y, radius float64) bool {
+	if g.BlockAt(x, y) == nil {
+		return true
+	}
+	return g.BoxSolid(playerBody(x, y, g.standingZ(x, y), radius))
+}
+
+// SweepCircle returns the fraction along a move where a player of the given radius,
+// standing on the floor at the start, is first blocked. It implements Geometry
+func (g *Grid) SweepCircle(x1, y1, x2, y2, radius float64) (float64, bool) {
+	body := playerBody(x1, y1, g.standingZ(x1, y1), radius)
+	end := Vec3{x2, y2, body.Center.Z}
+	return g.SweepBox(body.Center, end, body.Extents)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// newTestGrid returns an open grid with a floor at 0 and no ceilings, plus a solid
+// column at block (3, 0), a 32 unit step at (0, 2), a 128 unit ledge at (2, 2) and a
+// 60 unit ceiling at (0, 4)
+func newTestGrid() *Grid {
+	grid := &Grid{Blocks: make([]GridBlock, gridBlockCount)}
+	for i := range grid.Blocks {
+		grid.Blocks[i] = GridBlock{Column: i % gridWidth, Row: i / gridWidth, MidBoxBottomZ: gridSkyZ, MidBoxTopZ: gridSkyZ}
+	}
+	grid.Block(3, 0).MidBoxBottomZ, grid.Block(3, 0).MidBoxTopZ = 0, 500
+	grid.Block(0, 2).LowBoxTopZ = 32
+	grid.Block(2, 2).LowBoxTopZ = 128
+	grid.Block(0, 4).MidBoxBottomZ, grid.Block(0, 4).MidBoxTopZ = 60, 500
+	return grid
+}
+
+func TestOrientedBox(t *testing.T) {
+	box := NewOrientedBox(Vec3{0, 0, 0}, Vec3{64, 16, 64}, 0)
+	if !box.ContainsPoint(Vec3{10, 8, 32}) || box.ContainsPoint(Vec3{10, 20, 32}) {
+		t.Error("Unexpected containment for an unturned box")
+	}
+
+	// Turned a quarter turn, the long side runs along y
+	turned := NewOrientedBox(Vec3{0, 0, 0}, Vec3{64, 16, 64}, math.Pi/2)
+	if !turned.ContainsPoint(Vec3{32, 30, 32}) || turned.ContainsPoint(Vec3{50, 8, 32}) {
+		t.Error("Unexpected containment for a turned box")
+	}
+
+	tHit, hit := box.IntersectSegment(Vec3{-64, 8, 32}, Vec3{64, 8, 32})
+	if !hit || math.Abs(tHit-0.5) > 1e-9 {
+		t.Errorf("Expected the segment to enter halfway, got %v %.3f", hit, tHit)
+	}
+	if _, hit := box.IntersectSegment(Vec3{-64, 8, 100}, Vec3{64, 8, 100}); hit {
+		t.Error("Expected a segment above the box to miss")
+	}
+	if d, hit := box.IntersectRay(Vec3{32, -100, 32}, Vec3{0, 5, 0}, 1000); !hit || math.Abs(d-100) > 1e-9 {
+		t.Errorf("Expected the ray to hit after 100 units, got %v %.3f", hit, d)
+	}
+
+	if !box.Overlaps(turned) {
+		t.Error("Expected crossing boxes to overlap")
+	}
+	if box.Overlaps(NewOrientedBox(Vec3{0, 0, 64}, Vec3{64, 16, 64}, 0)) {
+		t.Error("Expected stacked boxes not to overlap")
+	}
+	if box.Overlaps(NewOrientedBox(Vec3{80, 40, 0}, Vec3{16, 16, 64}, math.Pi/4)) {
+		t.Error("Expected separated boxes not to overlap")
+	}
+}
+
+func TestGridCollisionQueries(t *testing.T) {
+	grid := newTestGrid()
+
+	if !grid.PointSolid(Vec3{224, 32, 100}) || !grid.PointSolid(Vec3{224, 32, -1}) || grid.PointSolid(Vec3{32, 32, 10}) {
+		t.Error("Unexpected point solidity")
+	}
+	if !grid.PointSolid(Vec3{-10, 32, 10}) {
+		t.Error("Expected points outside the grid to be solid")
+	}
+
+	// Sight lines stop at the column and pass over the step
+	if tHit, hit := grid.TraceSegment(32, 32, 288, 32); !hit || math.Abs(tHit-0.625) > 1e-9 {
+		t.Errorf("Expected the column to block at 0.625, got %v %.3f", hit, tHit)
+	}
+	if _, hit := grid.TraceSegment(32, 100, 32, 200); hit {
+		t.Error("Expected the step not to block sight")
+	}
+	if point, hit := grid.Raycast(Vec3{32, 32, 50}, Vec3{1, 0, 0}, 500); !hit || math.Abs(point.X-192) > 1e-9 {
+		t.Errorf("Expected the ray to stop at x=192, got %v %+v", hit, point)
+	}
+
+	// Players step onto the step but not the ledge, and need headroom
+	if grid.IsSolid(32, 160, playerRadius) {
+		t.Error("Expected a player to stand on the step")
+	}
+	if !grid.IsSolid(120, 160, playerRadius) {
+		t.Error("Expected the ledge to block a player beside it")
+	}
+	if !grid.IsSolid(32, 288, playerRadius) {
+		t.Error("Expected the low ceiling to leave too little headroom")
+	}
+	if tHit, hit := grid.SweepCircle(32, 160, 300, 160, playerRadius); !hit || math.Abs(tHit-(128-playerRadius-32)/268) > 1e-9 {
+		t.Errorf("Expected the ledge to stop the move, got %v %.3f", hit, tHit)
+	}
+	if _, hit := grid.SweepCircle(32, 32, 32, 160, playerRadius); hit {
+		t.Error("Expected the move onto the step to be clear")
+	}
+}
+
+func TestArenaUsesGridCollision(t *testing.T) {
+	arena := newTestArena(1)
+	arena.Geometry = newTestGrid()
+	arena.AddPlayer(1, TeamChaos)
+	arena.UpdatePlayerPosition(1, 32, 32)
+
+	if arena.UpdatePlayerPosition(1, 220, 32) {
+		t.Error("Expected the move into the column to be blocked")
+	}
+	if !arena.UpdatePlayerPosition(1, 150, 32) {
+		t.Error("Expected the open move to succeed")
+	}
+
+	wall := testWallSpell()
+	if err := arena.ValidateWall(wall, 160, 32, 0); err == nil {
+		t.Error("Expected a wall inside the column to be refused")
+	}
+	if err := arena.ValidateWall(wall, 100, 100, math.Pi/2); err != nil {
+		t.Errorf("Expected a wall in the open to be accepted: %v", err)
+	}
+}
+
+func TestShippedGridCollision(t *testing.T) {
+	grid := loadGrid(9)
+	if grid == nil {
+		t.Fatal("Grid.dat for grid 9 failed to load")
+	}
+	world := loadGridWorld(9)
+
+	chaos := world.Spawns[TeamChaos].Start
+	if grid.IsSolid(chaos.X, chaos.Y, playerRadius) {
+		t.Error("Expected a player to fit at the Chaos start")
+	}
+	if _, hit := grid.TraceSegment(chaos.X, chaos.Y, 0, 0); !hit {
+		t.Error("Expected the grid's outer rock to block sight")
+	}
+}
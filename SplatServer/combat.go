@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Geometry answers collision queries against an arena's level geometry
+type Geometry interface {
+	// TraceSegment returns the fraction along a segment where it first enters solid geometry
+	TraceSegment(x1, y1, x2, y2 float64) (float64, bool)
+	// IsSolid reports whether a circle at a point overlaps solid geometry
+	IsSolid(x, y, radius float64) bool
+	// SweepCircle returns the fraction along a move where a circle is first blocked
+	SweepCircle(x1, y1, x2, y2, radius float64) (float64, bool)
+}
+
+// playerRadius is the collision radius of a player in world units (a grid block is 64)
+const playerRadius = 16.0
+
+// CastSpell casts a spell from a player's spellbook. Casts made inside an arena are
+// placed in the world: bolts are traced instantly, targeted spells and
+// teleports are checked for range and line of sight, walls and runes are
+// spawned, dispels clear runes and projectiles are launched from the caster.
+// Dead players cannot cast, and spells the arena's rules forbid are refused.
+func (gs *GameState) CastSpell(casterID, spellID int, targetX, targetY float64, targetID int) (*SpellInstance, error) {
+	spell := gs.SpellSystem.SpellManager.GetSpell(spellID)
+	if spell == nil {
+		return nil, fmt.Errorf("spell %d not found", spellID)
+	}
+
+	player, exists := gs.GetPlayer(casterID)
+	if !exists {
+		return nil, fmt.Errorf("player %d is not connected", casterID)
+	}
+	if player.Spellbook == nil || !player.Spellbook.Has(spellID) {
+		return nil, fmt.Errorf("spell %d is not in player %d's spellbook", spellID, casterID)
+	}
+
+	arena := gs.ArenaManager.FindPlayerArena(casterID)
+	if arena == nil {
+		return gs.SpellSystem.CastSpell(casterID, spellID, targetX, targetY, targetID)
+	}
+	caster := arena.GetPlayer(casterID)
+	if caster == nil {
+		return nil, fmt.Errorf("player %d is not in arena %d", casterID, arena.ID)
+	}
+
+	arena.mu.RLock()
+	originX, originY, team, dead := caster.X, caster.Y, caster.Team, caster.Dead
+	arena.mu.RUnlock()
+	if dead {
+		return nil, fmt.Errorf("player %d is dead", casterID)
+	}
+
+	sm := gs.SpellSystem.SpellManager
+	if err := arena.AllowsSpell(spell, sm.linkedSpell(spell.TargetSpellID, spell), sm.linkedSpell(spell.TriggerSpellID, spell)); err != nil {
+		return nil, err
+	}
+
+	// Targeted spells and walls are checked before the cooldown is spent
+	angle := math.Atan2(targetY-originY, targetX-originX)
+	switch spell.Type {
+	case SpellTypeTarget:
+		if err := arena.ValidateTarget(casterID, spell, targetID); err != nil {
+			return nil, err
+		}
+	case SpellTypeTeleport:
+		if err := arena.ValidateTeleport(casterID, spell, targetX, targetY); err != nil {
+			return nil, err
+		}
+	case SpellTypeWall:
+		if err := arena.ValidateWall(spell, originX, originY, angle); err != nil {
+			return nil, err
+		}
+	}
+
+	instance, err := gs.SpellSystem.CastSpell(casterID, spellID, targetX, targetY, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case spell.ProjectileType == SpellProjectileBolt:
+		result := arena.FireBolt(casterID, spell, originX, originY, angle)
+		arena.Broadcast(BuildBoltPacket(arena.ID, result))
+		gs.SpellSystem.RemoveSpell(instance.ID)
+	case spell.Type == SpellTypeWall:
+		arena.AddWall(NewWall(spell, casterID, team, originX, originY, angle))
+		gs.SpellSystem.RemoveSpell(instance.ID)
+	case spell.Type == SpellTypeRune:
+		trigger := gs.SpellSystem.SpellManager.linkedSpell(spell.TriggerSpellID, spell)
+		arena.AddRune(NewRune(spell, trigger, casterID, team, originX, originY, angle))
+		gs.SpellSystem.RemoveSpell(instance.ID)
+	case spell.Type == SpellTypeTarget:
+		effect := gs.SpellSystem.SpellManager.linkedSpell(spell.TargetSpellID, spell)
+		gs.SpellSystem.RemoveSpell(instance.ID)
+		if err := arena.ApplyTargetSpell(casterID, spell, effect, targetID); err != nil {
+			return nil, err
+		}
+	case spell.Type == SpellTypeTeleport:
+		gs.SpellSystem.RemoveSpell(instance.ID)
+		if err := arena.TeleportPlayer(casterID, spell, targetX, targetY); err != nil {
+			return nil, err
+		}
+	case spell.Type == SpellTypeDispell:
+		if spell.Range <= 0 || math.Hypot(targetX-originX, targetY-originY) <= spell.Range {
+			arena.DispelRunes(targetX, targetY, runeDispelRadius)
+		}
+		gs.SpellSystem.RemoveSpell(instance.ID)
+	default:
+		if spell.Speed > 0 {
+			gs.SpellSystem.launch(instance, arena.ID, originX, originY, angle, spell.Speed)
+		}
+	}
+
+	return instance, nil
+}
+
+// launch starts a spell instance moving from an origin; it locks the spell system
+// since the game loop may be reading the instance
+func (ss *SpellSystem) launch(instance *SpellInstance, arenaID int, x, y, angle, speed float64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	instance.ArenaID = arenaID
+	instance.X, instance.Y = x, y
+	instance.PrevX, instance.PrevY = x, y
+	instance.VelocityX = speed * math.Cos(angle)
+	instance.VelocityY = speed * math.Sin(angle)
+}
+
+// ResolveProjectiles checks an arena's moving spells, as bucketed by
+// SpellSystem.ActiveSpellsByArena, against walls and players
+func ResolveProjectiles(gs *GameState, arena *Arena, active []*SpellInstance) {
+	var spent []int64
+	arena.mu.Lock()
+	for _, instance := range active {
+		spell := gs.SpellSystem.SpellManager.GetSpell(instance.SpellID)
+		if spell == nil {
+			continue
+		}
+		if arena.resolveProjectileLocked(instance, spell) {
+			spent = append(spent, instance.ID)
+		}
+	}
+	arena.mu.Unlock()
+
+	for _, id := range spent {
+		gs.SpellSystem.RemoveSpell(id)
+	}
+}
+
+// resolveProjectileLocked applies the first thing a projectile hits along its path
+// and reports whether it was consumed; the caller must hold a.mu
+func (a *Arena) resolveProjectileLocked(inst *SpellInstance, spell *Spell) bool {
+	wall, wallT := a.firstWallOnSegmentLocked(inst.PrevX, inst.PrevY, inst.X, inst.Y)
+	thin, thinT := a.firstThinOnSegmentLocked(inst.PrevX, inst.PrevY, inst.X, inst.Y)
+
+	var target *ArenaPlayer
+	targetT := math.MaxFloat64
+	for _, player := range a.playersAlongLocked(inst.PrevX, inst.PrevY, inst.X, inst.Y, playerRadius) {
+		if player.PlayerID == inst.CasterID || player.Dead {
+			continue
+		}
+		if t, ok := segmentCircleHit(inst.PrevX, inst.PrevY, inst.X, inst.Y, player.X, player.Y, playerRadius); ok && t < targetT {
+			target, targetT = player, t
+		}
+	}
+
+	geometryT := math.MaxFloat64
+	if a.Geometry != nil {
+		if t, hit := a.Geometry.TraceSegment(inst.PrevX, inst.PrevY, inst.X, inst.Y); hit {
+			geometryT = t
+		}
+	}
+
+	// Standing thins are part of the geometry, so a thin hit first is the geometry hit
+	switch {
+	case thin != nil && thinT <= geometryT && thinT < wallT && thinT < targetT:
+		a.damageThinLocked(thin, inst.CasterID, spell.Damage)
+		return true
+	case geometryT < wallT && geometryT < targetT:
+		return true
+	case wall != nil && wallT <= targetT:
+		a.damageWallLocked(wall.ID, spell, spell.Damage)
+		return true
+	case target != nil:
+		a.applySpellLocked(inst.CasterID, target, spell)
+		return true
+	}
+	return false
+}
+
+// applySpellLocked applies a spell's damage or healing to a player, killing them when
+// their health runs out; dead players are only affected by resurrect effects.
+// The caller must hold a.mu
+func (a *Arena) applySpellLocked(casterID int, target *ArenaPlayer, spell *Spell) {
+	team := TeamNone
+	if caster, exists := a.Players[casterID]; exists {
+		team = caster.Team
+	}
+	a.applyTeamSpellLocked(casterID, team, target, spell)
+}
+
+// applyTeamSpellLocked is applySpellLocked for a caster on a given team, who may have
+// left the arena, as the owner of a rune can; the caller must hold a.mu
+func (a *Arena) applyTeamSpellLocked(casterID int, team Team, target *ArenaPlayer, spell *Spell) {
+	caster := a.Players[casterID]
+	if target.Dead {
+		if spell.EffectType == SpellEffectResurrect {
+			a.raisePlayerLocked(caster, target, spell)
+		}
+		return
+	}
+	self := casterID == target.PlayerID
+	allies := a.teamAlliesLocked(team, target)
+	friendlyFire := a.Ruleset.Rules.Has(ArenaRuleFriendlyFire) && !self
+
+	if spell.Healing > 0 && (self || (allies && !a.Ruleset.Rules.Has(ArenaRuleNoFriendlyOther))) {
+		healed := min(target.maxHealth(), target.Health+spell.Healing) - target.Health
+		if healed > 0 {
+			target.Health += healed
+			a.recordHealingLocked(caster, target, healed)
+			a.giveExpLocked(caster, healingExp(healed))
+		}
+	}
+	if spell.Damage > 0 && (!allies || friendlyFire) && target.Health > 0 {
+		dealt := min(spell.Damage, target.Health)
+		target.Health -= dealt
+		killed := target.Health == 0
+		a.recordDamageLocked(caster, target, dealt, killed)
+		a.giveExpLocked(caster, damageExp(dealt)*1.8)
+		a.giveExpLocked(target, damageExp(dealt)*0.7)
+		if killed && caster != nil && caster != target {
+			a.giveExpLocked(caster, killExp(caster.Level, target.Level))
+		}
+		if killed {
+			a.killPlayerLocked(target, casterID)
+		}
+	}
+}
+
+// maxHealth returns the player's max health, treating an unset value as 100
+func (p *ArenaPlayer) maxHealth() int {
+	if p.MaxHealth <= 0 {
+		return 100
+	}
+	return p.MaxHealth
+}
+
+// segmentCircleHit returns the fraction along a segment where it first touches a circle
+func segmentCircleHit(x1, y1, x2, y2, cx, cy, radius float64) (float64, bool) {
+	dx, dy := x2-x1, y2-y1
+	fx, fy := x1-cx, y1-cy
+
+	a := dx*dx + dy*dy
+	c := fx*fx + fy*fy - radius*radius
+	if c <= 0 {
+		return 0, true
+	}
+	if a == 0 {
+		return 0, false
+	}
+
+	b := 2 * (fx*dx + fy*dy)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return 0, false
+	}
+	t := (-b - math.Sqrt(disc)) / (2 * a)
+	if t < 0 || t > 1 {
+		return 0, false
+	}
+	return t, true
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chatCommand handles a "!" chat command and returns the reply for the player
+type chatCommand func(args []string, player *Player, gs *GameState) string
+
+// chatCommands maps command names to their handlers
+var chatCommands map[string]chatCommand
+
+func init() {
+	chatCommands = map[string]chatCommand{
+		"help":      commandHelp,
+		"class":     commandClass,
+		"train":     commandTrain,
+		"learn":     commandLearn,
+		"forget":    commandForget,
+		"spellbook": commandSpellbook,
+		"respec":    commandRespec,
+		"stats":     commandStats,
+		"allocate":  commandAllocate,
+		"callraise": commandCallRaise,
+		"tap":       commandTap,
+		"rules":     commandRules,
+		"mode":      commandMode,
+		"shrines":   commandShrines,
+		"bots":      commandBots,
+		"admin":     commandAdmin,
+	}
+}
+
+// handleChatCommand processes "!" commands, returning true when the message was a command
+func handleChatCommand(message string, player *Player, gs *GameState) bool {
+	if !strings.HasPrefix(message, "!") {
+		return false
+	}
+
+	parts := strings.Fields(message[1:])
+	if len(parts) == 0 {
+		return false
+	}
+
+	command, exists := chatCommands[strings.ToLower(parts[0])]
+	if !exists {
+		player.Conn.Write([]byte(fmt.Sprintf("Unknown command !%s. Use !help for available commands\n", parts[0])))
+		return true
+	}
+
+	reply := command(parts[1:], player, gs)
+	player.Conn.Write([]byte(reply + "\n"))
+	return true
+}
+
+// commandHelp lists the available commands
+func commandHelp(args []string, player *Player, gs *GameState) string {
+	names := make([]string, 0, len(chatCommands))
+	for name := range chatCommands {
+		names = append(names, "!"+name)
+	}
+	sort.Strings(names)
+	return "Commands: " + strings.Join(names, " ")
+}
+
+// saveSpellbook persists a player's spellbook, logging failures
+func saveSpellbook(player *Player) {
+	if err := SaveSpellbook(player.ID, player.Spellbook); err != nil {
+		fmt.Printf("Failed to save spellbook for player %d: %v\n", player.ID, err)
+	}
+}
+
+// commandClass changes class, which resets the spellbook
+func commandClass(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	if len(args) != 1 {
+		return "Usage: !class <magician|arcanist|mentalist|cleric>"
+	}
+	class, err := ParsePlayerClass(args[0])
+	if err != nil {
+		return err.Error()
+	}
+
+	player.Spellbook.Respec(class)
+	saveSpellbook(player)
+	return fmt.Sprintf("You are now a %s. Your spellbook has been reset", class)
+}
+
+// commandTrain spends a spell list point
+func commandTrain(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	if len(args) == 0 {
+		return "Usage: !train <spell list name or id>"
+	}
+
+	level, _ := progressionOf(gs, player)
+	tree, err := player.Spellbook.Train(gs.SpellSystem.SpellManager.GetTrees(), strings.Join(args, " "), level)
+	if err != nil {
+		return err.Error()
+	}
+	saveSpellbook(player)
+	return fmt.Sprintf("%s trained to level %d (%d of %d points spent)",
+		tree.Name, player.Spellbook.TreeLevel(tree.ID), player.Spellbook.PointsSpent(), level)
+}
+
+// commandLearn equips an unlocked spell
+func commandLearn(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	if len(args) != 1 {
+		return "Usage: !learn <spell id>"
+	}
+	spellID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "Usage: !learn <spell id>"
+	}
+
+	level, _ := progressionOf(gs, player)
+	if err := player.Spellbook.Learn(gs.SpellSystem.SpellManager.GetTrees(), spellID, level); err != nil {
+		return err.Error()
+	}
+	saveSpellbook(player)
+	return fmt.Sprintf("Added %s to your spellbook", spellName(gs, spellID))
+}
+
+// commandForget removes a spell from the spellbook
+func commandForget(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	if len(args) != 1 {
+		return "Usage: !forget <spell id>"
+	}
+	spellID, err := strconv.Atoi(args[0])
+	if err != nil || !player.Spellbook.Forget(spellID) {
+		return fmt.Sprintf("Spell %s is not in your spellbook", args[0])
+	}
+	saveSpellbook(player)
+	return fmt.Sprintf("Removed %s from your spellbook", spellName(gs, spellID))
+}
+
+// commandSpellbook shows the player's class, training and equipped spells
+func commandSpellbook(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	return describeSpellbook(player, gs)
+}
+
+// commandRespec clears all spell list training and equipped spells
+func commandRespec(args []string, player *Player, gs *GameState) string {
+	if player.Spellbook == nil {
+		return "You must log in first"
+	}
+	level, _ := progressionOf(gs, player)
+	player.Spellbook.Respec(player.Spellbook.Class)
+	saveSpellbook(player)
+	return fmt.Sprintf("Your spellbook has been reset. You have %d spell list points to spend", level)
+}
+
+// commandStats shows the player's level, experience and stats
+func commandStats(args []string, player *Player, gs *GameState) string {
+	// The tick regenerates and levels players under gs.mu
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	level := effectiveLevel(player.Level)
+	response := fmt.Sprintf("Level %d, %d experience", level, player.Experience)
+	if level < levelCurve.MaxLevel() {
+		response += fmt.Sprintf(" (%d for level %d)", levelCurve.Required(level+1), level+1)
+	}
+	response += fmt.Sprintf("\nHealth %d/%d, power %d/%d, regeneration %.2f per tick\n",
+		player.Health, player.MaxHealth(), player.Power, player.MaxPower(), player.RegenPerTick())
+	response += fmt.Sprintf("Constitution %d, empathy %d, discipline %d, %d stat points available",
+		player.Stats.Value(StatConstitution), player.Stats.Value(StatEmpathy), player.Stats.Value(StatDiscipline),
+		player.AvailableStatPoints())
+	return response
+}
+
+// commandAllocate spends stat points on a stat
+func commandAllocate(args []string, player *Player, gs *GameState) string {
+	if len(args) == 0 || len(args) > 2 {
+		return "Usage: !allocate <constitution|empathy|discipline> [points]"
+	}
+	stat, err := ParseStat(args[0])
+	if err != nil {
+		return err.Error()
+	}
+	points := 1
+	if len(args) == 2 {
+		if points, err = strconv.Atoi(args[1]); err != nil {
+			return "Usage: !allocate <constitution|empathy|discipline> [points]"
+		}
+	}
+
+	// The tick reads stats under gs.mu to regenerate and level players
+	gs.mu.Lock()
+	if err := player.AllocateStat(stat, points); err != nil {
+		gs.mu.Unlock()
+		return err.Error()
+	}
+	if err := SaveProgression(player); err != nil {
+		fmt.Printf("Failed to save progression for player %d: %v\n", player.ID, err)
+	}
+	level, maxHealth := player.Level, player.MaxHealth()
+	reply := fmt.Sprintf("%s is now %d. You have %d stat points left",
+		stat, player.Stats.Value(stat), player.AvailableStatPoints())
+	gs.mu.Unlock()
+
+	if arena := gs.ArenaManager.FindPlayerArena(player.ID); arena != nil {
+		arena.SetPlayerProgression(player.ID, level, maxHealth)
+	}
+	return reply
+}
+
+// spellName returns a spell's name, or its ID when it is unknown
+func spellName(gs *GameState, spellID int) string {
+	if spell := gs.SpellSystem.SpellManager.GetSpell(spellID); spell != nil {
+		return spell.Name
+	}
+	return fmt.Sprintf("spell %d", spellID)
+}
+
+// describeSpellbook lists a player's spell lists and spells as text
+func describeSpellbook(player *Player, gs *GameState) string {
+	book := player.Spellbook
+	trees := gs.SpellSystem.SpellManager.GetTrees()
+	level, _ := progressionOf(gs, player)
+
+	book.mu.RLock()
+	class := book.Class
+	treeLevels := make(map[int]int, len(book.TreeLevels))
+	for id, trained := range book.TreeLevels {
+		treeLevels[id] = trained
+	}
+	book.mu.RUnlock()
+
+	response := fmt.Sprintf("Spellbook (%s, level %d, %d of %d list points spent):\n",
+		class, level, book.PointsSpent(), level)
+	for _, treeID := range trees.ClassTrees[class] {
+		response += fmt.Sprintf("  List %d %s: level %d\n", treeID, trees.Trees[treeID].Name, treeLevels[treeID])
+	}
+
+	response += "Equipped:\n"
+	for _, spellID := range book.GetSpells() {
+		response += fmt.Sprintf("- %s (ID: %d)\n", spellName(gs, spellID), spellID)
+	}
+
+	response += "Unlocked:\n"
+	for _, spellID := range trees.UnlockedSpells(class, level, treeLevels) {
+		if !book.Has(spellID) {
+			response += fmt.Sprintf("- %s (ID: %d)\n", spellName(gs, spellID), spellID)
+		}
+	}
+	return strings.TrimSuffix(response, "\n")
+}
+
+// commandCallRaise asks a dead player's teammates to raise them
+func commandCallRaise(args []string, player *Player, gs *GameState) string {
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		return "You are not in an arena"
+	}
+	if err := arena.CallForRaise(player.ID); err != nil {
+		return err.Error()
+	}
+	return "Your teammates have been asked to raise you"
+}
+
+// commandTap returns a dead player to their raise point by tapping their team's shrine
+func commandTap(args []string, player *Player, gs *GameState) string {
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		return "You are not in an arena"
+	}
+	if err := arena.TapShrine(player.ID); err != nil {
+		return err.Error()
+	}
+	return "You tapped your shrine"
+}
+
+// commandRules shows the current arena's rules, or lets an admin replace them with a custom set
+func commandRules(args []string, player *Player, gs *GameState) string {
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		return "You are not in an arena"
+	}
+	if len(args) == 0 {
+		return fmt.Sprintf("%s is playing %s", arena.Name, arena.GetRuleset())
+	}
+	if !isAdmin(player) {
+		return "Only admins can change the rules"
+	}
+
+	rules := ArenaRuleNone
+	for _, name := range args {
+		if strings.EqualFold(name, "none") {
+			continue
+		}
+		rule, err := ParseArenaRule(name)
+		if err != nil {
+			return err.Error()
+		}
+		rules |= rule
+	}
+	if err := arena.SetRuleset(CustomRuleset(rules)); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s is now playing %s", arena.Name, arena.GetRuleset())
+}
+
+// commandMode shows the current arena's mode, or lets an admin switch it to another mode's rules
+func commandMode(args []string, player *Player, gs *GameState) string {
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		return "You are not in an arena"
+	}
+	if len(args) == 0 {
+		return fmt.Sprintf("%s is playing %s", arena.Name, arena.GetRuleset())
+	}
+	if !isAdmin(player) {
+		return "Only admins can change the rules"
+	}
+	if len(args) > 1 {
+		return "Usage: !mode <normal|twoteams|freeforall|capturetheflag|deathmatch|expevent|custom>"
+	}
+
+	mode, err := ParseArenaMode(args[0])
+	if err != nil {
+		return err.Error()
+	}
+	if err := arena.SetRuleset(NewRuleset(mode)); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s is now playing %s", arena.Name, arena.GetRuleset())
+}
+
+// commandShrines shows the shrines and pools in the player's arena
+func commandShrines(args []string, player *Player, gs *GameState) string {
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		return "You are not in an arena"
+	}
+	return describeObjectives(arena)
+}
+
+// isAdmin reports whether a player's connection has signed in as an admin with !admin.
+// Player names are chosen by the client at login, so they are never trusted for this
+func isAdmin(player *Player) bool {
+	return player.Admin
+}
+
+// commandAdmin signs the player's connection in as an admin when given ADMIN_PASSWORD.
+// Without ADMIN_PASSWORD nobody can become an admin
+func commandAdmin(args []string, player *Player, gs *GameState) string {
+	password := getEnv("ADMIN_PASSWORD", "")
+	if len(args) != 1 {
+		return "Usage: !admin <password>"
+	}
+	if password == "" || subtle.ConstantTimeCompare([]byte(args[0]), []byte(password)) != 1 {
+		return "Admin sign in refused"
+	}
+	player.Admin = true
+	return "You are signed in as an admin"
+}
+
+// commandBots lets admins list, add and remove bots and set how many players arenas are
+// filled up to with them
+func commandBots(args []string, player *Player, gs *GameState) string {
+	const usage = "Usage: !bots [add <arena> [count] [team] | remove <arena> [count] | fill <arena> <count>]"
+	if !isAdmin(player) {
+		return "Only admins can manage bots"
+	}
+	if len(args) == 0 {
+		return describeBots(gs)
+	}
+	if len(args) < 2 {
+		return usage
+	}
+	arenaID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return usage
+	}
+	arena := gs.ArenaManager.GetArena(arenaID)
+	if arena == nil {
+		return fmt.Sprintf("Arena %d not found", arenaID)
+	}
+	count := 0
+	if len(args) > 2 {
+		if count, err = strconv.Atoi(args[2]); err != nil || count < 0 {
+			return usage
+		}
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		team := TeamNone
+		if len(args) > 3 {
+			if team, err = ParseTeam(args[3]); err != nil {
+				return err.Error()
+			}
+		}
+		added := 0
+		for ; added < max(1, count); added++ {
+			if _, err := gs.Bots.AddBot(gs, arena, team); err != nil {
+				return fmt.Sprintf("Added %d bots to %s: %v", added, arena.Name, err)
+			}
+		}
+		return fmt.Sprintf("Added %d bots to %s", added, arena.Name)
+	case "remove":
+		return fmt.Sprintf("Removed %d bots from %s", gs.Bots.RemoveBots(gs, arena.ID, count), arena.Name)
+	case "fill":
+		if len(args) != 3 {
+			return usage
+		}
+		gs.Bots.SetFill(arena.ID, count)
+		return fmt.Sprintf("%s is now filled up to %d players with bots", arena.Name, min(count, arena.MaxPlayers))
+	}
+	return usage
+}
+
+// describeBots lists the arenas with bots in them or a fill target
+func describeBots(gs *GameState) string {
+	gs.ArenaManager.mu.RLock()
+	ids := make([]int, 0, len(gs.ArenaManager.Arenas))
+	for id := range gs.ArenaManager.Arenas {
+		ids = append(ids, id)
+	}
+	gs.ArenaManager.mu.RUnlock()
+	sort.Ints(ids)
+
+	var lines []string
+	for _, id := range ids {
+		arena := gs.ArenaManager.GetArena(id)
+		bots, fill := gs.Bots.ArenaBots(id), gs.Bots.Fill(id)
+		if arena == nil || (len(bots) == 0 && fill == 0) {
+			continue
+		}
+		names := make([]string, len(bots))
+		for i, bot := range bots {
+			names[i] = bot.Player.Name
+		}
+		lines = append(lines, fmt.Sprintf("%d %s: %d bots (fill %d) %s", id, arena.Name, len(bots), fill, strings.Join(names, " ")))
+	}
+	if len(lines) == 0 {
+		return "No arena has bots"
+	}
+	return strings.Join(lines, "\n")
+}
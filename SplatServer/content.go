@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// contentDir returns the root of the Magestorm content tree
+func contentDir() string {
+	return getEnv("CONTENT_DIR", "../Content")
+}
+
+// findContentFile resolves a path below dir, matching each element
+// case-insensitively since the shipped content mixes .dat and .DAT
+func findContentFile(dir string, parts ...string) (string, error) {
+	current := dir
+	for _, part := range parts {
+		exact := filepath.Join(current, part)
+		if _, err := os.Stat(exact); err == nil {
+			current = exact
+			continue
+		}
+
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			return "", err
+		}
+		found := ""
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), part) {
+				found = filepath.Join(current, entry.Name())
+				break
+			}
+		}
+		if found == "" {
+			return "", fmt.Errorf("%s not found in %s", part, current)
+		}
+		current = found
+	}
+	return current, nil
+}
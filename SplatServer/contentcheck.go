@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ContentProblem is something wrong in the content tree
+type ContentProblem struct {
+	File    string // path below the content directory
+	Message string
+}
+
+func (p ContentProblem) String() string {
+	return p.File + ": " + p.Message
+}
+
+// contentCheck collects the problems found checking a content tree
+type contentCheck struct {
+	dir      string
+	problems []ContentProblem
+}
+
+// report records a problem in a file
+func (c *contentCheck) report(file, format string, args ...interface{}) {
+	c.problems = append(c.problems, ContentProblem{File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+// find resolves a content file case-insensitively, reporting it when it is missing
+func (c *contentCheck) find(parts ...string) (string, bool) {
+	path, err := findContentFile(c.dir, parts...)
+	if err != nil {
+		c.report(filepath.Join(parts...), "missing")
+		return "", false
+	}
+	return path, true
+}
+
+// load reports a file that failed to load, without repeating its path
+func (c *contentCheck) load(file, path string, err error) bool {
+	if err == nil {
+		return true
+	}
+	c.report(file, "%s", strings.TrimPrefix(err.Error(), path+": "))
+	return false
+}
+
+// CheckContent loads Arenas.dat, Spells.dat and every grid's files from a content tree,
+// returning the missing files, parse errors, references to things that do not exist and
+// spawn points inside solid blocks it finds
+func CheckContent(dir string) []ContentProblem {
+	c := &contentCheck{dir: dir}
+
+	grids := make(map[int]bool)
+	if entries, err := os.ReadDir(filepath.Join(dir, "Grids")); err == nil {
+		for _, entry := range entries {
+			if id, err := parseGridName(entry.Name()); err == nil && entry.IsDir() {
+				grids[id] = true
+			}
+		}
+	}
+	if path, ok := c.find("Arenas.dat"); ok {
+		templates, err := LoadArenaFile(path)
+		if c.load("Arenas.dat", path, err) {
+			for _, template := range templates {
+				if !grids[template.GridID] {
+					c.report("Arenas.dat", "arena %d (%s) uses grid%02d, which has no folder", template.ID, template.Name, template.GridID)
+				}
+			}
+		}
+	}
+	c.checkSpells()
+
+	ids := make([]int, 0, len(grids))
+	for id := range grids {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		c.checkGrid(id)
+	}
+	return c.problems
+}
+
+// checkSpells loads Spells.dat and checks the spells its runes, targeted spells and
+// spell lists refer to exist
+func (c *contentCheck) checkSpells() {
+	path, ok := c.find("Spells.dat")
+	if !ok {
+		return
+	}
+	ini, err := LoadIniFile(path)
+	if !c.load("Spells.dat", path, err) {
+		return
+	}
+	spells, err := parseSpellDefs(ini)
+	if !c.load("Spells.dat", path, err) {
+		return
+	}
+	trees, err := parseSpellTrees(ini)
+	if !c.load("Spells.dat", path, err) {
+		return
+	}
+
+	defined := make(map[int]bool)
+	for _, spell := range spells {
+		defined[spell.ID] = true
+	}
+	for _, spell := range spells {
+		if spell.TriggerSpellID != 0 && !defined[spell.TriggerSpellID] {
+			c.report("Spells.dat", "spell %d (%s) triggers unknown spell %d", spell.ID, spell.Name, spell.TriggerSpellID)
+		}
+		if spell.TargetSpellID != 0 && !defined[spell.TargetSpellID] {
+			c.report("Spells.dat", "spell %d (%s) targets with unknown spell %d", spell.ID, spell.Name, spell.TargetSpellID)
+		}
+	}
+	for id := 1; id <= len(trees.Trees); id++ {
+		tree := trees.Trees[id]
+		for level := 1; level <= maxTreeLevel; level++ {
+			if spellID, ok := tree.Levels[level]; ok && !defined[spellID] {
+				c.report("Spells.dat", "spell list %d (%s) level %d is unknown spell %d", tree.ID, tree.Name, level, spellID)
+			}
+		}
+	}
+}
+
+// checkGrid loads a grid's Grid.dat, Objects.dat, Misc.dat, Trigger.dat and World.dat and
+// checks what they refer to against each other
+func (c *contentCheck) checkGrid(gridID int) {
+	folder := fmt.Sprintf("Grid%02d", gridID)
+	file := func(name string) string {
+		return filepath.Join("Grids", folder, name)
+	}
+
+	var grid *Grid
+	if path, ok := c.find("Grids", folder, "Grid.dat"); ok {
+		var err error
+		grid, err = LoadGridFile(path)
+		c.load(file("Grid.dat"), path, err)
+	}
+	var defs []ObjectDef
+	defsLoaded := false
+	if path, ok := c.find("Grids", folder, "Objects.dat"); ok {
+		var err error
+		defs, err = LoadObjectFile(path)
+		defsLoaded = c.load(file("Objects.dat"), path, err)
+	}
+	var thins []Thin
+	if path, ok := c.find("Grids", folder, "Misc.dat"); ok {
+		var err error
+		thins, err = LoadThinFile(path)
+		c.load(file("Misc.dat"), path, err)
+	}
+	var triggers []TriggerDef
+	if path, ok := c.find("Grids", folder, "Trigger.dat"); ok {
+		var err error
+		triggers, err = LoadTriggerFile(path)
+		c.load(file("Trigger.dat"), path, err)
+	}
+	var world *World
+	if path, ok := c.find("Grids", folder, "World.dat"); ok {
+		var err error
+		world, err = LoadWorldFile(path)
+		c.load(file("World.dat"), path, err)
+	}
+	if grid == nil {
+		return
+	}
+
+	if defsLoaded {
+		grid.ObjectDefs = defs
+		for _, object := range grid.Objects {
+			if grid.ObjectDef(object.ObjectID) == nil {
+				c.report(file("Grid.dat"), "object at (%d, %d) uses undefined object %d", object.X, object.Y, object.ObjectID)
+			}
+		}
+	}
+
+	triggerIDs := make(map[int]bool)
+	for _, def := range triggers {
+		triggerIDs[def.ID] = true
+	}
+	for _, thin := range thins {
+		if triggers != nil && thin.TriggerID != 0 && !triggerIDs[thin.TriggerID] {
+			c.report(file("Misc.dat"), "thin %d uses unknown trigger %d", thin.ID, thin.TriggerID)
+		}
+	}
+	for _, def := range triggers {
+		if def.NextTrigger != 0 && !triggerIDs[def.NextTrigger] {
+			c.report(file("Trigger.dat"), "trigger %d chains to unknown trigger %d", def.ID, def.NextTrigger)
+		}
+		switch def.Type {
+		case TriggerElevator:
+			if grid.Block(def.Column1, def.Row1) == nil || grid.Block(def.Column2, def.Row2) == nil {
+				c.report(file("Trigger.dat"), "elevator %d lifts blocks (%d, %d) to (%d, %d) outside the grid", def.ID, def.Column1, def.Row1, def.Column2, def.Row2)
+			}
+		case TriggerTeleport:
+			for _, destination := range def.Destinations {
+				if problem := spawnProblem(grid, destination); problem != "" {
+					c.report(file("Trigger.dat"), "teleporter %d destination (%.0f, %.0f) is %s", def.ID, destination.X, destination.Y, problem)
+				}
+			}
+		}
+	}
+
+	if world == nil {
+		return
+	}
+	// Teams whose shrine has no power never play, so their spawns are never used, as
+	// with Splat Lake's walled in Order spawns
+	unpowered := make(map[Team]bool)
+	for _, shrine := range world.Shrines {
+		unpowered[shrine.Team] = shrine.Power == 0
+	}
+	teams := make([]Team, 0, len(world.Spawns))
+	for team := range world.Spawns {
+		if !unpowered[team] {
+			teams = append(teams, team)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+	for _, team := range teams {
+		spawn := world.Spawns[team]
+		if problem := spawnProblem(grid, spawn.Start); problem != "" {
+			c.report(file("World.dat"), "%s start point (%.0f, %.0f) is %s", team, spawn.Start.X, spawn.Start.Y, problem)
+		}
+		if problem := spawnProblem(grid, spawn.Raise); problem != "" {
+			c.report(file("World.dat"), "%s raise point (%.0f, %.0f) is %s", team, spawn.Raise.X, spawn.Raise.Y, problem)
+		}
+	}
+}
+
+// spawnProblem describes why players cannot be put at a point, or returns "" when they can
+func spawnProblem(grid *Grid, point SpawnPoint) string {
+	block := grid.BlockAt(point.X, point.Y)
+	switch {
+	case block == nil:
+		return "outside the grid"
+	case block.IsSolid():
+		return "inside a solid block"
+	}
+	return ""
+}
+
+// runContentCheck checks a content tree, printing its problems, and returns the exit
+// status: 0 when the content is clean and 1 when it has problems
+func runContentCheck(dir string, out io.Writer) int {
+	problems := CheckContent(dir)
+	for _, problem := range problems {
+		fmt.Fprintln(out, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(out, "%d problems found in %s\n", len(problems), dir)
+		return 1
+	}
+	fmt.Fprintf(out, "No problems found in %s\n", dir)
+	return 0
+}
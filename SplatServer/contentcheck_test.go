@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeContentFile writes a file below a test content tree
+func writeContentFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testGridData returns Grid.dat data with open blocks but for a solid block (1, 1), and
+// an object at (200, 200) using undefined object 5
+func testGridData() []byte {
+	data := make([]byte, gridBlockCount*gridBlockBytes+4+gridObjectCount*gridObjectBytes)
+	for i := 0; i < gridBlockCount; i++ {
+		if i != gridWidth+1 {
+			binary.LittleEndian.PutUint16(data[i*gridBlockBytes+6*2:], 256)
+		}
+	}
+	objects := data[gridBlockCount*gridBlockBytes+4:]
+	for n, value := range []int32{5, 200, 200, 0} {
+		binary.LittleEndian.PutUint32(objects[n*4:], uint32(value))
+	}
+	return data
+}
+
+func TestCheckContent(t *testing.T) {
+	dir := t.TempDir()
+	writeContentFile(t, dir, "Arenas.dat", []byte("[arenadefs]\nnumarenas=2\n[arena00]\nname=Test\ngrid=grid00\nmaxplayers=8\n[arena01]\nname=Lost\ngrid=grid05\nmaxplayers=8\n"))
+	writeContentFile(t, dir, "Spells.dat", []byte("[spelldefs]\nnumspells=1\n[spell01]\nname=Trap\ntype=rune\ndeath_spell_effect=9\n[listdefs]\nnumlists=1\n[spelllist01]\nname=Traps\nlevel01=1\nlevel02=7\n"))
+	writeContentFile(t, dir, "Grids/Grid00/Grid.DAT", testGridData())
+	writeContentFile(t, dir, "Grids/Grid00/Objects.dat", nil)
+	writeContentFile(t, dir, "Grids/Grid00/Trigger.dat", []byte("[triggerdefs]\nnumtriggers=1\n[trigger01]\ntype=door\nnext_trigger=4\n"))
+	writeContentFile(t, dir, "Grids/Grid00/world.dat", []byte("[chaos]\nstartx=1\nstarty=1\nraisex=2\nraisey=2\n[order]\nstartx=200\nstarty=2\nraisex=2\nraisey=2\n"))
+
+	var got []string
+	for _, problem := range CheckContent(dir) {
+		got = append(got, problem.String())
+	}
+	expected := []string{
+		"Arenas.dat: arena 2 (Lost) uses grid05, which has no folder",
+		"Spells.dat: spell 1 (Trap) triggers unknown spell 9",
+		"Spells.dat: spell list 1 (Traps) level 2 is unknown spell 7",
+		"Grids/Grid00/Objects.dat: object data is truncated: EOF",
+		"Grids/Grid00/Misc.dat: missing",
+		"Grids/Grid00/Trigger.dat: trigger 1 chains to unknown trigger 4",
+		"Grids/Grid00/World.dat: Chaos start point (96, 96) is inside a solid block",
+		"Grids/Grid00/World.dat: Order start point (12832, 160) is outside the grid",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected problems:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// With Objects.dat loaded the undefined object is reported too
+	writeContentFile(t, dir, "Grids/Grid00/Objects.dat", make([]byte, objectDefCount*objectDefBytes))
+	found := false
+	for _, problem := range CheckContent(dir) {
+		found = found || problem.String() == "Grids/Grid00/Grid.dat: object at (200, 200) uses undefined object 5"
+	}
+	if !found {
+		t.Error("Expected the undefined object to be reported")
+	}
+
+	// The spawns of a team whose shrine has no power are never used
+	writeContentFile(t, dir, "Grids/Grid00/world.dat", []byte("[chaos]\nstartx=1\nstarty=1\nraisex=2\nraisey=2\n[shrinedefs]\nnumshrines=1\n[shrine00]\npower=0\nalignment=chaos\n"))
+	for _, problem := range CheckContent(dir) {
+		if strings.Contains(problem.String(), "Chaos start point") {
+			t.Errorf("Expected the unpowered team's spawns to be skipped, got %s", problem)
+		}
+	}
+}
+
+func TestContentCheckCommand(t *testing.T) {
+	var out bytes.Buffer
+	if status := runCommand([]string{"content", "check", t.TempDir()}, &out); status != 1 {
+		t.Errorf("Expected an empty content tree to fail, got status %d", status)
+	}
+	if !strings.Contains(out.String(), "Arenas.dat: missing") {
+		t.Errorf("Expected the missing Arenas.dat to be reported, got %q", out.String())
+	}
+	if status := runCommand([]string{"content", "lint"}, &out); status != 2 {
+		t.Errorf("Expected an unknown command to fail with status 2, got %d", status)
+	}
+
+	// The shipped content is clean
+	out.Reset()
+	if status := runCommand([]string{"content", "check", contentDir()}, &out); status != 0 {
+		t.Errorf("Expected the shipped content to pass the check, got status %d:\n%s", status, out.String())
+	}
+	if !strings.Contains(out.String(), "No problems found") {
+		t.Errorf("Expected no problems in the shipped content, got:\n%s", out.String())
+	}
+}
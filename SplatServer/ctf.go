@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// OrbState is where a team's capture-the-flag orb is, as in MageServer's CTFOrbState
+type OrbState int
+
+const (
+	OrbInHomeShrine OrbState = iota
+	OrbOnEnemyPlayer
+	OrbOnAnotherPlayer // unused, as in MageServer; kept so state values match
+	OrbOnGround
+)
+
+const (
+	orbTouchRadius      = 48.0 // distance at which a player touches an orb or its shrine
+	defaultCaptureLimit = 3
+	captureScore        = 3 // score for carrying an enemy orb home
+)
+
+// CTFOrb is a team's orb in a capture-the-flag match
+type CTFOrb struct {
+	Team         Team
+	State        OrbState
+	CarrierID    int // player carrying the orb, 0 when it is not carried
+	X, Y         float64
+	HomeX, HomeY float64 // the team's shrine, where the orb rests and is captured
+}
+
+// isHome reports whether a point touches the orb's shrine
+func (o *CTFOrb) isHome(x, y float64) bool {
+	return math.Hypot(x-o.HomeX, y-o.HomeY) <= orbTouchRadius
+}
+
+// touches reports whether a point touches the orb where it lies
+func (o *CTFOrb) touches(x, y float64) bool {
+	return math.Hypot(x-o.X, y-o.Y) <= orbTouchRadius
+}
+
+// captureLimit returns the captures a team needs to win
+func (a *Arena) captureLimit() int {
+	if a.CaptureLimit <= 0 {
+		return defaultCaptureLimit
+	}
+	return a.CaptureLimit
+}
+
+// placeOrbsLocked puts an orb at the shrine of every team playing a capture-the-flag
+// match and clears the capture counts; the caller must hold a.mu
+func (a *Arena) placeOrbsLocked() {
+	a.Orbs = nil
+	a.Captures = nil
+	if !a.Ruleset.Rules.Has(ArenaRuleCaptureTheFlag) || a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		return
+	}
+
+	a.Orbs = make(map[Team]*CTFOrb)
+	a.Captures = make(map[Team]int)
+	for _, team := range []Team{TeamChaos, TeamBalance, TeamOrder} {
+		if !a.teamAllowedLocked(team) {
+			continue
+		}
+		orb := &CTFOrb{Team: team}
+		orb.HomeX, orb.HomeY = a.orbHomeLocked(team)
+		a.resetOrbLocked(orb)
+		a.Orbs[team] = orb
+	}
+}
+
+// orbHomeLocked returns where a team's orb rests: its shrine, its start point from
+// World.dat without one, or the origin without either; the caller must hold a.mu
+func (a *Arena) orbHomeLocked(team Team) (float64, float64) {
+	if shrine, exists := a.Shrines[team]; exists {
+		return shrine.X, shrine.Y
+	}
+	if spawn, ok := a.spawnLocked(team); ok {
+		return spawn.Start.X, spawn.Start.Y
+	}
+	return 0, 0
+}
+
+// carriedOrbLocked returns the orb a player is carrying, or nil; the caller must hold a.mu
+func (a *Arena) carriedOrbLocked(playerID int) *CTFOrb {
+	for _, orb := range a.Orbs {
+		if orb.State == OrbOnEnemyPlayer && orb.CarrierID == playerID {
+			return orb
+		}
+	}
+	return nil
+}
+
+// updateOrbsLocked moves carried orbs with their carriers and applies every touch:
+// enemies pick up orbs from shrines and the ground, teammates return dropped orbs,
+// and carriers capture by reaching their own shrine while their orb is home.
+// The caller must hold a.mu
+func (a *Arena) updateOrbsLocked() {
+	for _, orb := range a.Orbs {
+		if orb.State != OrbOnEnemyPlayer {
+			continue
+		}
+		if carrier, exists := a.Players[orb.CarrierID]; exists {
+			orb.X, orb.Y = carrier.X, carrier.Y
+		}
+	}
+
+	for _, player := range a.Players {
+		if player.Dead || player.Team == TeamNone {
+			continue
+		}
+		for _, orb := range a.Orbs {
+			a.touchOrbLocked(player, orb)
+		}
+	}
+}
+
+// touchOrbLocked applies a living player's touch to an orb; the caller must hold a.mu
+func (a *Arena) touchOrbLocked(player *ArenaPlayer, orb *CTFOrb) {
+	switch {
+	case orb.Team != player.Team:
+		if orb.State == OrbOnEnemyPlayer || !orb.touches(player.X, player.Y) || a.carriedOrbLocked(player.PlayerID) != nil {
+			return
+		}
+		// As in MageServer, an orb cannot be taken from a destroyed shrine
+		if orb.State == OrbInHomeShrine && !a.shrineAliveLocked(orb.Team) {
+			return
+		}
+		orb.State, orb.CarrierID = OrbOnEnemyPlayer, player.PlayerID
+		a.broadcastOrbLocked(orb, fmt.Sprintf("Player %d has picked up the %s orb!", player.PlayerID, orb.Team))
+
+	case orb.State == OrbOnGround && orb.touches(player.X, player.Y):
+		a.resetOrbLocked(orb)
+		a.broadcastOrbLocked(orb, fmt.Sprintf("The %s orb has been returned to its shrine.", orb.Team))
+
+	case orb.State == OrbInHomeShrine && orb.isHome(player.X, player.Y):
+		captured := a.carriedOrbLocked(player.PlayerID)
+		if captured == nil || !a.shrineAliveLocked(player.Team) {
+			return
+		}
+		a.resetOrbLocked(captured)
+		a.damageShrineLocked(captured.Team, orbCaptureShrineBias)
+		a.Captures[player.Team]++
+		player.Score += captureScore
+		a.giveExpLocked(player, captureExp(effectiveLevel(player.Level), a.teamCountsLocked()[captured.Team]))
+		a.broadcastOrbLocked(captured, fmt.Sprintf("Player %d has captured the %s orb!", player.PlayerID, captured.Team))
+	}
+}
+
+// dropOrbLocked leaves the orb a dying player carries on the ground where they fell;
+// the caller must hold a.mu
+func (a *Arena) dropOrbLocked(player *ArenaPlayer) {
+	orb := a.carriedOrbLocked(player.PlayerID)
+	if orb == nil {
+		return
+	}
+	orb.State, orb.CarrierID = OrbOnGround, 0
+	orb.X, orb.Y = player.X, player.Y
+	a.broadcastOrbLocked(orb, fmt.Sprintf("The %s orb has been dropped by player %d.", orb.Team, player.PlayerID))
+}
+
+// returnOrbLocked sends the orb a leaving player carries back to its shrine;
+// the caller must hold a.mu
+func (a *Arena) returnOrbLocked(playerID int) {
+	orb := a.carriedOrbLocked(playerID)
+	if orb == nil {
+		return
+	}
+	a.resetOrbLocked(orb)
+	a.broadcastOrbLocked(orb, fmt.Sprintf("The %s orb has been returned to its shrine.", orb.Team))
+}
+
+// resetOrbLocked puts an orb back in its shrine; the caller must hold a.mu
+func (a *Arena) resetOrbLocked(orb *CTFOrb) {
+	orb.State, orb.CarrierID = OrbInHomeShrine, 0
+	orb.X, orb.Y = orb.HomeX, orb.HomeY
+}
+
+// broadcastOrbLocked announces an orb's new state to the arena; the caller must hold a.mu
+func (a *Arena) broadcastOrbLocked(orb *CTFOrb, message string) {
+	a.broadcastLocked(BuildOrbStatePacket(a.ID, orb))
+	a.broadcastLocked(BuildChatPacket(message))
+}
+
+// captureWinnerLocked returns the team that has reached the capture limit, if any;
+// the caller must hold a.mu
+func (a *Arena) captureWinnerLocked() (Team, bool) {
+	for team, captures := range a.Captures {
+		if captures >= a.captureLimit() {
+			return team, true
+		}
+	}
+	return TeamNone, false
+}
+
+// OrbPackets describes every orb in the arena, for players joining a match in progress
+func (a *Arena) OrbPackets() []*Packet {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	packets := make([]*Packet, 0, len(a.Orbs))
+	for _, orb := range a.Orbs {
+		packets = append(packets, BuildOrbStatePacket(a.ID, orb))
+	}
+	return packets
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newTestCTFArena returns an active capture-the-flag arena with Chaos and Order shrines
+// 1000 units apart and one player on each team at their own shrine
+func newTestCTFArena(t *testing.T) *Arena {
+	t.Helper()
+	arena := newTestArena(1)
+	arena.Ruleset = Ruleset{Mode: ArenaModeCaptureTheFlag, Rules: ArenaRuleCaptureTheFlag | ArenaRuleTwoTeams}
+	arena.World = &World{Spawns: map[Team]TeamSpawn{
+		TeamChaos: {Start: SpawnPoint{X: 0, Y: 0}, Raise: SpawnPoint{X: 0, Y: 0}},
+		TeamOrder: {Start: SpawnPoint{X: 1000, Y: 0}, Raise: SpawnPoint{X: 1000, Y: 0}},
+	}}
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.mu.Lock()
+	arena.DisabledTeam = TeamBalance
+	arena.startLocked()
+	arena.mu.Unlock()
+	if len(arena.Orbs) != 2 {
+		t.Fatalf("Expected an orb for each playing team, got %d", len(arena.Orbs))
+	}
+	return arena
+}
+
+// moveAndUpdateOrbs moves a player and runs one orb update
+func moveAndUpdateOrbs(arena *Arena, playerID int, x, y float64) {
+	arena.UpdatePlayerPosition(playerID, x, y)
+	arena.mu.Lock()
+	arena.updateOrbsLocked()
+	arena.mu.Unlock()
+}
+
+func TestOrbPickupAndCapture(t *testing.T) {
+	arena := newTestCTFArena(t)
+	conn := &captureConn{}
+	arena.SetPlayerConn(2, conn)
+	orderOrb := arena.Orbs[TeamOrder]
+
+	// Touching your own orb at home does nothing
+	moveAndUpdateOrbs(arena, 2, 1000, 0)
+	if orderOrb.State != OrbInHomeShrine || conn.written.Len() != 0 {
+		t.Fatalf("Expected the orb to stay home, got %+v", orderOrb)
+	}
+
+	moveAndUpdateOrbs(arena, 1, 990, 10)
+	if orderOrb.State != OrbOnEnemyPlayer || orderOrb.CarrierID != 1 {
+		t.Fatalf("Expected player 1 to carry the Order orb, got %+v", orderOrb)
+	}
+	packet, err := DeserializePacket(bytes.NewReader(conn.written.Bytes()))
+	if err != nil || packet.Type != PacketOrbState {
+		t.Errorf("Expected an orb state packet, got %+v, %v", packet, err)
+	}
+
+	moveAndUpdateOrbs(arena, 1, 500, 0)
+	if orderOrb.X != 500 {
+		t.Errorf("Expected the orb to follow its carrier, got %+v", orderOrb)
+	}
+
+	moveAndUpdateOrbs(arena, 1, 10, 0)
+	capturer := arena.GetPlayer(1)
+	if orderOrb.State != OrbInHomeShrine || orderOrb.X != 1000 {
+		t.Errorf("Expected the captured orb back at its shrine, got %+v", orderOrb)
+	}
+	if arena.Captures[TeamChaos] != 1 || capturer.Score != captureScore || capturer.PendingExp == 0 {
+		t.Errorf("Expected a scored capture, got %d captures, score %d and %d experience",
+			arena.Captures[TeamChaos], capturer.Score, capturer.PendingExp)
+	}
+}
+
+func TestCaptureNeedsOwnOrbHome(t *testing.T) {
+	arena := newTestCTFArena(t)
+
+	moveAndUpdateOrbs(arena, 1, 1000, 0)
+	moveAndUpdateOrbs(arena, 2, 0, 0)
+	moveAndUpdateOrbs(arena, 1, 0, 0)
+	if arena.Captures[TeamChaos] != 0 || arena.Captures[TeamOrder] != 0 {
+		t.Errorf("Expected no capture while both orbs are carried, got %v", arena.Captures)
+	}
+}
+
+func TestOrbDroppedOnDeathAndReturned(t *testing.T) {
+	arena := newTestCTFArena(t)
+	orderOrb := arena.Orbs[TeamOrder]
+
+	moveAndUpdateOrbs(arena, 1, 1000, 0)
+	moveAndUpdateOrbs(arena, 1, 600, 0)
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[1], 2)
+	arena.mu.Unlock()
+	if orderOrb.State != OrbOnGround || orderOrb.X != 600 || orderOrb.CarrierID != 0 {
+		t.Fatalf("Expected the orb on the ground where its carrier fell, got %+v", orderOrb)
+	}
+
+	moveAndUpdateOrbs(arena, 2, 610, 0)
+	if orderOrb.State != OrbInHomeShrine || orderOrb.X != 1000 {
+		t.Errorf("Expected Order to return its orb, got %+v", orderOrb)
+	}
+
+	// A carrier leaving sends the orb home
+	arena.AddPlayer(3, TeamChaos)
+	moveAndUpdateOrbs(arena, 3, 1000, 0)
+	arena.RemovePlayer(3)
+	if orderOrb.State != OrbInHomeShrine || orderOrb.CarrierID != 0 {
+		t.Errorf("Expected the orb home after its carrier left, got %+v", orderOrb)
+	}
+}
+
+func TestCaptureLimitEndsMatch(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	arena := newTestCTFArena(t)
+	arena.CaptureLimit = 1
+	gs.ArenaManager.Arenas[arena.ID] = arena
+
+	moveAndUpdateOrbs(arena, 1, 1000, 0)
+	UpdateArenas(gs)
+	if arena.State != ArenaStateActive {
+		t.Fatal("Expected the match to continue before the limit is reached")
+	}
+
+	arena.UpdatePlayerPosition(1, 0, 0)
+	UpdateArenas(gs)
+	if arena.State != ArenaStateEnded || arena.Captures[TeamChaos] != 1 {
+		t.Errorf("Expected the capture to end the match, got state %d and %v", arena.State, arena.Captures)
+	}
+}
@@ -90,11 +90,180 @@ func CreateTables() {
 		log.Fatalf("Failed to create players table: %v", err)
 	}
 
+	var spellbookTable string
+	if dbType == "sqlite" {
+		spellbookTable = `
+		CREATE TABLE IF NOT EXISTS spellbooks (
+			player_id INTEGER PRIMARY KEY,
+			class INTEGER DEFAULT 0,
+			trees TEXT NOT NULL DEFAULT '',
+			spells TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`
+	} else {
+		spellbookTable = `
+		CREATE TABLE IF NOT EXISTS spellbooks (
+			player_id INT PRIMARY KEY,
+			class INT DEFAULT 0,
+			trees VARCHAR(1024) NOT NULL DEFAULT '',
+			spells VARCHAR(1024) NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		)`
+	}
+
+	if _, err := db.Exec(spellbookTable); err != nil {
+		log.Fatalf("Failed to create spellbooks table: %v", err)
+	}
+
+	var progressionTable string
+	if dbType == "sqlite" {
+		progressionTable = `
+		CREATE TABLE IF NOT EXISTS progression (
+			player_id INTEGER PRIMARY KEY,
+			level INTEGER DEFAULT 1,
+			experience INTEGER DEFAULT 0,
+			constitution INTEGER DEFAULT 0,
+			empathy INTEGER DEFAULT 0,
+			discipline INTEGER DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`
+	} else {
+		progressionTable = `
+		CREATE TABLE IF NOT EXISTS progression (
+			player_id INT PRIMARY KEY,
+			level INT DEFAULT 1,
+			experience INT DEFAULT 0,
+			constitution INT DEFAULT 0,
+			empathy INT DEFAULT 0,
+			discipline INT DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		)`
+	}
+
+	if _, err := db.Exec(progressionTable); err != nil {
+		log.Fatalf("Failed to create progression table: %v", err)
+	}
+
+	var matchStatisticsTable, playerStatisticsTable string
+	if dbType == "sqlite" {
+		matchStatisticsTable = `
+		CREATE TABLE IF NOT EXISTS match_statistics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_id INTEGER NOT NULL,
+			arena_id INTEGER NOT NULL,
+			kills INTEGER DEFAULT 0,
+			deaths INTEGER DEFAULT 0,
+			raises INTEGER DEFAULT 0,
+			damage_done INTEGER DEFAULT 0,
+			damage_taken INTEGER DEFAULT 0,
+			healing_done INTEGER DEFAULT 0,
+			healing_taken INTEGER DEFAULT 0,
+			experience INTEGER DEFAULT 0,
+			played_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`
+		playerStatisticsTable = `
+		CREATE TABLE IF NOT EXISTS player_statistics (
+			player_id INTEGER PRIMARY KEY,
+			matches INTEGER DEFAULT 0,
+			kills INTEGER DEFAULT 0,
+			deaths INTEGER DEFAULT 0,
+			raises INTEGER DEFAULT 0,
+			damage_done INTEGER DEFAULT 0,
+			damage_taken INTEGER DEFAULT 0,
+			healing_done INTEGER DEFAULT 0,
+			healing_taken INTEGER DEFAULT 0,
+			experience INTEGER DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`
+	} else {
+		matchStatisticsTable = `
+		CREATE TABLE IF NOT EXISTS match_statistics (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			player_id INT NOT NULL,
+			arena_id INT NOT NULL,
+			kills INT DEFAULT 0,
+			deaths INT DEFAULT 0,
+			raises INT DEFAULT 0,
+			damage_done INT DEFAULT 0,
+			damage_taken INT DEFAULT 0,
+			healing_done INT DEFAULT 0,
+			healing_taken INT DEFAULT 0,
+			experience INT DEFAULT 0,
+			played_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX (player_id, played_at)
+		)`
+		playerStatisticsTable = `
+		CREATE TABLE IF NOT EXISTS player_statistics (
+			player_id INT PRIMARY KEY,
+			matches INT DEFAULT 0,
+			kills INT DEFAULT 0,
+			deaths INT DEFAULT 0,
+			raises INT DEFAULT 0,
+			damage_done INT DEFAULT 0,
+			damage_taken INT DEFAULT 0,
+			healing_done INT DEFAULT 0,
+			healing_taken INT DEFAULT 0,
+			experience INT DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		)`
+	}
+
+	if _, err := db.Exec(matchStatisticsTable); err != nil {
+		log.Fatalf("Failed to create match_statistics table: %v", err)
+	}
+	if _, err := db.Exec(playerStatisticsTable); err != nil {
+		log.Fatalf("Failed to create player_statistics table: %v", err)
+	}
+
+	var matchesTable string
+	if dbType == "sqlite" {
+		matchesTable = `
+		CREATE TABLE IF NOT EXISTS matches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			arena_id INTEGER NOT NULL,
+			arena_name TEXT NOT NULL,
+			mode INTEGER DEFAULT 0,
+			winner_team INTEGER DEFAULT 0,
+			winner_player_id INTEGER DEFAULT 0,
+			reason TEXT NOT NULL DEFAULT '',
+			players INTEGER DEFAULT 0,
+			started_at DATETIME,
+			ended_at DATETIME
+		)`
+	} else {
+		matchesTable = `
+		CREATE TABLE IF NOT EXISTS matches (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			arena_id INT NOT NULL,
+			arena_name VARCHAR(255) NOT NULL,
+			mode INT DEFAULT 0,
+			winner_team INT DEFAULT 0,
+			winner_player_id INT DEFAULT 0,
+			reason VARCHAR(64) NOT NULL DEFAULT '',
+			players INT DEFAULT 0,
+			started_at DATETIME,
+			ended_at DATETIME,
+			INDEX (arena_id, ended_at)
+		)`
+	}
+
+	if _, err := db.Exec(matchesTable); err != nil {
+		log.Fatalf("Failed to create matches table: %v", err)
+	}
+
 	fmt.Println("Database tables ready")
 }
 
-// SavePlayer saves or updates a player in the database
+// SavePlayer saves or updates a player and their progression in the database
 func SavePlayer(p *Player) error {
+	if err := savePlayerRow(p); err != nil {
+		return err
+	}
+	return SaveProgression(p)
+}
+
+// savePlayerRow saves or updates the players table row
+func savePlayerRow(p *Player) error {
 	if dbType == "sqlite" {
 		// SQLite doesn't support ON DUPLICATE KEY UPDATE
 		// First try to update, if no rows affected, insert
@@ -141,9 +310,174 @@ func LoadPlayer(id int) (*Player, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := LoadProgression(&p); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
+// SaveProgression saves a player's level, experience and allocated stat points
+func SaveProgression(p *Player) error {
+	var query string
+	if dbType == "sqlite" {
+		query = `
+			INSERT OR REPLACE INTO progression (player_id, level, experience, constitution, empathy, discipline, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, datetime('now'))`
+	} else {
+		query = `
+			INSERT INTO progression (player_id, level, experience, constitution, empathy, discipline)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE level=VALUES(level), experience=VALUES(experience),
+				constitution=VALUES(constitution), empathy=VALUES(empathy), discipline=VALUES(discipline)`
+	}
+
+	_, err := db.Exec(query, p.ID, effectiveLevel(p.Level), p.Experience,
+		p.Stats.Constitution, p.Stats.Empathy, p.Stats.Discipline)
+	return err
+}
+
+// LoadProgression loads a player's progression, leaving a new character at level 1
+func LoadProgression(p *Player) error {
+	row := db.QueryRow("SELECT level, experience, constitution, empathy, discipline FROM progression WHERE player_id = ?", p.ID)
+	err := row.Scan(&p.Level, &p.Experience, &p.Stats.Constitution, &p.Stats.Empathy, &p.Stats.Discipline)
+	if err == sql.ErrNoRows {
+		p.Level = 1
+		return nil
+	}
+	return err
+}
+
+// SaveSpellbook saves a player's class, tree training and equipped spells
+func SaveSpellbook(playerID int, book *Spellbook) error {
+	book.mu.RLock()
+	class, trees, spells := int(book.Class), encodeTreeLevels(book.TreeLevels), encodeSpellIDs(book.Spells)
+	book.mu.RUnlock()
+
+	var query string
+	if dbType == "sqlite" {
+		query = `
+			INSERT OR REPLACE INTO spellbooks (player_id, class, trees, spells, updated_at)
+			VALUES (?, ?, ?, ?, datetime('now'))`
+	} else {
+		query = `
+			INSERT INTO spellbooks (player_id, class, trees, spells)
+			VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE class=VALUES(class), trees=VALUES(trees), spells=VALUES(spells)`
+	}
+
+	_, err := db.Exec(query, playerID, class, trees, spells)
+	return err
+}
+
+// LoadSpellbook loads a player's spellbook from the database
+func LoadSpellbook(playerID int) (*Spellbook, error) {
+	var class int
+	var trees, spells string
+	row := db.QueryRow("SELECT class, trees, spells FROM spellbooks WHERE player_id = ?", playerID)
+	if err := row.Scan(&class, &trees, &spells); err != nil {
+		return nil, err
+	}
+
+	book := NewSpellbook(PlayerClass(class))
+	var err error
+	if book.TreeLevels, err = decodeTreeLevels(trees); err != nil {
+		return nil, err
+	}
+	if book.Spells, err = decodeSpellIDs(spells); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// SaveMatchStatistics records a player's statistics for one match and adds them to
+// the player's lifetime totals
+func SaveMatchStatistics(playerID, arenaID int, sheet StatisticSheet) error {
+	counters := []interface{}{sheet.Kills, sheet.Deaths, sheet.Raises,
+		sheet.DamageDone, sheet.DamageTaken, sheet.HealingDone, sheet.HealingTaken, sheet.Experience}
+
+	_, err := db.Exec(`
+		INSERT INTO match_statistics (player_id, arena_id, kills, deaths, raises,
+			damage_done, damage_taken, healing_done, healing_taken, experience)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		append([]interface{}{playerID, arenaID}, counters...)...)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	if dbType == "sqlite" {
+		query = `
+			INSERT INTO player_statistics (player_id, matches, kills, deaths, raises,
+				damage_done, damage_taken, healing_done, healing_taken, experience, updated_at)
+			VALUES (?, 1, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+			ON CONFLICT(player_id) DO UPDATE SET matches=matches+1,
+				kills=kills+excluded.kills, deaths=deaths+excluded.deaths, raises=raises+excluded.raises,
+				damage_done=damage_done+excluded.damage_done, damage_taken=damage_taken+excluded.damage_taken,
+				healing_done=healing_done+excluded.healing_done, healing_taken=healing_taken+excluded.healing_taken,
+				experience=experience+excluded.experience, updated_at=excluded.updated_at`
+	} else {
+		query = `
+			INSERT INTO player_statistics (player_id, matches, kills, deaths, raises,
+				damage_done, damage_taken, healing_done, healing_taken, experience)
+			VALUES (?, 1, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE matches=matches+1,
+				kills=kills+VALUES(kills), deaths=deaths+VALUES(deaths), raises=raises+VALUES(raises),
+				damage_done=damage_done+VALUES(damage_done), damage_taken=damage_taken+VALUES(damage_taken),
+				healing_done=healing_done+VALUES(healing_done), healing_taken=healing_taken+VALUES(healing_taken),
+				experience=experience+VALUES(experience)`
+	}
+
+	_, err = db.Exec(query, append([]interface{}{playerID}, counters...)...)
+	return err
+}
+
+// LoadLifetimeStatistics loads a player's lifetime totals; players without any matches get zeros
+func LoadLifetimeStatistics(playerID int) (LifetimeStatistics, error) {
+	var l LifetimeStatistics
+	row := db.QueryRow(`
+		SELECT matches, kills, deaths, raises, damage_done, damage_taken, healing_done, healing_taken, experience
+		FROM player_statistics WHERE player_id = ?`, playerID)
+	err := row.Scan(&l.Matches, &l.Kills, &l.Deaths, &l.Raises,
+		&l.DamageDone, &l.DamageTaken, &l.HealingDone, &l.HealingTaken, &l.Experience)
+	if err == sql.ErrNoRows {
+		return LifetimeStatistics{}, nil
+	}
+	return l, err
+}
+
+// SaveMatchResult records the outcome of a match
+func SaveMatchResult(r MatchResult) error {
+	_, err := db.Exec(`
+		INSERT INTO matches (arena_id, arena_name, mode, winner_team, winner_player_id, reason, players, started_at, ended_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ArenaID, r.ArenaName, int(r.Mode), int(r.Winner), r.WinnerID, r.Reason, r.Players,
+		r.StartedAt.UTC(), r.EndedAt.UTC())
+	return err
+}
+
+// LoadRecentMatches loads an arena's most recent match results, newest first
+func LoadRecentMatches(arenaID, limit int) ([]MatchResult, error) {
+	rows, err := db.Query(`
+		SELECT arena_id, arena_name, mode, winner_team, winner_player_id, reason, players, started_at, ended_at
+		FROM matches WHERE arena_id = ? ORDER BY ended_at DESC, id DESC LIMIT ?`, arenaID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []MatchResult
+	for rows.Next() {
+		var r MatchResult
+		var mode, winner int
+		if err := rows.Scan(&r.ArenaID, &r.ArenaName, &mode, &winner, &r.WinnerID, &r.Reason, &r.Players, &r.StartedAt, &r.EndedAt); err != nil {
+			return nil, err
+		}
+		r.Mode, r.Winner = ArenaMode(mode), Team(winner)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
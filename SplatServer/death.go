@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultRespawnDelay is how long a dead player waits before returning at their raise point
+const defaultRespawnDelay = 10 * time.Second
+
+// respawnDelay returns the time dead players wait to respawn in this arena
+func (a *Arena) respawnDelay() time.Duration {
+	if a.RespawnDelay <= 0 {
+		return defaultRespawnDelay
+	}
+	return a.RespawnDelay
+}
+
+// killPlayerLocked leaves a player's corpse where they fell and starts their respawn
+// timer; the caller must hold a.mu
+func (a *Arena) killPlayerLocked(victim *ArenaPlayer, killerID int) {
+	victim.Health = 0
+	victim.Dead = true
+	victim.RespawnAt = time.Now().Add(a.respawnDelay())
+	a.broadcastLocked(BuildPlayerDeathPacket(a.ID, victim, killerID, a.respawnDelay()))
+	a.dropOrbLocked(victim)
+}
+
+// updateDeadLocked returns players whose respawn timer has run out to their team's
+// raise point with full health; the caller must hold a.mu
+func (a *Arena) updateDeadLocked(now time.Time) {
+	for _, player := range a.Players {
+		if !player.Dead || now.Before(player.RespawnAt) {
+			continue
+		}
+
+		point := SpawnPoint{X: player.X, Y: player.Y, Angle: player.Angle}
+		if spawn, ok := a.spawnLocked(player.Team); ok {
+			point = spawn.Raise
+		}
+		a.reviveLocked(player, point, player.maxHealth(), 0)
+	}
+}
+
+// raisePlayerLocked brings a dead player back where they fell with the share of their
+// health the resurrect effect restores; the caller must hold a.mu
+func (a *Arena) raisePlayerLocked(caster, target *ArenaPlayer, effect *Spell) {
+	if !target.Dead || a.Ruleset.Rules.Has(ArenaRuleNoRaiseCall) {
+		return
+	}
+
+	raisedBy := 0
+	if caster != nil {
+		raisedBy = caster.PlayerID
+	}
+	here := SpawnPoint{X: target.X, Y: target.Y, Angle: target.Angle}
+	a.reviveLocked(target, here, target.maxHealth()*effect.RaisePercent/100, raisedBy)
+
+	if caster != nil && caster != target {
+		a.recordRaiseLocked(caster)
+		a.giveExpLocked(caster, raiseExp(target.Level, target.Health))
+	}
+}
+
+// reviveLocked brings a dead player back to life at a point with at least one
+// health; raisedBy is 0 for a timed respawn. The caller must hold a.mu
+func (a *Arena) reviveLocked(player *ArenaPlayer, point SpawnPoint, health, raisedBy int) {
+	player.Dead = false
+	player.RespawnAt = time.Time{}
+	a.movePlayerLocked(player, point.X, point.Y)
+	player.Angle = point.Angle
+	player.Health = max(1, min(health, player.maxHealth()))
+	a.broadcastLocked(BuildPlayerRespawnPacket(a.ID, player, raisedBy))
+}
+
+// CallForRaise lets a dead player ask their teammates to raise them
+func (a *Arena) CallForRaise(playerID int) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	player, exists := a.Players[playerID]
+	if !exists {
+		return fmt.Errorf("player %d is not in arena %d", playerID, a.ID)
+	}
+	if !player.Dead {
+		return fmt.Errorf("only the dead can call for a raise")
+	}
+	if a.Ruleset.Rules.Has(ArenaRuleNoRaiseCall) {
+		return fmt.Errorf("raise calls are not allowed in %s", a.Name)
+	}
+
+	data := BuildChatPacket(fmt.Sprintf("Player %d calls for a raise at (%.0f, %.0f)", playerID, player.X, player.Y)).Serialize()
+	for _, mate := range a.Players {
+		if mate == player || mate.Conn == nil || player.Team == TeamNone || mate.Team != player.Team {
+			continue
+		}
+		mate.Conn.Write(data)
+	}
+	return nil
+}
+
+// TapShrine lets a dead player tap their team's shrine, as in MageServer, to return at
+// their raise point with full health without waiting out the respawn timer. Tapping is
+// refused under the NoTapping rule and once the team's shrine is dead
+func (a *Arena) TapShrine(playerID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, exists := a.Players[playerID]
+	if !exists {
+		return fmt.Errorf("player %d is not in arena %d", playerID, a.ID)
+	}
+	if !player.Dead {
+		return fmt.Errorf("only the dead can tap their shrine")
+	}
+	if a.Ruleset.Rules.Has(ArenaRuleNoTapping) {
+		return fmt.Errorf("tapping is not allowed in %s", a.Name)
+	}
+	if player.Team != TeamNone && !a.shrineAliveLocked(player.Team) {
+		return fmt.Errorf("your team's shrine is dead")
+	}
+
+	point := SpawnPoint{X: player.X, Y: player.Y, Angle: player.Angle}
+	if spawn, ok := a.spawnLocked(player.Team); ok {
+		point = spawn.Raise
+	}
+	a.reviveLocked(player, point, player.maxHealth(), 0)
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKilledPlayerRespawnsAtRaisePoint(t *testing.T) {
+	arena := newTestArena(1)
+	arena.World = &World{Spawns: map[Team]TeamSpawn{
+		TeamOrder: {Raise: SpawnPoint{X: 640, Y: 320}},
+	}}
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 100, 50)
+	conn := &captureConn{}
+	arena.SetPlayerConn(1, conn)
+
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 500})
+	arena.mu.Unlock()
+
+	victim := arena.GetPlayer(2)
+	if !victim.Dead || victim.Health != 0 || victim.X != 100 || victim.Y != 50 {
+		t.Fatalf("Expected a corpse at (100, 50), got %+v", victim)
+	}
+	packet, err := DeserializePacket(bytes.NewReader(conn.written.Bytes()))
+	if err != nil || packet.Type != PacketPlayerDeath {
+		t.Fatalf("Expected a death packet, got %+v, %v", packet, err)
+	}
+
+	// Corpses cannot move, be hurt or be healed
+	if arena.UpdatePlayerPosition(2, 0, 0) {
+		t.Error("Expected a dead player not to move")
+	}
+	arena.mu.Lock()
+	arena.applySpellLocked(1, victim, &Spell{ID: 1, Name: "Hit", Damage: 10})
+	arena.applySpellLocked(2, victim, &Spell{ID: 2, Name: "Heal", Healing: 10})
+	arena.mu.Unlock()
+	if victim.Health != 0 || arena.GetPlayer(1).Statistics.Kills != 1 {
+		t.Errorf("Expected the corpse to be untouched, got health %d", victim.Health)
+	}
+
+	arena.mu.Lock()
+	arena.updateDeadLocked(time.Now())
+	arena.mu.Unlock()
+	if !victim.Dead {
+		t.Fatal("Expected the player to stay dead until the respawn delay passes")
+	}
+
+	conn.written.Reset()
+	arena.mu.Lock()
+	arena.updateDeadLocked(time.Now().Add(defaultRespawnDelay))
+	arena.mu.Unlock()
+	if victim.Dead || victim.Health != 100 || victim.X != 640 || victim.Y != 320 {
+		t.Errorf("Expected a respawn at (640, 320) with full health, got %+v", victim)
+	}
+	if packet, err := DeserializePacket(bytes.NewReader(conn.written.Bytes())); err != nil || packet.Type != PacketPlayerRespawn {
+		t.Errorf("Expected a respawn packet, got %+v, %v", packet, err)
+	}
+}
+
+func TestRaiseSpell(t *testing.T) {
+	gs := NewGameState()
+	effect := &Spell{ID: 286, Name: "Resurrect II Effect", Type: SpellTypeEffect, EffectType: SpellEffectResurrect, RaisePercent: 30}
+	raise := &Spell{ID: 287, Name: "Resurrect II", Type: SpellTypeTarget, FriendlyType: SpellFriendlyDead, Range: 400, TargetSpellID: 286}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{effect, raise})
+	addTestCaster(gs, 1, 287)
+	addTestCaster(gs, 3, 287)
+
+	arena := newTestArena(1)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamChaos)
+	arena.AddPlayer(3, TeamOrder)
+	arena.UpdatePlayerPosition(2, 100, 0)
+
+	if _, err := gs.CastSpell(1, 287, 0, 0, 2); err == nil {
+		t.Error("Expected raising a living player to fail")
+	}
+
+	arena.mu.Lock()
+	arena.applySpellLocked(3, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 500})
+	arena.mu.Unlock()
+
+	if _, err := gs.CastSpell(3, 287, 0, 0, 2); err == nil {
+		t.Error("Expected an enemy not to raise the corpse")
+	}
+	if _, err := gs.CastSpell(1, 287, 0, 0, 2); err != nil {
+		t.Fatalf("Failed to raise a dead ally: %v", err)
+	}
+
+	raised, caster := arena.GetPlayer(2), arena.GetPlayer(1)
+	if raised.Dead || raised.Health != 30 || raised.X != 100 {
+		t.Errorf("Expected a raise in place with 30 health, got %+v", raised)
+	}
+	// 25 + 30 healed + level 1 * 5
+	if caster.Statistics.Raises != 1 || caster.PendingExp != 60 {
+		t.Errorf("Expected a credited raise, got %+v with %d experience", caster.Statistics, caster.PendingExp)
+	}
+	if raised.Statistics.Deaths != 1 || arena.GetPlayer(3).Statistics.Kills != 1 {
+		t.Error("Expected the kill and death to be credited")
+	}
+}
+
+func TestNoRaiseCallRule(t *testing.T) {
+	gs := NewGameState()
+	effect := &Spell{ID: 286, Name: "Resurrect II Effect", Type: SpellTypeEffect, EffectType: SpellEffectResurrect, RaisePercent: 30}
+	raise := &Spell{ID: 287, Name: "Resurrect II", Type: SpellTypeTarget, FriendlyType: SpellFriendlyDead, Range: 400, TargetSpellID: 286}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{effect, raise})
+	addTestCaster(gs, 1, 287)
+	dead := addTestCaster(gs, 2)
+
+	arena := newTestArena(1)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamChaos)
+	teammate := &captureConn{}
+	arena.SetPlayerConn(1, teammate)
+
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[2], 0)
+	arena.mu.Unlock()
+
+	teammate.written.Reset()
+	handleChatCommand("!callraise", dead, gs)
+	if !strings.Contains(teammate.written.String(), "calls for a raise") {
+		t.Errorf("Expected the teammate to hear the call, got %q", teammate.written.String())
+	}
+
+	arena.Ruleset.Rules = ArenaRuleNoRaiseCall
+	if err := arena.CallForRaise(2); err == nil {
+		t.Error("Expected raise calls to be refused")
+	}
+	if _, err := gs.CastSpell(1, 287, 0, 0, 2); err == nil {
+		t.Error("Expected raising to be refused")
+	}
+	if !arena.GetPlayer(2).Dead {
+		t.Error("Expected the player to stay dead")
+	}
+}
+
+func TestTapShrine(t *testing.T) {
+	arena := newTestObjectiveArena(t, 0)
+	arena.World.Spawns[TeamChaos] = TeamSpawn{Raise: SpawnPoint{X: 40, Y: 60}}
+	arena.UpdatePlayerPosition(1, 300, 0)
+	if err := arena.TapShrine(1); err == nil {
+		t.Error("Expected the living not to tap")
+	}
+
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[1], 2)
+	arena.mu.Unlock()
+	if err := arena.TapShrine(1); err != nil {
+		t.Fatalf("TapShrine failed: %v", err)
+	}
+	if player := arena.GetPlayer(1); player.Dead || player.Health != player.maxHealth() || player.X != 40 || player.Y != 60 {
+		t.Errorf("Expected a return at (40, 60) with full health, got %+v", player)
+	}
+
+	// Tapping needs a living shrine and is refused under NoTapping
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[1], 2)
+	arena.Shrines[TeamChaos].Bias = 0
+	arena.mu.Unlock()
+	if err := arena.TapShrine(1); err == nil {
+		t.Error("Expected tapping a dead shrine to be refused")
+	}
+	arena.mu.Lock()
+	arena.Shrines[TeamChaos].Bias = 100
+	arena.Ruleset.Rules = ArenaRuleNoTapping
+	arena.mu.Unlock()
+	if err := arena.TapShrine(1); err == nil || !arena.GetPlayer(1).Dead {
+		t.Error("Expected tapping to be refused under NoTapping")
+	}
+}
+
+func TestDeadPlayersDoNotRegenerate(t *testing.T) {
+	gs := NewGameState()
+	player := &Player{ID: 1, Name: "Ghost", Health: 50, LastSeen: time.Now()}
+	gs.AddPlayer(player)
+
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.mu.Lock()
+	arena.killPlayerLocked(arena.Players[1], 0)
+	arena.mu.Unlock()
+
+	UpdateGameState(gs)
+	if player.Health != 50 || arena.GetPlayer(1).Health != 0 {
+		t.Errorf("Expected a dead player not to regenerate, got %d and %d in the arena", player.Health, arena.GetPlayer(1).Health)
+	}
+}
@@ -12,8 +12,16 @@ type Player struct {
 	Name     string
 	X, Y     float64
 	Health   int
+	Power    int
+	Level    int
+	Experience int
+	Stats    CharacterStats
+	Spellbook *Spellbook
 	Conn     net.Conn
 	LastSeen time.Time
+	Admin    bool // this connection gave ADMIN_PASSWORD with !admin
+
+	healthRegen, powerRegen float64 // fractional regeneration carried between ticks
 }
 
 // GameState holds the overall game world state
@@ -21,6 +29,8 @@ type GameState struct {
 	Players       map[int]*Player
 	ArenaManager  *ArenaManager
 	SpellSystem   *SpellSystem
+	Leaderboards  *Leaderboards
+	Bots          *BotManager
 	mu            sync.RWMutex
 }
 
@@ -30,6 +40,8 @@ func NewGameState() *GameState {
 		Players:      make(map[int]*Player),
 		ArenaManager: NewArenaManager(),
 		SpellSystem:  NewSpellSystem(),
+		Leaderboards: NewLeaderboards(),
+		Bots:         NewBotManager(),
 	}
 }
 
@@ -22,7 +22,6 @@ func GameLoop(gs *GameState) {
 // UpdateGameState updates the game state each tick
 func UpdateGameState(gs *GameState) {
 	gs.mu.Lock()
-	defer gs.mu.Unlock()
 
 	now := time.Now()
 
@@ -39,9 +38,14 @@ func UpdateGameState(gs *GameState) {
 	}
 
 	// Update player positions, health, etc. (only for remaining players)
+	regenScales := gs.ArenaManager.RegenScales()
 	for _, player := range gs.Players {
-		// Example: Simple movement or health regeneration
-		player.Health = min(100, player.Health+1) // Regenerate health
+		// Arena rules and death slow or stop regeneration for players in an arena
+		scale, inArena := regenScales[player.ID]
+		if !inArena {
+			scale = 1
+		}
+		player.Regenerate(scale)
 		player.LastSeen = time.Now()
 
 		// Periodic save (every 10 seconds)
@@ -53,11 +57,18 @@ func UpdateGameState(gs *GameState) {
 	}
 
 	// Update arenas
-	UpdateArenas(gs)
+	progressed := UpdateArenas(gs)
+	gs.mu.Unlock()
+
+	// Save levels gained and matches ended off the lock so the database doesn't hold up the game
+	saveProgressions(progressed)
 }
 
-// UpdateArenas updates all arenas in the game state
-func UpdateArenas(gs *GameState) {
+// UpdateArenas updates all arenas in the game state and returns snapshots of the
+// characters whose progression should be saved: those who levelled or finished a match
+func UpdateArenas(gs *GameState) []Player {
+	var progressed []Player
+	var finished []int
 	gs.ArenaManager.mu.RLock()
 	arenas := make([]*Arena, 0, len(gs.ArenaManager.Arenas))
 	for _, arena := range gs.ArenaManager.Arenas {
@@ -67,10 +78,42 @@ func UpdateArenas(gs *GameState) {
 
 	for _, arena := range arenas {
 		UpdateArena(arena)
+		if arena.CheckMatchEnd(time.Now()) {
+			for _, entry := range EndMatch(arena) {
+				if !entry.Bot {
+					finished = append(finished, entry.PlayerID)
+				}
+			}
+		}
 	}
 
 	// Update spell system
 	gs.SpellSystem.UpdateSpellSystem(16 * time.Millisecond) // ~60 FPS
+
+	// Resolve projectile hits now that they have moved
+	active := gs.SpellSystem.ActiveSpellsByArena()
+	for _, arena := range arenas {
+		ResolveProjectiles(gs, arena, active[arena.ID])
+	}
+
+	// Credit experience earned in arenas this tick
+	for _, arena := range arenas {
+		progressed = append(progressed, creditArenaExp(gs, arena)...)
+	}
+
+	// Regenerate arena health from each character's discipline
+	now := time.Now()
+	for _, arena := range arenas {
+		regenerateArena(gs, arena, now)
+	}
+
+	// Snapshot the players whose match ended once this tick's experience is credited
+	for _, id := range finished {
+		if player, exists := gs.Players[id]; exists {
+			progressed = append(progressed, progressionSnapshot(player))
+		}
+	}
+	return progressed
 }
 
 // UpdateArena updates a single arena
@@ -78,20 +121,24 @@ func UpdateArena(arena *Arena) {
 	arena.mu.Lock()
 	defer arena.mu.Unlock()
 
-	// Check if arena should start (minimum players, etc.)
-	if arena.State == ArenaStateWaiting && arena.GetPlayerCount() >= 2 {
-		arena.StartArena()
-		fmt.Printf("Arena %s started with %d players\n", arena.Name, arena.GetPlayerCount())
-	}
+	// Count down, warn and reopen; matches are ended by UpdateArenas
+	now := time.Now()
+	arena.updateLifecycleLocked(now)
+
+	// Walls and runes persist across states until their duration runs out
+	arena.expireWallsLocked(now)
+	arena.updateRunesLocked(now)
+	arena.updateDeadLocked(now)
+	arena.updateTriggersLocked(now)
+	arena.sendUpdatesLocked(now)
 
 	// Update arena logic based on state
 	switch arena.State {
 	case ArenaStateActive:
 		// Handle active arena gameplay
-		// TODO: Add collision detection, projectile updates, etc.
-	case ArenaStateEnded:
-		// Handle arena end logic
-		// TODO: Calculate winners, distribute rewards, etc.
+		arena.updateObjectivesLocked(now)
+		arena.announceGuildStandingsLocked(now)
+		arena.updateOrbsLocked()
 	}
 }
 
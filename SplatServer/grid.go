@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Grid.dat layout, as read by MageServer's Grid.LoadGrid
+const (
+	gridWidth       = 128 // blocks along each side of a grid
+	gridBlockCount  = gridWidth * gridWidth
+	gridBlockBytes  = 38 // nineteen little-endian int16 fields per block
+	gridObjectCount = 997
+	gridObjectBytes = 16    // object ID, x, y and z as little-endian int32s
+	gridSkyZ        = 32767 // MidBoxTopZ of a block open to the sky
+)
+
+// Block flags, as in MageServer's GridBlockFlag
+const (
+	blockFlagPool     = 0x78 // plus the pool ID, up to the first shrine flag
+	blockFlagShrine   = 0x96 // plus the shrine ID, for three shrines
+	blockFlagValhalla = 0xD2 // where the dead wait to be raised
+)
+
+// BlockShape is the shape cut into the top of a block's low box or the bottom of its mid box
+type BlockShape int
+
+// blockShapeNames names each BlockShape, as in MageServer's GridBlockShape
+var blockShapeNames = []string{
+	"None", "CenterPointShort", "WestShortSlant", "EastStairway", "MediumFullArchEastWest",
+	"SmallWestHalfArch", "SmallEastHalfArch", "SmallNorthHalfArch", "SmallSouthHalfArch",
+	"CenterPointLong", "CenterPointMid", "MediumFullArchNorthSouth", "Cylinder",
+	"EastCurvedRamp", "WestCurvedRamp", "SouthCurvedRamp", "NorthCurvedRamp",
+	"SouthEastCurvedRamp", "NorthEastCurvedRamp", "NorthWestCurvedRamp", "SouthWestCurvedRamp",
+	"EastAndSouthCurvedRamp", "EastAndNorthCurvedRamp", "WestAndNorthCurvedRamp", "WestAndSouthCurvedRamp",
+	"LargeWestHalfArch", "LargeEastHalfArch", "LargeNorthHalfArch", "LargeSouthHalfArch",
+	"LargeWestAndNorthHalfArch", "LargeWestAndSouthHalfArch", "LargeEastAndSouthHalfArch", "LargeEastAndNorthHalfArch",
+	"EastLongSlant", "WestLongSlant", "SouthLongSlant", "NorthLongSlant",
+	"EastLowLongSlant", "WestLowLongSlant", "SouthLowLongSlant", "NorthLowLongSlant",
+	"EastHalfCutFullArch", "WestHalfCutFullArch", "SouthHalfCutFullArch", "NorthHalfCutFullArch",
+	"WestFullVerticalHalfArch", "EastFullVerticalHalfArch", "NorthFullVerticalHalfArch", "SouthFullVerticalHalfArch",
+	"SmallFullArchEastWest", "SmallFullArchNorthSouth",
+}
+
+// String returns the shape's name
+func (s BlockShape) String() string {
+	if s >= 0 && int(s) < len(blockShapeNames) {
+		return blockShapeNames[s]
+	}
+	return fmt.Sprintf("shape %d", int(s))
+}
+
+// BlockTextures holds the texture IDs drawn on each face of a block
+type BlockTextures struct {
+	LowSides, LowTop int
+	MidSides, MidTop int
+	High, Ceiling    int
+}
+
+// GridBlock is one 64 unit square column of a grid, as in MageServer's GridBlock. The
+// low box rises from the ground to LowBoxTopZ and is the floor; the mid box hangs from
+// MidBoxTopZ down to MidBoxBottomZ and is the ceiling. Blocks open to the sky have a
+// MidBoxTopZ of 32767
+type GridBlock struct {
+	Column, Row    int
+	LowBoxTopZ     int
+	LowBoxTopMod   int // lowers the floor on the server, as MageServer does when loading
+	MidBoxBottomZ  int
+	MidBoxTopZ     int
+	HighBoxBottomZ int
+	LowTopShape    BlockShape
+	MidBottomShape BlockShape
+	LowTileID      int // tile cut into the top of the low box, 0 for none
+	Flags          int // pool, shrine or Valhalla marker
+	Textures       BlockTextures
+}
+
+// FloorZ returns the height a player stands at in the block
+func (b *GridBlock) FloorZ() int {
+	return b.LowBoxTopZ - b.LowBoxTopMod
+}
+
+// CeilingZ returns the height of the underside of the block's mid box
+func (b *GridBlock) CeilingZ() int {
+	return b.MidBoxBottomZ
+}
+
+// HasSkybox reports whether the block is open to the sky
+func (b *GridBlock) HasSkybox() bool {
+	return b.MidBoxTopZ == gridSkyZ
+}
+
+// IsSolid reports whether the block has no room between its floor and ceiling
+func (b *GridBlock) IsSolid() bool {
+	return b.CeilingZ() <= b.FloorZ()
+}
+
+// Center returns the centre of the block in world units
+func (b *GridBlock) Center() (float64, float64) {
+	return float64(b.Column)*blockSize + blockSize/2, float64(b.Row)*blockSize + blockSize/2
+}
+
+// PoolID returns the earthblood pool the block belongs to
+func (b *GridBlock) PoolID() (int, bool) {
+	if b.Flags >= blockFlagPool && b.Flags < blockFlagShrine {
+		return b.Flags - blockFlagPool, true
+	}
+	return 0, false
+}
+
+// ShrineID returns the shrine the block belongs to
+func (b *GridBlock) ShrineID() (int, bool) {
+	if b.Flags >= blockFlagShrine && b.Flags < blockFlagShrine+3 {
+		return b.Flags - blockFlagShrine, true
+	}
+	return 0, false
+}
+
+// IsValhalla reports whether the block is part of Valhalla
+func (b *GridBlock) IsValhalla() bool {
+	return b.Flags == blockFlagValhalla
+}
+
+// GridObject is a prop placed in the grid, referring to a definition in Objects.dat
+type GridObject struct {
+	ObjectID int
+	X, Y, Z  int
+}
+
+// Grid is the block map of a grid's Grid.dat, 128 by 128 blocks stored row by row, with
+// the props, thins and triggers placed in it. Each arena plays on its own copy so its
+// doors, elevators and broken thins do not change other arenas
+type Grid struct {
+	Blocks     []GridBlock
+	Objects    []GridObject // placed objects, without the empty slots
+	ObjectDefs []ObjectDef  // prop definitions from Objects.dat
+	Thins      []Thin       // panels from Misc.dat
+	Triggers   []TriggerDef // doors, elevators and teleporters from Trigger.dat
+
+	props     map[int][]OrientedBox // solid props, by the index of each block they touch
+	floors    map[int]float64       // floor heights moved by elevators, by block index
+	ceilings  map[int]float64       // ceiling heights moved along with them
+	obstacles []OrientedBox         // door panels in their current positions and standing thins
+	nav       *navCache             // navigation graph, shared by the arena copies
+}
+
+// LoadGridFile reads a grid's Grid.dat file
+func LoadGridFile(path string) (*Grid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	grid, err := ParseGrid(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return grid, nil
+}
+
+// ParseGrid parses Grid.dat data: the blocks, four unused bytes and the object slots.
+// Some shipped grids carry extra bytes after the objects, which are ignored
+func ParseGrid(r io.Reader) (*Grid, error) {
+	data := make([]byte, gridBlockCount*gridBlockBytes+4+gridObjectCount*gridObjectBytes)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("grid data is truncated: %v", err)
+	}
+
+	grid := &Grid{Blocks: make([]GridBlock, gridBlockCount)}
+	for i := range grid.Blocks {
+		field := func(n int) int {
+			return int(int16(binary.LittleEndian.Uint16(data[i*gridBlockBytes+n*2:])))
+		}
+		grid.Blocks[i] = GridBlock{
+			Column:         i % gridWidth,
+			Row:            i / gridWidth,
+			LowBoxTopMod:   field(1),
+			LowBoxTopZ:     field(5),
+			MidBoxBottomZ:  field(6),
+			MidBoxTopZ:     field(7),
+			LowTileID:      field(8),
+			HighBoxBottomZ: field(9),
+			Flags:          field(10),
+			LowTopShape:    BlockShape(field(11)),
+			MidBottomShape: BlockShape(field(12)),
+			Textures: BlockTextures{
+				LowSides: field(2),
+				LowTop:   field(3),
+				MidSides: field(13),
+				MidTop:   field(14),
+				High:     field(4),
+				Ceiling:  field(15),
+			},
+		}
+	}
+
+	objects := data[gridBlockCount*gridBlockBytes+4:]
+	for i := 0; i < gridObjectCount; i++ {
+		field := func(n int) int {
+			return int(int32(binary.LittleEndian.Uint32(objects[i*gridObjectBytes+n*4:])))
+		}
+		if object := (GridObject{ObjectID: field(0), X: field(1), Y: field(2), Z: field(3)}); object.ObjectID != 0 {
+			grid.Objects = append(grid.Objects, object)
+		}
+	}
+	return grid, nil
+}
+
+// Block returns the block at a column and row, or nil outside the grid
+func (g *Grid) Block(column, row int) *GridBlock {
+	if column < 0 || row < 0 || column >= gridWidth || row >= gridWidth {
+		return nil
+	}
+	return &g.Blocks[row*gridWidth+column]
+}
+
+// BlockAt returns the block containing a point in world units, or nil outside the grid
+func (g *Grid) BlockAt(x, y float64) *GridBlock {
+	return g.Block(int(math.Floor(x/blockSize)), int(math.Floor(y/blockSize)))
+}
+
+// FloorHeight returns the floor height at a point, or false outside the grid
+func (g *Grid) FloorHeight(x, y float64) (float64, bool) {
+	block := g.BlockAt(x, y)
+	if block == nil {
+		return 0, false
+	}
+	return g.floorZ(block), true
+}
+
+// CeilingHeight returns the ceiling height at a point, or false outside the grid
+func (g *Grid) CeilingHeight(x, y float64) (float64, bool) {
+	block := g.BlockAt(x, y)
+	if block == nil {
+		return 0, false
+	}
+	return g.ceilingZ(block), true
+}
+
+// floorZ returns a block's floor height, as moved by any elevator in it
+func (g *Grid) floorZ(b *GridBlock) float64 {
+	if z, ok := g.floors[b.Row*gridWidth+b.Column]; ok {
+		return z
+	}
+	return float64(b.FloorZ())
+}
+
+// ceilingZ returns a block's ceiling height, as moved by any elevator in it
+func (g *Grid) ceilingZ(b *GridBlock) float64 {
+	if z, ok := g.ceilings[b.Row*gridWidth+b.Column]; ok {
+		return z
+	}
+	return float64(b.CeilingZ())
+}
+
+// arenaCopy returns a copy of the grid sharing its blocks, props, thins, triggers and
+// navigation graph but with no doors or elevators moved, for one arena to play on
+func (g *Grid) arenaCopy() *Grid {
+	return &Grid{Blocks: g.Blocks, Objects: g.Objects, ObjectDefs: g.ObjectDefs, Thins: g.Thins, Triggers: g.Triggers, props: g.props, nav: g.nav}
+}
+
+// flaggedCenter returns the centre of the blocks matching a flag, or false when none do
+func (g *Grid) flaggedCenter(matches func(*GridBlock) bool) (float64, float64, bool) {
+	var sumX, sumY float64
+	count := 0
+	for i := range g.Blocks {
+		if block := &g.Blocks[i]; matches(block) {
+			x, y := block.Center()
+			sumX, sumY = sumX+x, sumY+y
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0, false
+	}
+	return sumX / float64(count), sumY / float64(count), true
+}
+
+// placeObjectives positions the world's shrines and pools at the centre of their flagged
+// blocks. Flags naming shrines or pools the world does not define are ignored, as in
+// MageServer
+func (w *World) placeObjectives(grid *Grid) {
+	for i := range w.Shrines {
+		shrine := &w.Shrines[i]
+		if x, y, ok := grid.flaggedCenter(func(b *GridBlock) bool {
+			id, ok := b.ShrineID()
+			return ok && id == shrine.ID
+		}); ok {
+			shrine.X, shrine.Y, shrine.HasPosition = x, y, true
+		}
+	}
+	for i := range w.Pools {
+		pool := &w.Pools[i]
+		if x, y, ok := grid.flaggedCenter(func(b *GridBlock) bool {
+			id, ok := b.PoolID()
+			return ok && id == pool.ID
+		}); ok {
+			pool.X, pool.Y, pool.HasPosition = x, y, true
+		}
+	}
+}
+
+// loadGrid loads the Grid.dat for a grid, returning nil when it is unavailable, along
+// with its Objects.dat props, Misc.dat thins and Trigger.dat triggers when those load
+func loadGrid(gridID int) *Grid {
+	folder := fmt.Sprintf("Grid%02d", gridID)
+	path, err := findContentFile(contentDir(), "Grids", folder, "Grid.dat")
+	if err != nil {
+		fmt.Printf("SplatServer: Grid.dat for grid %d not found: %v\n", gridID, err)
+		return nil
+	}
+
+	grid, err := LoadGridFile(path)
+	if err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+		return nil
+	}
+
+	if path, err := findContentFile(contentDir(), "Grids", folder, "Objects.dat"); err != nil {
+		fmt.Printf("SplatServer: Objects.dat for grid %d not found: %v\n", gridID, err)
+	} else if grid.ObjectDefs, err = LoadObjectFile(path); err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+	}
+	grid.placeProps()
+	if path, err := findContentFile(contentDir(), "Grids", folder, "Misc.dat"); err != nil {
+		fmt.Printf("SplatServer: Misc.dat for grid %d not found: %v\n", gridID, err)
+	} else if grid.Thins, err = LoadThinFile(path); err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+	}
+	if path, err := findContentFile(contentDir(), "Grids", folder, "Trigger.dat"); err != nil {
+		fmt.Printf("SplatServer: Trigger.dat for grid %d not found: %v\n", gridID, err)
+	} else if grid.Triggers, err = LoadTriggerFile(path); err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+	}
+	grid.nav = &navCache{}
+	return grid
+}
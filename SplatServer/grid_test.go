@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestLoadShippedGrids(t *testing.T) {
+	for gridID := 0; gridID < 10; gridID++ {
+		path, err := findContentFile(contentDir(), "Grids", fmt.Sprintf("Grid%02d", gridID), "Grid.dat")
+		if err != nil {
+			t.Fatalf("Grid.dat for grid %d not found: %v", gridID, err)
+		}
+		grid, err := LoadGridFile(path)
+		if err != nil {
+			t.Fatalf("LoadGridFile failed: %v", err)
+		}
+		if len(grid.Blocks) != gridBlockCount {
+			t.Fatalf("Grid %d: expected %d blocks, got %d", gridID, gridBlockCount, len(grid.Blocks))
+		}
+
+		valhalla := 0
+		for i := range grid.Blocks {
+			if grid.Blocks[i].IsValhalla() {
+				valhalla++
+			}
+		}
+		if valhalla == 0 {
+			t.Errorf("Grid %d: expected Valhalla blocks", gridID)
+		}
+
+		// Every start and raise point must be somewhere a player can stand. Splat Lake's
+		// Order spawns are walled in, but its Order shrine has no power so Order never plays
+		world := loadGridWorld(gridID)
+		if world == nil {
+			t.Fatalf("Grid %d: World.dat failed to load", gridID)
+		}
+		unpowered := make(map[Team]bool)
+		for _, shrine := range world.Shrines {
+			unpowered[shrine.Team] = shrine.Power == 0
+		}
+		for team, spawn := range world.Spawns {
+			if unpowered[team] {
+				continue
+			}
+			for _, point := range []SpawnPoint{spawn.Start, spawn.Raise} {
+				if block := grid.BlockAt(point.X, point.Y); block == nil || block.IsSolid() {
+					t.Errorf("Grid %d: %s spawn (%.0f, %.0f) is in a solid block %+v", gridID, team, point.X, point.Y, block)
+				}
+			}
+		}
+	}
+}
+
+func TestGridBlocksAndHeights(t *testing.T) {
+	grid := loadGrid(9)
+	if grid == nil {
+		t.Fatal("Grid.dat for grid 9 failed to load")
+	}
+
+	// The Chaos start at block (77, 35) has its floor at 80 and ceiling at 500
+	block := grid.BlockAt(77*64+10, 35*64+60)
+	if block == nil || block.Column != 77 || block.Row != 35 || block.FloorZ() != 80 || block.CeilingZ() != 500 || block.IsSolid() {
+		t.Fatalf("Unexpected start block %+v", block)
+	}
+	if floor, ok := grid.FloorHeight(77*64, 35*64); !ok || floor != 80 {
+		t.Errorf("Expected a floor height of 80, got %.0f", floor)
+	}
+	if _, ok := grid.CeilingHeight(-1, 0); ok {
+		t.Error("Expected no ceiling outside the grid")
+	}
+	if grid.BlockAt(128*64, 0) != nil {
+		t.Error("Expected no block outside the grid")
+	}
+	if len(grid.Objects) != 56 {
+		t.Errorf("Expected 56 placed objects, got %d", len(grid.Objects))
+	}
+
+	// The Chaos shrine, shrine 1, covers blocks (74..75, 35..36)
+	if id, ok := grid.Block(75, 36).ShrineID(); !ok || id != 1 {
+		t.Errorf("Expected shrine 1 at block (75, 36), got %d", id)
+	}
+	world := loadGridWorld(9)
+	world.placeObjectives(grid)
+	if chaos := world.Shrines[1]; !chaos.HasPosition || chaos.X != 75*64 || chaos.Y != 36*64 {
+		t.Errorf("Unexpected Chaos shrine position %+v", chaos)
+	}
+	for _, pool := range world.Pools {
+		if !pool.HasPosition {
+			t.Errorf("Expected pool %d to be placed", pool.ID)
+		}
+	}
+}
+
+func TestParseGridTruncated(t *testing.T) {
+	if _, err := ParseGrid(bytes.NewReader(make([]byte, gridBlockBytes*10))); err == nil {
+		t.Error("Expected truncated grid data to fail")
+	}
+}
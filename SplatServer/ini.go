@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IniFile holds the sections of a Magestorm style .dat file.
+// Section and key names are case-insensitive and the first occurrence wins,
+// matching GetPrivateProfileString.
+type IniFile struct {
+	Sections map[string]map[string]string
+}
+
+// LoadIniFile reads and parses an INI file from disk
+func LoadIniFile(path string) (*IniFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ini, err := ParseIni(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ini, nil
+}
+
+// ParseIni parses INI data from a reader
+func ParseIni(r io.Reader) (*IniFile, error) {
+	ini := &IniFile{Sections: make(map[string]map[string]string)}
+
+	var section map[string]string
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNum)
+			}
+			name := strings.ToLower(strings.TrimSpace(line[1:end]))
+			if _, exists := ini.Sections[name]; !exists {
+				ini.Sections[name] = make(map[string]string)
+			}
+			section = ini.Sections[name]
+			continue
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 || section == nil {
+			// Windows ignores stray lines, so do we
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:eq]))
+		if _, exists := section[key]; !exists {
+			section[key] = strings.TrimSpace(line[eq+1:])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ini, nil
+}
+
+// HasSection reports whether the section exists
+func (f *IniFile) HasSection(section string) bool {
+	_, exists := f.Sections[strings.ToLower(section)]
+	return exists
+}
+
+// Lookup returns the raw value of a key and whether it was present
+func (f *IniFile) Lookup(section, key string) (string, bool) {
+	s, exists := f.Sections[strings.ToLower(section)]
+	if !exists {
+		return "", false
+	}
+	v, exists := s[strings.ToLower(key)]
+	return v, exists
+}
+
+// String returns a string value or def when missing
+func (f *IniFile) String(section, key, def string) string {
+	if v, ok := f.Lookup(section, key); ok {
+		return v
+	}
+	return def
+}
+
+// Int returns an integer value or def when missing.
+// Like GetPrivateProfileInt only the leading digits are used.
+func (f *IniFile) Int(section, key string, def int) int {
+	v, ok := f.Lookup(section, key)
+	if !ok {
+		return def
+	}
+	end := 0
+	if end < len(v) && (v[end] == '-' || v[end] == '+') {
+		end++
+	}
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(v[:end])
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Float returns a floating point value or def when missing
+func (f *IniFile) Float(section, key string, def float64) float64 {
+	v, ok := f.Lookup(section, key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return float64(f.Int(section, key, int(def)))
+	}
+	return n
+}
+
+// Bool returns a boolean value or def when missing. Like MageServer's
+// GetPrivateProfileBoolean it accepts true and false or a number, nonzero being true
+func (f *IniFile) Bool(section, key string, def bool) bool {
+	v, ok := f.Lookup(section, key)
+	if !ok {
+		return def
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return f.Int(section, key, 0) != 0
+}
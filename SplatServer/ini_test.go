@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIni(t *testing.T) {
+	data := `; header comment
+[ArenaDefs]
+numarenas=10 ; inline comment
+
+[arena01]
+name = Wizard's Keep
+grid=grid04
+timelimit=3600 seconds
+name=Duplicate
+expbonus=1.5
+`
+	ini, err := ParseIni(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseIni failed: %v", err)
+	}
+
+	if !ini.HasSection("arenadefs") {
+		t.Error("Expected section lookup to be case-insensitive")
+	}
+	if n := ini.Int("ARENADEFS", "NumArenas", 0); n != 10 {
+		t.Errorf("Expected numarenas 10, got %d", n)
+	}
+	if name := ini.String("arena01", "name", ""); name != "Wizard's Keep" {
+		t.Errorf("Expected first name to win, got '%s'", name)
+	}
+	if limit := ini.Int("arena01", "timelimit", 0); limit != 3600 {
+		t.Errorf("Expected leading integer 3600, got %d", limit)
+	}
+	if bonus := ini.Float("arena01", "expbonus", 0); bonus != 1.5 {
+		t.Errorf("Expected expbonus 1.5, got %f", bonus)
+	}
+	if v := ini.Int("arena01", "missing", 7); v != 7 {
+		t.Errorf("Expected default 7, got %d", v)
+	}
+}
+
+func TestParseIniUnterminatedSection(t *testing.T) {
+	if _, err := ParseIni(strings.NewReader("[broken\nkey=value\n")); err == nil {
+		t.Error("Expected error for unterminated section header")
+	}
+}
+
+func TestFindContentFile(t *testing.T) {
+	// Grid08 ships Sounds.DAT in upper case
+	path, err := findContentFile(contentDir(), "grids", "grid08", "sounds.dat")
+	if err != nil {
+		t.Fatalf("findContentFile failed: %v", err)
+	}
+	if !strings.HasSuffix(path, "Sounds.DAT") {
+		t.Errorf("Expected on-disk casing to be returned, got %s", path)
+	}
+
+	if _, err := findContentFile(contentDir(), "NoSuchFile.dat"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestIniBool(t *testing.T) {
+	ini, err := ParseIni(strings.NewReader("[trigger01]\nvalhalla=TRUE\nenabled=0\nlocked=2\nopen=false\n"))
+	if err != nil {
+		t.Fatalf("ParseIni failed: %v", err)
+	}
+	if !ini.Bool("trigger01", "valhalla", false) || ini.Bool("trigger01", "enabled", true) || !ini.Bool("trigger01", "locked", false) {
+		t.Error("Unexpected boolean values")
+	}
+	if ini.Bool("trigger01", "open", true) || !ini.Bool("trigger01", "missing", true) {
+		t.Error("Expected false and the default for a missing key")
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LeaderboardStat is the value characters are ranked by
+type LeaderboardStat int
+
+const (
+	LeaderboardLevel LeaderboardStat = iota
+	LeaderboardExperience
+	LeaderboardKills
+	LeaderboardKillDeath
+	LeaderboardHealing
+)
+
+// LeaderboardWindow limits a leaderboard to a period of play
+type LeaderboardWindow int
+
+const (
+	LeaderboardAllTime LeaderboardWindow = iota
+	LeaderboardWeekly                    // since Monday 00:00 UTC
+)
+
+const (
+	leaderboardAllClasses = -1
+	maxLeaderboardSize    = 1000 // characters ranked per leaderboard
+	leaderboardPageSize   = 10
+	defaultLeaderboardTTL = time.Minute
+)
+
+// leaderboardStatValues maps each stat to its value for the all-time and weekly sources.
+// The all-time source "st" is player_statistics; the weekly one sums match_statistics.
+var leaderboardStatValues = map[LeaderboardStat][2]string{
+	LeaderboardLevel:      {"COALESCE(pr.level, 1)", "COALESCE(pr.level, 1)"},
+	LeaderboardExperience: {"COALESCE(pr.experience, 0)", "st.experience"},
+	LeaderboardKills:      {"st.kills", "st.kills"},
+	LeaderboardKillDeath:  {"st.kills * 1.0 / CASE WHEN st.deaths < 1 THEN 1 ELSE st.deaths END", "st.kills * 1.0 / CASE WHEN st.deaths < 1 THEN 1 ELSE st.deaths END"},
+	LeaderboardHealing:    {"st.healing_done", "st.healing_done"},
+}
+
+// LeaderboardKey identifies one ranking
+type LeaderboardKey struct {
+	Stat   LeaderboardStat
+	Class  int // a PlayerClass, or leaderboardAllClasses
+	Window LeaderboardWindow
+}
+
+// LeaderboardEntry is one ranked character
+type LeaderboardEntry struct {
+	Rank     int
+	PlayerID int
+	Name     string
+	Class    PlayerClass
+	Level    int
+	Value    float64
+}
+
+// LeaderboardPage is one page of a ranking
+type LeaderboardPage struct {
+	Key        LeaderboardKey
+	Page       int
+	TotalPages int
+	Entries    []LeaderboardEntry
+}
+
+// weekStart returns Monday 00:00 UTC of the week containing t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// QueryLeaderboard ranks characters from the database, best first
+func QueryLeaderboard(key LeaderboardKey, now time.Time) ([]LeaderboardEntry, error) {
+	values, ok := leaderboardStatValues[key.Stat]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard stat %d", key.Stat)
+	}
+
+	var args []interface{}
+	var value, source string
+	switch key.Window {
+	case LeaderboardAllTime:
+		value = values[0]
+		if key.Stat != LeaderboardLevel && key.Stat != LeaderboardExperience {
+			source = "JOIN player_statistics st ON st.player_id = p.id"
+		}
+	case LeaderboardWeekly:
+		value = values[1]
+		source = `JOIN (
+			SELECT player_id, SUM(kills) AS kills, SUM(deaths) AS deaths,
+				SUM(healing_done) AS healing_done, SUM(experience) AS experience
+			FROM match_statistics WHERE played_at >= ? GROUP BY player_id
+		) st ON st.player_id = p.id`
+		args = append(args, weekStart(now).Format("2006-01-02 15:04:05"))
+	default:
+		return nil, fmt.Errorf("unknown leaderboard window %d", key.Window)
+	}
+
+	where := ""
+	if key.Class != leaderboardAllClasses {
+		where = "WHERE COALESCE(sb.class, 0) = ?"
+		args = append(args, key.Class)
+	}
+	args = append(args, maxLeaderboardSize)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, COALESCE(sb.class, 0), COALESCE(pr.level, 1), %s AS rank_value
+		FROM players p
+		LEFT JOIN spellbooks sb ON sb.player_id = p.id
+		LEFT JOIN progression pr ON pr.player_id = p.id
+		%s
+		%s
+		ORDER BY rank_value DESC, COALESCE(pr.experience, 0) DESC, p.id
+		LIMIT ?`, value, source, where)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var class int
+		var value sql.NullFloat64
+		if err := rows.Scan(&entry.PlayerID, &entry.Name, &class, &entry.Level, &value); err != nil {
+			return nil, err
+		}
+		entry.Rank = len(entries) + 1
+		entry.Class = PlayerClass(class)
+		entry.Value = value.Float64
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// leaderboardCache holds one ranking; ready is closed once the first load finishes
+type leaderboardCache struct {
+	entries    []LeaderboardEntry
+	err        error
+	fetchedAt  time.Time
+	refreshing bool
+	ready      chan struct{}
+}
+
+// Leaderboards serves rankings from a cache. Stale rankings are served while they
+// refresh in the background, so only the first request for a ranking waits on the database.
+type Leaderboards struct {
+	TTL   time.Duration
+	Query func(key LeaderboardKey, now time.Time) ([]LeaderboardEntry, error)
+	cache map[LeaderboardKey]*leaderboardCache
+	mu    sync.Mutex
+}
+
+// NewLeaderboards creates a leaderboard service backed by the database
+func NewLeaderboards() *Leaderboards {
+	return &Leaderboards{
+		TTL:   defaultLeaderboardTTL,
+		Query: QueryLeaderboard,
+		cache: make(map[LeaderboardKey]*leaderboardCache),
+	}
+}
+
+// Ranking returns the full cached ranking for a key
+func (l *Leaderboards) Ranking(key LeaderboardKey) ([]LeaderboardEntry, error) {
+	l.mu.Lock()
+	c, exists := l.cache[key]
+	if !exists {
+		c = &leaderboardCache{refreshing: true, ready: make(chan struct{})}
+		l.cache[key] = c
+		l.mu.Unlock()
+		l.refresh(key, c)
+	} else {
+		if !c.refreshing && time.Since(c.fetchedAt) > l.TTL {
+			c.refreshing = true
+			go l.refresh(key, c)
+		}
+		l.mu.Unlock()
+	}
+
+	<-c.ready
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return c.entries, c.err
+}
+
+// refresh reloads a ranking, keeping the previous entries if the query fails
+func (l *Leaderboards) refresh(key LeaderboardKey, c *leaderboardCache) {
+	entries, err := l.Query(key, time.Now())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil || c.entries == nil {
+		c.entries, c.err = entries, err
+	}
+	if err != nil {
+		fmt.Printf("Failed to load leaderboard %+v: %v\n", key, err)
+		if c.entries == nil {
+			delete(l.cache, key) // retry on the next request rather than caching the failure
+		}
+	}
+	c.fetchedAt = time.Now()
+	c.refreshing = false
+	select {
+	case <-c.ready:
+	default:
+		close(c.ready)
+	}
+}
+
+// Page returns one page of a ranking, counting pages from 0
+func (l *Leaderboards) Page(key LeaderboardKey, page int) (LeaderboardPage, error) {
+	entries, err := l.Ranking(key)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	result := LeaderboardPage{
+		Key:        key,
+		Page:       page,
+		TotalPages: (len(entries) + leaderboardPageSize - 1) / leaderboardPageSize,
+	}
+	start := page * leaderboardPageSize
+	if page < 0 || start >= len(entries) {
+		return result, nil
+	}
+	result.Entries = entries[start:min(start+leaderboardPageSize, len(entries))]
+	return result, nil
+}
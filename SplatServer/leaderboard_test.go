@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	// 2026-10-18 is a Sunday
+	got := weekStart(time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC))
+	if !got.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Monday 2026-10-12, got %v", got)
+	}
+}
+
+func TestQueryLeaderboard(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	characters := []struct {
+		id, level, exp int
+		class          PlayerClass
+		sheet          StatisticSheet
+	}{
+		{1, 5, 30000, ClassMagician, StatisticSheet{Kills: 10, Deaths: 5, HealingDone: 0, Experience: 900}},
+		{2, 9, 120000, ClassCleric, StatisticSheet{Kills: 4, Deaths: 0, HealingDone: 800, Experience: 300}},
+		{3, 7, 80000, ClassMagician, StatisticSheet{Kills: 12, Deaths: 12, HealingDone: 50, Experience: 500}},
+	}
+	for _, c := range characters {
+		player := &Player{ID: c.id, Name: fmt.Sprintf("Mage%d", c.id), Health: 100, Level: c.level, Experience: c.exp}
+		if err := SavePlayer(player); err != nil {
+			t.Fatalf("SavePlayer failed: %v", err)
+		}
+		if err := SaveSpellbook(c.id, NewSpellbook(c.class)); err != nil {
+			t.Fatalf("SaveSpellbook failed: %v", err)
+		}
+		RecordMatchStatistics(c.id, 1, c.sheet)
+	}
+	// An old match that falls outside the weekly window
+	if _, err := db.Exec("UPDATE match_statistics SET played_at = '2000-01-01 00:00:00' WHERE player_id = 3"); err != nil {
+		t.Fatalf("Failed to age match: %v", err)
+	}
+
+	order := func(key LeaderboardKey) []int {
+		entries, err := QueryLeaderboard(key, time.Now())
+		if err != nil {
+			t.Fatalf("QueryLeaderboard(%+v) failed: %v", key, err)
+		}
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			if entry.Rank != i+1 {
+				t.Errorf("Expected rank %d, got %d", i+1, entry.Rank)
+			}
+			ids[i] = entry.PlayerID
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		key      LeaderboardKey
+		expected []int
+	}{
+		{LeaderboardKey{LeaderboardLevel, leaderboardAllClasses, LeaderboardAllTime}, []int{2, 3, 1}},
+		{LeaderboardKey{LeaderboardKills, leaderboardAllClasses, LeaderboardAllTime}, []int{3, 1, 2}},
+		{LeaderboardKey{LeaderboardKillDeath, leaderboardAllClasses, LeaderboardAllTime}, []int{2, 1, 3}},
+		{LeaderboardKey{LeaderboardHealing, leaderboardAllClasses, LeaderboardAllTime}, []int{2, 3, 1}},
+		{LeaderboardKey{LeaderboardLevel, int(ClassMagician), LeaderboardAllTime}, []int{3, 1}},
+		{LeaderboardKey{LeaderboardKills, leaderboardAllClasses, LeaderboardWeekly}, []int{1, 2}},
+		{LeaderboardKey{LeaderboardExperience, leaderboardAllClasses, LeaderboardWeekly}, []int{1, 2}},
+	} {
+		got := order(tc.key)
+		if fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+			t.Errorf("%+v: expected %v, got %v", tc.key, tc.expected, got)
+		}
+	}
+}
+
+func TestLeaderboardCachingAndPaging(t *testing.T) {
+	var queries int32
+	l := NewLeaderboards()
+	l.Query = func(key LeaderboardKey, now time.Time) ([]LeaderboardEntry, error) {
+		atomic.AddInt32(&queries, 1)
+		entries := make([]LeaderboardEntry, 25)
+		for i := range entries {
+			entries[i] = LeaderboardEntry{Rank: i + 1, PlayerID: i + 1}
+		}
+		return entries, nil
+	}
+
+	key := LeaderboardKey{LeaderboardKills, leaderboardAllClasses, LeaderboardAllTime}
+	page, err := l.Page(key, 2)
+	if err != nil {
+		t.Fatalf("Page failed: %v", err)
+	}
+	if page.TotalPages != 3 || len(page.Entries) != 5 || page.Entries[0].Rank != 21 {
+		t.Errorf("Unexpected last page %+v", page)
+	}
+	if page, _ := l.Page(key, 7); len(page.Entries) != 0 {
+		t.Error("Expected no entries past the last page")
+	}
+	if atomic.LoadInt32(&queries) != 1 {
+		t.Errorf("Expected one query while cached, got %d", queries)
+	}
+
+	// A stale ranking is served immediately and refreshed in the background
+	l.TTL = 0
+	time.Sleep(time.Millisecond)
+	if _, err := l.Page(key, 0); err != nil {
+		t.Fatalf("Page failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&queries) != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&queries) != 2 {
+		t.Errorf("Expected a background refresh, got %d queries", queries)
+	}
+}
+
+func TestLeaderboardMessage(t *testing.T) {
+	gs := NewGameState()
+	gs.Leaderboards.Query = func(key LeaderboardKey, now time.Time) ([]LeaderboardEntry, error) {
+		return []LeaderboardEntry{{Rank: 1, PlayerID: 4, Name: "Top", Class: ClassCleric, Level: 12, Value: 3.5}}, nil
+	}
+	player := addTestCaster(gs, 1)
+
+	// stat K/D, cleric, weekly, page 0
+	handleLeaderboard(&Message{Type: MsgLeaderboard, Data: []byte{3, 3, 1, 0, 0}}, player, gs)
+
+	packet, err := DeserializePacket(bytes.NewReader(player.Conn.(*captureConn).written.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read leaderboard packet: %v", err)
+	}
+	if packet.Type != PacketLeaderboard {
+		t.Fatalf("Expected leaderboard packet, got type %d", packet.Type)
+	}
+	// 8 byte header, then rank, id, class, level, value and "Top\0"
+	if len(packet.Data) != 8+2+4+1+1+8+4 || packet.Data[1] != 3 || packet.Data[2] != 1 || string(packet.Data[24:27]) != "Top" {
+		t.Errorf("Unexpected leaderboard packet data %v", packet.Data)
+	}
+}
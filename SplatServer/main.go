@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"os"
 	"sync/atomic"
 	"time"
 )
@@ -19,6 +20,10 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdout))
+	}
+
 	fmt.Println("SplatServer: Starting game server on port", SERVER_PORT)
 
 	// Initialize database
@@ -29,13 +34,18 @@ func main() {
 
 	// Initialize spell system
 	InitializeSpellSystem()
+	loadSpellContent(gameState)
 
-	// Initialize basic arenas
+	// Initialize arenas from Arenas.dat
 	initializeArenas(gameState)
+	loadArenaWorlds(gameState)
 
 	// Start the game loop in a goroutine
 	go GameLoop(gameState)
 
+	// Run server-side bots beside the game loop
+	go RunBots(gameState)
+
 	// Start UDP listener for real-time updates
 	go startUDPListener()
 
@@ -56,6 +66,23 @@ func main() {
 	}
 }
 
+// runCommand runs a command-line subcommand instead of the server and returns the exit
+// status, 2 for a command it does not know
+func runCommand(args []string, out io.Writer) int {
+	switch {
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "content" && args[1] == "check":
+		dir := contentDir()
+		if len(args) == 3 {
+			dir = args[2]
+		}
+		return runContentCheck(dir, out)
+	case len(args) == 4 && args[0] == "content" && args[1] == "map":
+		return runContentMap(args[2], args[3], out)
+	}
+	fmt.Fprintln(out, "Usage: splatserver [content check [content dir] | content map <grid> <file.png>]")
+	return 2
+}
+
 func startUDPListener() {
 	addr, err := net.ResolveUDPAddr("udp", ":"+SERVER_PORT)
 	if err != nil {
@@ -92,12 +119,17 @@ func handleUDPPacket(conn *net.UDPConn, addr *net.UDPAddr, data []byte) {
 }
 
 func initializeArenas(gs *GameState) {
-	// Create some basic arenas
-	gs.ArenaManager.CreateArena(1, "Chaos Arena", 8, 1)
-	gs.ArenaManager.CreateArena(2, "Balance Arena", 8, 2)
-	gs.ArenaManager.CreateArena(3, "Order Arena", 8, 3)
+	// Create the enabled arenas from Arenas.dat
+	count := 0
+	for _, template := range loadArenaTemplates() {
+		if !template.Enabled {
+			continue
+		}
+		gs.ArenaManager.CreateArenaFromTemplate(template)
+		count++
+	}
 
-	fmt.Println("SplatServer: Initialized 3 basic arenas")
+	fmt.Printf("SplatServer: Initialized %d arenas\n", count)
 }
 
 func handleConnection(conn net.Conn) {
@@ -111,6 +143,7 @@ func handleConnection(conn net.Conn) {
 		X:        0,
 		Y:        0,
 		Health:   100,
+		Level:    1,
 		Conn:     conn,
 		LastSeen: time.Now(),
 	}
@@ -126,6 +159,13 @@ func handleConnection(conn net.Conn) {
 			if err != io.EOF {
 				fmt.Printf("Error parsing message from %d: %v\n", playerID, err)
 			}
+			// Save before removing, copying the player under the lock the tick updates it with
+			gameState.mu.RLock()
+			saved := *player
+			gameState.mu.RUnlock()
+			if err := SavePlayer(&saved); err != nil {
+				fmt.Printf("Failed to save player %d on disconnect: %v\n", playerID, err)
+			}
 			gameState.RemovePlayer(playerID)
 			return
 		}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	minMatchPlayers    = 2
+	defaultCountdown   = 10 * time.Second // warm-up between enough players joining and the match starting
+	defaultTimeLimit   = time.Hour        // Arenas.dat's usual timelimit=3600
+	defaultResetDelay  = 15 * time.Second // time the results stay up before the arena reopens
+	oneMinuteWarning   = time.Minute
+	matchEndedByLimit  = "time limit"
+	matchEndedManually = "ended"
+)
+
+// MatchResult is the outcome of one match, as saved to the matches table
+type MatchResult struct {
+	ArenaID   int
+	ArenaName string
+	Mode      ArenaMode
+	Winner    Team // TeamNone for a draw or a free-for-all
+	WinnerID  int  // the winning player in a free-for-all, 0 otherwise
+	Reason    string
+	Players   int
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// Describe returns the result as an announcement
+func (r MatchResult) Describe() string {
+	switch {
+	case r.WinnerID != 0:
+		return fmt.Sprintf("Player %d has won the match (%s)!", r.WinnerID, r.Reason)
+	case r.Winner != TeamNone:
+		return fmt.Sprintf("%s has won the match (%s)!", r.Winner, r.Reason)
+	}
+	return fmt.Sprintf("The match is a draw (%s).", r.Reason)
+}
+
+// countdown returns the warm-up before a match starts
+func (a *Arena) countdown() time.Duration {
+	if a.Countdown <= 0 {
+		return defaultCountdown
+	}
+	return a.Countdown
+}
+
+// resetDelay returns how long an ended arena waits before reopening
+func (a *Arena) resetDelay() time.Duration {
+	if a.ResetDelay <= 0 {
+		return defaultResetDelay
+	}
+	return a.ResetDelay
+}
+
+// updateLifecycleLocked moves the arena through its states: waiting for players, the
+// warm-up countdown, the one minute warning and reopening after the results. Matches
+// are ended by UpdateArenas so their results can be saved. The caller must hold a.mu
+func (a *Arena) updateLifecycleLocked(now time.Time) {
+	switch a.State {
+	case ArenaStateWaiting:
+		if a.readyToStartLocked() {
+			a.State = ArenaStateCountdown
+			a.CountdownEnds = now.Add(a.countdown())
+			a.broadcastLocked(BuildChatPacket(fmt.Sprintf("The match begins in %d seconds!", int(a.countdown().Seconds()))))
+			fmt.Printf("Arena %s counting down with %d players\n", a.Name, len(a.Players))
+		}
+
+	case ArenaStateCountdown:
+		if !a.readyToStartLocked() {
+			a.State = ArenaStateWaiting
+			a.broadcastLocked(BuildChatPacket("Not enough players, the countdown has stopped."))
+			return
+		}
+		if !now.Before(a.CountdownEnds) {
+			a.startLocked()
+			a.broadcastLocked(BuildChatPacket("The match has begun!"))
+			fmt.Printf("Arena %s started with %d players\n", a.Name, len(a.Players))
+		}
+
+	case ArenaStateActive:
+		if a.TimeLimit > 0 && !a.warnedOneMinute && now.After(a.StartTime.Add(a.TimeLimit-oneMinuteWarning)) {
+			a.warnedOneMinute = true
+			a.broadcastLocked(BuildChatPacket("One minute remaining!"))
+		}
+
+	case ArenaStateEnded:
+		if !now.Before(a.EndTime.Add(a.resetDelay())) {
+			a.resetLocked()
+		}
+	}
+}
+
+// readyToStartLocked reports whether the arena has enough players for a match: two, and
+// in team modes players on at least two teams. The caller must hold a.mu
+func (a *Arena) readyToStartLocked() bool {
+	if len(a.Players) < minMatchPlayers {
+		return false
+	}
+	return a.Ruleset.Rules.Has(ArenaRuleNoTeams) || len(a.teamsPresentLocked()) >= 2
+}
+
+// teamsPresentLocked returns the teams with players in the arena; the caller must hold a.mu
+func (a *Arena) teamsPresentLocked() map[Team]bool {
+	teams := make(map[Team]bool)
+	for _, player := range a.Players {
+		if player.Team != TeamNone {
+			teams[player.Team] = true
+		}
+	}
+	return teams
+}
+
+// CheckMatchEnd reports whether an active match is over, recording its result for
+// EndArena. Matches end when a team reaches the capture or score limit, when one team's
+// shrine is the last one standing, when only one team or player is left, when everyone
+// has left, or when the time limit runs out
+func (a *Arena) CheckMatchEnd(now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.State != ArenaStateActive {
+		return false
+	}
+	result, over := a.matchOutcomeLocked(now)
+	if over {
+		a.result = &result
+	}
+	return over
+}
+
+// matchOutcomeLocked applies the win checks for the arena's mode; the caller must hold a.mu
+func (a *Arena) matchOutcomeLocked(now time.Time) (MatchResult, bool) {
+	result := a.newResultLocked()
+	noTeams := a.Ruleset.Rules.Has(ArenaRuleNoTeams)
+
+	if len(a.Players) == 0 {
+		result.Reason = "abandoned"
+		return result, true
+	}
+
+	if team, won := a.captureWinnerLocked(); won {
+		result.Winner, result.Reason = team, "capture limit"
+		return result, true
+	}
+
+	if a.ScoreLimit > 0 {
+		if team, playerID, best := a.leaderLocked(); best >= a.ScoreLimit && (team != TeamNone || playerID != 0) {
+			result.Winner, result.WinnerID, result.Reason = team, playerID, "score limit"
+			return result, true
+		}
+	}
+
+	if !noTeams && !a.Ruleset.Rules.Has(ArenaRuleCaptureTheFlag) {
+		if team, won := a.lastShrineLocked(); won {
+			result.Winner, result.Reason = team, "shrines destroyed"
+			return result, true
+		}
+	}
+
+	if team, playerID, won := a.lastStandingLocked(); won {
+		result.Winner, result.WinnerID, result.Reason = team, playerID, "last team standing"
+		if noTeams {
+			result.Reason = "last player standing"
+		}
+		return result, true
+	}
+
+	if a.TimeLimit > 0 && !now.Before(a.StartTime.Add(a.TimeLimit)) {
+		return a.standingsResultLocked(matchEndedByLimit), true
+	}
+	return result, false
+}
+
+// newResultLocked starts a result for the current match; the caller must hold a.mu
+func (a *Arena) newResultLocked() MatchResult {
+	return MatchResult{
+		ArenaID:   a.ID,
+		ArenaName: a.Name,
+		Mode:      a.Ruleset.Mode,
+		Players:   len(a.Players),
+		StartedAt: a.StartTime,
+	}
+}
+
+// standingsResultLocked decides a match that ran out of time or was ended early by the
+// standings: most captures in capture the flag, most guild points under guild rules,
+// otherwise the highest score. The caller must hold a.mu
+func (a *Arena) standingsResultLocked(reason string) MatchResult {
+	result := a.newResultLocked()
+	result.Reason = reason
+
+	switch {
+	case a.Ruleset.Rules.Has(ArenaRuleCaptureTheFlag):
+		captures := make(map[Team]float64)
+		for team, count := range a.Captures {
+			captures[team] = float64(count)
+		}
+		result.Winner = bestTeam(captures)
+	case a.Ruleset.Rules.Has(ArenaRuleGuildRules):
+		points := make(map[Team]float64)
+		for team, shrine := range a.Shrines {
+			points[team] = shrine.GuildPoints
+		}
+		result.Winner = bestTeam(points)
+	default:
+		team, playerID, _ := a.leaderLocked()
+		result.Winner, result.WinnerID = team, playerID
+	}
+	return result
+}
+
+// bestTeam returns the team with the single highest value, or TeamNone on a tie
+func bestTeam(values map[Team]float64) Team {
+	best, tied := TeamNone, false
+	for team, value := range values {
+		switch {
+		case best == TeamNone || value > values[best]:
+			best, tied = team, false
+		case value == values[best]:
+			tied = true
+		}
+	}
+	if tied || (best != TeamNone && values[best] == 0) {
+		return TeamNone
+	}
+	return best
+}
+
+// leaderLocked returns the leading team by total score, or the leading player without
+// teams, along with the leading score; ties lead nobody. The caller must hold a.mu
+func (a *Arena) leaderLocked() (Team, int, int) {
+	if a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		scoreboard := a.scoreboardLocked()
+		if len(scoreboard) == 0 {
+			return TeamNone, 0, 0
+		}
+		if len(scoreboard) > 1 && scoreboard[1].Score == scoreboard[0].Score {
+			return TeamNone, 0, scoreboard[0].Score
+		}
+		return TeamNone, scoreboard[0].PlayerID, scoreboard[0].Score
+	}
+
+	scores := make(map[Team]float64)
+	for _, player := range a.Players {
+		if player.Team != TeamNone {
+			scores[player.Team] += float64(player.Score)
+		}
+	}
+	team := bestTeam(scores)
+	return team, 0, int(scores[team])
+}
+
+// lastShrineLocked returns the team whose shrine is the only one left alive among two or
+// more in play; the caller must hold a.mu
+func (a *Arena) lastShrineLocked() (Team, bool) {
+	inPlay, alive := 0, []Team{}
+	for team, shrine := range a.Shrines {
+		if shrine.Disabled || shrine.IsIndestructible() {
+			continue
+		}
+		inPlay++
+		if !shrine.IsDead() {
+			alive = append(alive, team)
+		}
+	}
+	if inPlay >= 2 && len(alive) == 1 {
+		return alive[0], true
+	}
+	return TeamNone, false
+}
+
+// lastStandingLocked returns the team, or without teams the player, left alone in the
+// arena after the others have left; the caller must hold a.mu
+func (a *Arena) lastStandingLocked() (Team, int, bool) {
+	if a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		if len(a.Players) != 1 {
+			return TeamNone, 0, false
+		}
+		for _, player := range a.Players {
+			return TeamNone, player.PlayerID, true
+		}
+	}
+
+	teams := a.teamsPresentLocked()
+	if len(teams) != 1 {
+		return TeamNone, 0, false
+	}
+	for team := range a.startingTeams {
+		if teams[team] {
+			continue
+		}
+		// A team that started the match has left, leaving the other one standing
+		for standing := range teams {
+			return standing, 0, true
+		}
+	}
+	return TeamNone, 0, false
+}
+
+// takeResult returns the result of a match that has just ended, once
+func (a *Arena) takeResult() (MatchResult, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.result == nil || a.resultSaved {
+		return MatchResult{}, false
+	}
+	a.resultSaved = true
+	return *a.result, true
+}
+
+// LastResult returns the result of the arena's most recent match
+func (a *Arena) LastResult() (MatchResult, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.result == nil {
+		return MatchResult{}, false
+	}
+	return *a.result, true
+}
+
+// resetLocked reopens an ended arena for the next match. Players are returned to the
+// lobby when ReturnPlayers is set; otherwise they stay and are restored at their start
+// point with a fresh score. The caller must hold a.mu
+func (a *Arena) resetLocked() {
+	a.State = ArenaStateWaiting
+	a.result, a.resultSaved = nil, false
+	a.warnedOneMinute = false
+	a.Walls = make(map[int64]*Wall)
+	a.Runes = make(map[int64]*Rune)
+	a.index = nil
+	a.Orbs, a.Captures = nil, nil
+	a.Shrines, a.Pools = nil, nil
+	a.resetGridLocked()
+
+	for id, player := range a.Players {
+		if a.ReturnPlayers {
+			if player.Conn != nil {
+				player.Conn.Write(BuildChatPacket(fmt.Sprintf("%s has closed. You have returned to the lobby.", a.Name)).Serialize())
+			}
+			delete(a.Players, id)
+			continue
+		}
+		start, _ := a.spawnLocked(player.Team)
+		a.movePlayerLocked(player, start.Start.X, start.Start.Y)
+		player.Angle = start.Start.Angle
+		player.Dead = false
+		player.Health = player.maxHealth()
+		player.Score = 0
+		player.Statistics = StatisticSheet{}
+		player.statisticsRecorded = false
+	}
+
+	a.broadcastLocked(BuildChatPacket(fmt.Sprintf("%s is open for the next match.", a.Name)))
+}
+
+// RecordMatchResult saves a match result, logging failures
+func RecordMatchResult(result MatchResult) {
+	if err := SaveMatchResult(result); err != nil {
+		fmt.Printf("Failed to save the result of a match in arena %d: %v\n", result.ArenaID, err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchCountdown(t *testing.T) {
+	am := NewArenaManager()
+	arena := am.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+
+	UpdateArena(arena)
+	if arena.State != ArenaStateCountdown {
+		t.Fatalf("Expected a countdown once two players join, got state %d", arena.State)
+	}
+
+	arena.RemovePlayer(2)
+	UpdateArena(arena)
+	if arena.State != ArenaStateWaiting {
+		t.Fatalf("Expected the countdown to stop when a player leaves, got state %d", arena.State)
+	}
+
+	// Teammates alone have nobody to fight
+	arena.AddPlayer(3, TeamChaos)
+	UpdateArena(arena)
+	if arena.State != ArenaStateWaiting {
+		t.Fatalf("Expected no countdown with one team, got state %d", arena.State)
+	}
+	arena.RemovePlayer(3)
+
+	arena.AddPlayer(2, TeamOrder)
+	UpdateArena(arena)
+	arena.mu.Lock()
+	arena.CountdownEnds = time.Now().Add(-time.Millisecond)
+	arena.mu.Unlock()
+	UpdateArena(arena)
+	if arena.State != ArenaStateActive || arena.StartTime.IsZero() {
+		t.Errorf("Expected the match to start after the countdown, got state %d", arena.State)
+	}
+}
+
+func TestMatchWinConditions(t *testing.T) {
+	now := time.Now()
+
+	// Destroying every other shrine in play wins
+	shrines := newTestObjectiveArena(t, 0)
+	if shrines.CheckMatchEnd(now) {
+		t.Fatal("Expected the match to go on while both shrines stand")
+	}
+	shrines.Shrines[TeamChaos].Bias = 0
+	if !shrines.CheckMatchEnd(now) {
+		t.Fatal("Expected the match to end when the Chaos shrine falls")
+	}
+	if result, _ := shrines.LastResult(); result.Winner != TeamOrder || result.Reason != "shrines destroyed" {
+		t.Errorf("Expected Order to win by shrines, got %+v", result)
+	}
+
+	// The last team left in the arena wins
+	standing := newTestArena(1)
+	standing.AddPlayer(1, TeamChaos)
+	standing.AddPlayer(2, TeamOrder)
+	standing.StartArena()
+	standing.RemovePlayer(1)
+	if !standing.CheckMatchEnd(now) {
+		t.Fatal("Expected the match to end when only Order is left")
+	}
+	if result, _ := standing.LastResult(); result.Winner != TeamOrder {
+		t.Errorf("Expected Order to be the last team standing, got %+v", result)
+	}
+
+	// A match that started with one team is not won by it for being alone
+	alone := newTestArena(1)
+	alone.AddPlayer(1, TeamChaos)
+	alone.AddPlayer(2, TeamChaos)
+	alone.StartArena()
+	if alone.CheckMatchEnd(now) {
+		t.Error("Expected a match started by one team to go on")
+	}
+	alone.AddPlayer(3, TeamOrder)
+	alone.RemovePlayer(3)
+	if alone.CheckMatchEnd(now) {
+		t.Error("Expected a team that joined after the start not to count as leaving")
+	}
+
+	// Score limits without teams are won by a player
+	ffa := newTestArena(1)
+	ffa.Ruleset = NewRuleset(ArenaModeFreeForAll)
+	ffa.ScoreLimit = 2
+	ffa.AddPlayer(1, TeamNone)
+	ffa.AddPlayer(2, TeamNone)
+	ffa.AddPlayer(3, TeamNone)
+	ffa.StartArena()
+	ffa.GetPlayer(3).Score = 2
+	if !ffa.CheckMatchEnd(now) {
+		t.Fatal("Expected the score limit to end the match")
+	}
+	if result, _ := ffa.LastResult(); result.WinnerID != 3 || result.Winner != TeamNone {
+		t.Errorf("Expected player 3 to win, got %+v", result)
+	}
+}
+
+func TestMatchTimeLimit(t *testing.T) {
+	arena := newTestArena(1)
+	arena.TimeLimit = time.Minute
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamChaos)
+	arena.AddPlayer(3, TeamOrder)
+	arena.StartArena()
+	arena.GetPlayer(1).Score = 1
+	arena.GetPlayer(2).Score = 1
+	arena.GetPlayer(3).Score = 1
+
+	if arena.CheckMatchEnd(arena.StartTime.Add(30 * time.Second)) {
+		t.Fatal("Expected the match to go on before the time limit")
+	}
+	if !arena.CheckMatchEnd(arena.StartTime.Add(time.Minute)) {
+		t.Fatal("Expected the time limit to end the match")
+	}
+	if result, _ := arena.LastResult(); result.Winner != TeamChaos || result.Reason != matchEndedByLimit {
+		t.Errorf("Expected Chaos to win on score, got %+v", result)
+	}
+
+	arena.GetPlayer(1).Score = 0
+	arena.CheckMatchEnd(arena.StartTime.Add(time.Minute))
+	if result, _ := arena.LastResult(); result.Winner != TeamNone {
+		t.Errorf("Expected a draw on equal scores, got %+v", result)
+	}
+}
+
+func TestMatchResultSavedAndArenaReset(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	arena := newTestArena(1)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.StartArena()
+	arena.GetPlayer(1).Score = 3
+	arena.GetPlayer(2).Health = 40
+	arena.RemovePlayer(2)
+
+	UpdateArenas(gs)
+	if arena.State != ArenaStateEnded {
+		t.Fatalf("Expected the match to end, got state %d", arena.State)
+	}
+	results, err := LoadRecentMatches(arena.ID, 10)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected one saved result, got %v, %v", results, err)
+	}
+	if r := results[0]; r.Winner != TeamChaos || r.Reason != "last team standing" || r.Players != 1 || r.EndedAt.IsZero() {
+		t.Errorf("Unexpected saved result %+v", r)
+	}
+
+	// Ending again does not save the result twice
+	EndMatch(arena)
+	if results, _ := LoadRecentMatches(arena.ID, 10); len(results) != 1 {
+		t.Errorf("Expected the result to be saved once, got %d", len(results))
+	}
+
+	arena.mu.Lock()
+	arena.EndTime = time.Now().Add(-defaultResetDelay)
+	arena.mu.Unlock()
+	UpdateArena(arena)
+	player := arena.GetPlayer(1)
+	if arena.State != ArenaStateWaiting || player == nil || player.Score != 0 || player.Health != 100 {
+		t.Fatalf("Expected the arena to reopen with player 1 kept and reset, got state %d and %+v", arena.State, player)
+	}
+	if _, ok := arena.LastResult(); ok {
+		t.Error("Expected the last result to be cleared for the next match")
+	}
+
+	arena.ReturnPlayers = true
+	arena.StartArena()
+	arena.EndArena()
+	arena.mu.Lock()
+	arena.EndTime = time.Now().Add(-defaultResetDelay)
+	arena.mu.Unlock()
+	UpdateArena(arena)
+	if arena.GetPlayerCount() != 0 {
+		t.Errorf("Expected players to return to the lobby, got %d left", arena.GetPlayerCount())
+	}
+}
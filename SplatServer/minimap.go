@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+)
+
+// defaultMinimapScale is the width of a block in a minimap, in pixels
+const defaultMinimapScale = 4
+
+// Minimap colours: solid blocks are near black and open ones run from dark to light grey
+// with the height of their floor
+var (
+	minimapSolid = color.RGBA{16, 16, 24, 255}
+	minimapDead  = color.RGBA{128, 128, 128, 255}
+
+	minimapTeamColors = map[Team]color.RGBA{
+		TeamNone:    {255, 255, 255, 255},
+		TeamChaos:   {220, 40, 40, 255},
+		TeamBalance: {40, 200, 60, 255},
+		TeamOrder:   {60, 100, 240, 255},
+	}
+	minimapTriggerColors = map[TriggerType]color.RGBA{
+		TriggerDoor:     {255, 140, 0, 255},
+		TriggerElevator: {0, 220, 220, 255},
+		TriggerTeleport: {230, 0, 230, 255},
+		TriggerLever:    {255, 230, 0, 255},
+	}
+)
+
+// Minimap is a top-down view of a grid's blocks, shaded by floor height, with its
+// trigger locations and optionally a world's team start and raise points and players'
+// positions drawn over them
+type Minimap struct {
+	Grid    *Grid
+	World   *World        // team start points are drawn filled and raise points hollow, when set
+	Players []ArenaPlayer // players drawn as discs, grey when dead
+	Scale   int           // pixels per block, defaultMinimapScale when 0
+}
+
+// scale returns the pixels per block
+func (m *Minimap) scale() int {
+	if m.Scale <= 0 {
+		return defaultMinimapScale
+	}
+	return m.Scale
+}
+
+// pixel returns the pixel a world position falls in
+func (m *Minimap) pixel(x, y float64) (int, int) {
+	scale := float64(m.scale()) / blockSize
+	return int(math.Floor(x * scale)), int(math.Floor(y * scale))
+}
+
+// Render draws the minimap
+func (m *Minimap) Render() *image.RGBA {
+	scale := m.scale()
+	img := image.NewRGBA(image.Rect(0, 0, gridWidth*scale, gridWidth*scale))
+	m.drawBlocks(img)
+	m.drawTriggers(img)
+
+	marker := max(3, scale)
+	if m.World != nil {
+		for team, spawn := range m.World.Spawns {
+			x, y := m.pixel(spawn.Start.X, spawn.Start.Y)
+			fillRect(img, x-marker/2, y-marker/2, marker, marker, minimapTeamColors[team])
+			x, y = m.pixel(spawn.Raise.X, spawn.Raise.Y)
+			outlineRect(img, x-marker/2, y-marker/2, marker, marker, minimapTeamColors[team])
+		}
+	}
+	for _, player := range m.Players {
+		c := minimapTeamColors[player.Team]
+		if player.Dead {
+			c = minimapDead
+		}
+		x, y := m.pixel(player.X, player.Y)
+		fillDisc(img, x, y, marker/2+1, c)
+	}
+	return img
+}
+
+// drawBlocks fills each block, shading open blocks by how high their floor is between
+// the lowest and highest open floors in the grid
+func (m *Minimap) drawBlocks(img *image.RGBA) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range m.Grid.Blocks {
+		block := &m.Grid.Blocks[i]
+		if floor := m.Grid.floorZ(block); m.Grid.ceilingZ(block) > floor {
+			low, high = math.Min(low, floor), math.Max(high, floor)
+		}
+	}
+
+	scale := m.scale()
+	for i := range m.Grid.Blocks {
+		block := &m.Grid.Blocks[i]
+		c := minimapSolid
+		if floor := m.Grid.floorZ(block); m.Grid.ceilingZ(block) > floor {
+			shade := uint8(64)
+			if high > low {
+				shade = uint8(64 + 176*(floor-low)/(high-low))
+			}
+			c = color.RGBA{shade, shade, shade, 255}
+		}
+		fillRect(img, block.Column*scale, block.Row*scale, scale, scale, c)
+	}
+}
+
+// drawTriggers draws the thins of doors, teleporter pads and levers as lines, outlines the
+// blocks elevators lift and crosses teleporter destinations
+func (m *Minimap) drawTriggers(img *image.RGBA) {
+	defs := make(map[int]*TriggerDef)
+	for i := range m.Grid.Triggers {
+		defs[m.Grid.Triggers[i].ID] = &m.Grid.Triggers[i]
+	}
+
+	scale := m.scale()
+	for _, thin := range m.Grid.Thins {
+		if def := defs[thin.TriggerID]; def != nil {
+			x1, y1 := m.pixel(float64(thin.X1), float64(thin.Y1))
+			x2, y2 := m.pixel(float64(thin.X2), float64(thin.Y2))
+			drawLine(img, x1, y1, x2, y2, minimapTriggerColors[def.Type])
+		}
+	}
+	for _, def := range m.Grid.Triggers {
+		c := minimapTriggerColors[def.Type]
+		switch def.Type {
+		case TriggerElevator:
+			column, row := min(def.Column1, def.Column2), min(def.Row1, def.Row2)
+			width, height := absInt(def.Column2-def.Column1)+1, absInt(def.Row2-def.Row1)+1
+			outlineRect(img, column*scale, row*scale, width*scale, height*scale, c)
+		case TriggerTeleport:
+			for _, destination := range def.Destinations {
+				x, y := m.pixel(destination.X, destination.Y)
+				arm := max(2, scale/2)
+				drawLine(img, x-arm, y-arm, x+arm, y+arm, c)
+				drawLine(img, x-arm, y+arm, x+arm, y-arm, c)
+			}
+		}
+	}
+}
+
+// WritePNG renders the minimap and writes it as a PNG image
+func (m *Minimap) WritePNG(w io.Writer) error {
+	return png.Encode(w, m.Render())
+}
+
+// RenderMinimap writes a PNG minimap of the arena's grid as it is now, with its doors
+// and elevators where they stand and its players where they are
+func (a *Arena) RenderMinimap(w io.Writer, scale int) error {
+	a.mu.Lock()
+	if a.Grid == nil {
+		a.mu.Unlock()
+		return fmt.Errorf("arena %d has no grid loaded", a.ID)
+	}
+	minimap := &Minimap{Grid: a.Grid, World: a.World, Scale: scale}
+	for _, player := range a.Players {
+		minimap.Players = append(minimap.Players, *player)
+	}
+	img := minimap.Render()
+	a.mu.Unlock()
+
+	return png.Encode(w, img)
+}
+
+// runContentMap writes a PNG minimap of a grid from the content tree, with its team
+// start and raise points and triggers, and returns the exit status
+func runContentMap(gridName, path string, out io.Writer) int {
+	gridID, err := parseGridName(gridName)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	grid := loadGrid(gridID)
+	if grid == nil {
+		fmt.Fprintf(out, "Grid %d could not be loaded\n", gridID)
+		return 1
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	minimap := &Minimap{Grid: grid, World: loadGridWorld(gridID)}
+	if err := minimap.WritePNG(f); err != nil {
+		f.Close()
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Wrote the minimap of grid %d to %s\n", gridID, path)
+	return 0
+}
+
+// fillRect fills a rectangle of pixels, clipped to the image
+func fillRect(img *image.RGBA, x, y, width, height int, c color.RGBA) {
+	r := image.Rect(x, y, x+width, y+height).Intersect(img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+// outlineRect draws the edge of a rectangle of pixels
+func outlineRect(img *image.RGBA, x, y, width, height int, c color.RGBA) {
+	fillRect(img, x, y, width, 1, c)
+	fillRect(img, x, y+height-1, width, 1, c)
+	fillRect(img, x, y, 1, height, c)
+	fillRect(img, x+width-1, y, 1, height, c)
+}
+
+// fillDisc fills the pixels within a radius of a centre pixel
+func fillDisc(img *image.RGBA, cx, cy, radius int, c color.RGBA) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius && image.Pt(cx+dx, cy+dy).In(img.Bounds()) {
+				img.SetRGBA(cx+dx, cy+dy, c)
+			}
+		}
+	}
+}
+
+// drawLine draws a line of pixels between two points
+func drawLine(img *image.RGBA, x1, y1, x2, y2 int, c color.RGBA) {
+	steps := max(absInt(x2-x1), absInt(y2-y1))
+	for i := 0; i <= steps; i++ {
+		x, y := x1, y1
+		if steps > 0 {
+			x = x1 + (x2-x1)*i/steps
+			y = y1 + (y2-y1)*i/steps
+		}
+		if image.Pt(x, y).In(img.Bounds()) {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// absInt returns the absolute value of an int
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMinimapRender(t *testing.T) {
+	grid := newTestGrid()
+	grid.Triggers = []TriggerDef{
+		{ID: 1, Type: TriggerDoor},
+		{ID: 2, Type: TriggerElevator, Column1: 12, Row1: 1, Column2: 12, Row2: 1},
+	}
+	grid.Thins = []Thin{{ID: 1, X1: 640, Y1: 64, X2: 640, Y2: 128, Tall: 128, TriggerID: 1}}
+	world := &World{Spawns: map[Team]TeamSpawn{
+		TeamChaos: {Start: SpawnPoint{X: 5*64 + 32, Y: 5*64 + 32}, Raise: SpawnPoint{X: 6*64 + 32, Y: 6*64 + 32}},
+	}}
+	minimap := &Minimap{
+		Grid:  grid,
+		World: world,
+		Players: []ArenaPlayer{
+			{PlayerID: 1, Team: TeamOrder, X: 10*64 + 32, Y: 10*64 + 32},
+			{PlayerID: 2, Team: TeamOrder, X: 20*64 + 32, Y: 10*64 + 32, Dead: true},
+		},
+	}
+	img := minimap.Render()
+	if size := img.Bounds().Size(); size.X != 512 || size.Y != 512 {
+		t.Fatalf("Expected a 512 pixel square minimap, got %v", size)
+	}
+
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"solid block", 13, 1, minimapSolid},
+		{"lowest floor", 5, 5, color.RGBA{64, 64, 64, 255}},
+		{"highest floor", 9, 9, color.RGBA{240, 240, 240, 255}},
+		{"team start", 22, 22, minimapTeamColors[TeamChaos]},
+		{"raise point edge", 24, 24, minimapTeamColors[TeamChaos]},
+		{"door", 40, 6, minimapTriggerColors[TriggerDoor]},
+		{"elevator edge", 48, 5, minimapTriggerColors[TriggerElevator]},
+		{"player", 42, 42, minimapTeamColors[TeamOrder]},
+		{"dead player", 82, 42, minimapDead},
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: expected %v at (%d, %d), got %v", tt.name, tt.want, tt.x, tt.y, got)
+		}
+	}
+	if got := img.RGBAAt(26, 26); got == minimapTeamColors[TeamChaos] {
+		t.Error("Expected the raise point to be drawn hollow")
+	}
+}
+
+func TestArenaRenderMinimap(t *testing.T) {
+	arena := newTestArena(1)
+	var buf bytes.Buffer
+	if err := arena.RenderMinimap(&buf, 2); err == nil {
+		t.Error("Expected an arena without a grid to fail")
+	}
+
+	arena.Grid = newTestGrid()
+	arena.AddPlayer(1, TeamBalance)
+	placeTriggerPlayer(arena, 10*64+32, 10*64+32)
+	if err := arena.RenderMinimap(&buf, 2); err != nil {
+		t.Fatalf("RenderMinimap failed: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode the minimap: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 256 || size.Y != 256 {
+		t.Errorf("Expected a 256 pixel square minimap, got %v", size)
+	}
+	if got := color.RGBAModel.Convert(img.At(21, 21)); got != minimapTeamColors[TeamBalance] {
+		t.Errorf("Expected the player drawn at their position, got %v", got)
+	}
+}
+
+func TestDebugMinimapCommand(t *testing.T) {
+	gs := NewGameState()
+	arena := newTestArena(1)
+	arena.Grid = newTestGrid()
+	gs.ArenaManager.Arenas[arena.ID] = arena
+	player := addTestCaster(gs, 1)
+	conn := player.Conn.(*captureConn)
+	dir := t.TempDir()
+	t.Setenv("MINIMAP_DIR", dir)
+
+	sendArenaMinimap([]string{"1"}, player, gs)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 || !strings.Contains(conn.written.String(), "Only admins") {
+		t.Fatalf("Expected the minimap to be for admins only, got %q", conn.written.String())
+	}
+
+	// Each arena has one minimap file, replaced each time
+	player.Admin = true
+	sendArenaMinimap([]string{"1"}, player, gs)
+	sendArenaMinimap([]string{"1"}, player, gs)
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "arena01.png" {
+		t.Errorf("Expected a single arena01.png, got %v", entries)
+	}
+}
+
+func TestContentMapCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid04.png")
+	var out bytes.Buffer
+	if status := runCommand([]string{"content", "map", "grid04", path}, &out); status != 0 {
+		t.Fatalf("Expected the map of grid 4 to be written, got status %d: %s", status, out.String())
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if img, err := png.Decode(f); err != nil || img.Bounds().Dx() != gridWidth*defaultMinimapScale {
+		t.Errorf("Expected a %d pixel PNG, got error %v", gridWidth*defaultMinimapScale, err)
+	}
+
+	if status := runCommand([]string{"content", "map", "lake", path}, &out); status != 2 {
+		t.Errorf("Expected a bad grid name to fail with status 2, got %d", status)
+	}
+}
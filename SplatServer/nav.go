@@ -0,0 +1,470 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+)
+
+// navDirections are the steps from a block to its eight neighbours
+var navDirections = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// Waypoint is a point along a path. A waypoint with a TriggerID is only reachable once
+// that door or elevator is in the TriggerOn state, so movers set it off if it is not and
+// wait for it to get there first
+type Waypoint struct {
+	X, Y      float64
+	TriggerID int
+	TriggerOn bool
+	Teleport  bool // the waypoint is on a teleporter pad and the path goes on from its destination
+}
+
+// navEdge is a step between neighbouring blocks a player can walk, centre to centre
+type navEdge struct {
+	To      int
+	Cost    float64
+	Trigger int  // door the step goes through or elevator it steps on or off, 0 for none
+	On      bool // whether the step needs the trigger on or off
+	Thin    int  // barrier thin the step goes through, 0 for none
+	Via     int  // block a step onto a teleporter pad walks toward, To being its destination
+	Pad     bool // the step crosses a teleporter pad
+}
+
+// NavGraph is the walkable blocks of a grid and the steps between them. Steps are
+// worked out with the same collision as player movement, so they climb no more than
+// maxStepHeight, keep to headroom and go around props; a step may drop any height. The
+// graph is built with doors closed, thins standing and elevators at both ends of their
+// travel, and steps that depend on them are marked so paths can follow an arena's state.
+// Steps onto teleporter pads lead to the pad's destinations
+type NavGraph struct {
+	walkable []bool
+	edges    [][]navEdge
+}
+
+// navCache builds a grid's navigation graph the first time a path is asked for, and is
+// shared by the arena copies of the grid
+type navCache struct {
+	once  sync.Once
+	graph *NavGraph
+}
+
+// NavGraph returns the grid's navigation graph, building it on first use
+func (g *Grid) NavGraph() *NavGraph {
+	if g.nav == nil {
+		g.nav = &navCache{}
+	}
+	g.nav.once.Do(func() {
+		g.nav.graph = buildNavGraph(g)
+	})
+	return g.nav.graph
+}
+
+// buildNavGraph works out the walkable blocks and steps of a grid as loaded, then adds
+// the steps each elevator opens up when it is on and marks the steps it closes
+func buildNavGraph(g *Grid) *NavGraph {
+	static := &Grid{Blocks: g.Blocks, props: g.props}
+	n := &NavGraph{walkable: make([]bool, gridBlockCount), edges: make([][]navEdge, gridBlockCount)}
+	for i := range g.Blocks {
+		block := &g.Blocks[i]
+		x, y := block.Center()
+		n.walkable[i] = !static.IsSolid(x, y, playerRadius)
+	}
+	for i := range g.Blocks {
+		n.edges[i] = navSteps(static, n.walkable, i)
+	}
+
+	for _, def := range g.Triggers {
+		if def.Type == TriggerElevator && def.Enabled {
+			n.addElevator(g, def)
+		}
+	}
+	n.markThins(g)
+	n.markTeleporters(g)
+	return n
+}
+
+// navSteps returns the steps a player can take from a block to its neighbours
+func navSteps(grid *Grid, walkable []bool, from int) []navEdge {
+	if !walkable[from] {
+		return nil
+	}
+	column, row := from%gridWidth, from/gridWidth
+	x1, y1 := grid.Blocks[from].Center()
+
+	var edges []navEdge
+	for _, d := range navDirections {
+		to := grid.Block(column+d[0], row+d[1])
+		if to == nil || !walkable[to.Row*gridWidth+to.Column] {
+			continue
+		}
+		// Diagonal steps may not cut the corners of solid blocks
+		if d[0] != 0 && d[1] != 0 && (!walkable[row*gridWidth+column+d[0]] || !walkable[(row+d[1])*gridWidth+column]) {
+			continue
+		}
+		x2, y2 := to.Center()
+		if _, blocked := grid.SweepCircle(x1, y1, x2, y2, playerRadius); blocked {
+			continue
+		}
+		edges = append(edges, navEdge{To: to.Row*gridWidth + to.Column, Cost: math.Hypot(x2-x1, y2-y1)})
+	}
+	return edges
+}
+
+// addElevator lifts an elevator's blocks to their on height and works out the steps on,
+// off and around it again. Steps only possible with the elevator on are added, and those
+// only possible with it off are marked as needing it off
+func (n *NavGraph) addElevator(g *Grid, def TriggerDef) {
+	lifted := &Grid{Blocks: g.Blocks, props: g.props, floors: make(map[int]float64), ceilings: make(map[int]float64)}
+	(&Trigger{Def: &def, Position: 1}).liftBlocks(lifted)
+
+	affected := make(map[int]bool)
+	for row := min(def.Row1, def.Row2) - 1; row <= max(def.Row1, def.Row2)+1; row++ {
+		for column := min(def.Column1, def.Column2) - 1; column <= max(def.Column1, def.Column2)+1; column++ {
+			if block := g.Block(column, row); block != nil {
+				affected[row*gridWidth+column] = true
+			}
+		}
+	}
+	walkable := make([]bool, gridBlockCount)
+	copy(walkable, n.walkable)
+	for i := range affected {
+		x, y := g.Blocks[i].Center()
+		walkable[i] = !lifted.IsSolid(x, y, playerRadius)
+	}
+
+	for from := range affected {
+		onSteps := make(map[int]navEdge)
+		for _, edge := range navSteps(lifted, walkable, from) {
+			onSteps[edge.To] = edge
+		}
+		var edges []navEdge
+		for _, edge := range n.edges[from] {
+			if !affected[edge.To] || edge.Trigger != 0 {
+				edges = append(edges, edge)
+				continue
+			}
+			if _, both := onSteps[edge.To]; both {
+				delete(onSteps, edge.To)
+				edges = append(edges, edge)
+				continue
+			}
+			edge.Trigger, edge.On = def.ID, false
+			edges = append(edges, edge)
+		}
+		for _, edge := range onSteps {
+			if !affected[edge.To] {
+				continue
+			}
+			edge.Trigger, edge.On = def.ID, true
+			edges = append(edges, edge)
+		}
+		n.edges[from] = edges
+		n.walkable[from] = n.walkable[from] || walkable[from]
+	}
+}
+
+// markThins marks the steps blocked by a closed door or a barrier thin tall enough to
+// stop players
+func (n *NavGraph) markThins(g *Grid) {
+	for i := range g.Thins {
+		thin := &g.Thins[i]
+		def := g.triggerDef(thin.TriggerID)
+		door := def != nil && def.Type == TriggerDoor
+		if !door && !thin.Barrier() {
+			continue
+		}
+		blocker := &Grid{Blocks: g.Blocks, obstacles: []OrientedBox{thin.Box()}}
+		n.stepsNear(g, thin, func(from int, edge *navEdge, x1, y1, x2, y2 float64) {
+			if _, blocked := blocker.SweepCircle(x1, y1, x2, y2, playerRadius); !blocked {
+				return
+			}
+			if door {
+				edge.Trigger, edge.On = thin.TriggerID, true
+			} else {
+				edge.Thin = thin.ID
+			}
+		})
+	}
+}
+
+// markTeleporters sends the steps onto or through an enabled teleporter's pads to the
+// blocks of each destination it may choose. Teleporters choosing by team or at random
+// may send movers somewhere other than the path planned, so they find a new path from
+// wherever they land
+func (n *NavGraph) markTeleporters(g *Grid) {
+	for _, def := range g.Triggers {
+		if def.Type != TriggerTeleport || !def.Enabled || len(def.Destinations) == 0 {
+			continue
+		}
+		choices := def.Destinations[:1]
+		if def.TeamDestinations {
+			choices = def.Destinations
+		} else if def.Random > 1 {
+			choices = def.Destinations[:min(def.Random, len(def.Destinations))]
+		}
+		var destinations []int
+		for _, choice := range choices {
+			if node, ok := navNode(choice.X, choice.Y); ok && n.walkable[node] {
+				destinations = append(destinations, node)
+			}
+		}
+		if len(destinations) == 0 {
+			continue
+		}
+
+		for i := range g.Thins {
+			thin := &g.Thins[i]
+			if thin.TriggerID != def.ID {
+				continue
+			}
+			type padStep struct {
+				from int
+				edge *navEdge
+			}
+			var onto []padStep
+			n.stepsNear(g, thin, func(from int, edge *navEdge, x1, y1, x2, y2 float64) {
+				if !edge.Pad && (thin.Crosses(x1, y1, x2, y2) || thin.DistanceTo(x2, y2) <= playerRadius) {
+					onto = append(onto, padStep{from, edge})
+				}
+			})
+			for _, step := range onto {
+				step.edge.Via, step.edge.Pad = step.edge.To, true
+				for _, destination := range destinations[1:] {
+					edge := *step.edge
+					edge.To = destination
+					n.edges[step.from] = append(n.edges[step.from], edge)
+				}
+				step.edge.To = destinations[0]
+			}
+		}
+	}
+}
+
+// stepsNear calls fn with each step from a block within a block of a thin, and the
+// centres of the blocks it goes between
+func (n *NavGraph) stepsNear(g *Grid, thin *Thin, fn func(from int, edge *navEdge, x1, y1, x2, y2 float64)) {
+	minColumn, maxColumn := int(math.Floor(float64(min(thin.X1, thin.X2))/blockSize))-1, int(math.Floor(float64(max(thin.X1, thin.X2))/blockSize))+1
+	minRow, maxRow := int(math.Floor(float64(min(thin.Y1, thin.Y2))/blockSize))-1, int(math.Floor(float64(max(thin.Y1, thin.Y2))/blockSize))+1
+	for row := minRow; row <= maxRow; row++ {
+		for column := minColumn; column <= maxColumn; column++ {
+			block := g.Block(column, row)
+			if block == nil {
+				continue
+			}
+			from := row*gridWidth + column
+			x1, y1 := block.Center()
+			edges := n.edges[from]
+			for j := range edges {
+				x2, y2 := g.Blocks[edges[j].To].Center()
+				fn(from, &edges[j], x1, y1, x2, y2)
+			}
+		}
+	}
+}
+
+// triggerDef returns the definition of a trigger by ID, or nil when there is none
+func (g *Grid) triggerDef(id int) *TriggerDef {
+	if id < 1 || id > len(g.Triggers) || g.Triggers[id-1].ID != id {
+		return nil
+	}
+	return &g.Triggers[id-1]
+}
+
+// Walkable reports whether a player can stand at the centre of the block a point is in
+func (n *NavGraph) Walkable(x, y float64) bool {
+	node, ok := navNode(x, y)
+	return ok && n.walkable[node]
+}
+
+// navNode returns the block a point is in
+func navNode(x, y float64) (int, bool) {
+	column, row := int(math.Floor(x/blockSize)), int(math.Floor(y/blockSize))
+	if column < 0 || row < 0 || column >= gridWidth || row >= gridWidth {
+		return 0, false
+	}
+	return row*gridWidth + column, true
+}
+
+// nearestWalkable returns the walkable block closest to a point among the block it is
+// in and that block's neighbours
+func (n *NavGraph) nearestWalkable(x, y float64) (int, bool) {
+	column, row := int(math.Floor(x/blockSize)), int(math.Floor(y/blockSize))
+	best, bestDistance := 0, math.Inf(1)
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			c, r := column+dx, row+dy
+			if c < 0 || r < 0 || c >= gridWidth || r >= gridWidth || !n.walkable[r*gridWidth+c] {
+				continue
+			}
+			cx, cy := float64(c)*blockSize+blockSize/2, float64(r)*blockSize+blockSize/2
+			if distance := math.Hypot(cx-x, cy-y); distance < bestDistance {
+				best, bestDistance = r*gridWidth+c, distance
+			}
+		}
+	}
+	return best, !math.IsInf(bestDistance, 1)
+}
+
+// navQueue is the A* open set, ordered by estimated total cost
+type navQueue []navItem
+
+type navItem struct {
+	node     int
+	estimate float64
+}
+
+func (q navQueue) Len() int            { return len(q) }
+func (q navQueue) Less(i, j int) bool  { return q[i].estimate < q[j].estimate }
+func (q navQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x interface{}) { *q = append(*q, x.(navItem)) }
+func (q *navQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// navHeuristic is the octile distance between two blocks, which never overestimates
+// a path of eight-way steps
+func navHeuristic(from, to int) float64 {
+	dx := float64(absInt(from%gridWidth - to%gridWidth))
+	dy := float64(absInt(from/gridWidth - to/gridWidth))
+	return (dx + dy + (math.Sqrt2-2)*math.Min(dx, dy)) * blockSize
+}
+
+// findPath searches for the cheapest path between two points with A*. cross decides
+// whether a step through a door, elevator or thin can be taken, and whether movers have
+// to wait for its trigger first. The waypoints run from the first block after the start
+// to the goal, with straight runs of blocks left out
+func (n *NavGraph) findPath(x1, y1, x2, y2 float64, cross func(edge *navEdge) (passable, wait bool)) ([]Waypoint, bool) {
+	start, ok := n.nearestWalkable(x1, y1)
+	if !ok {
+		return nil, false
+	}
+	goal, ok := n.nearestWalkable(x2, y2)
+	if !ok {
+		return nil, false
+	}
+
+	cost := make([]float64, gridBlockCount)
+	for i := range cost {
+		cost[i] = math.Inf(1)
+	}
+	from := make([]int, gridBlockCount)
+	steps := make([]navEdge, gridBlockCount)
+	closed := make([]bool, gridBlockCount)
+	cost[start] = 0
+	open := &navQueue{{start, navHeuristic(start, goal)}}
+	for open.Len() > 0 {
+		node := heap.Pop(open).(navItem).node
+		if closed[node] {
+			continue
+		}
+		if node == goal {
+			break
+		}
+		closed[node] = true
+		for i := range n.edges[node] {
+			edge := n.edges[node][i]
+			if edge.Trigger != 0 || edge.Thin != 0 {
+				passable, wait := cross(&edge)
+				if !passable {
+					continue
+				}
+				if !wait {
+					edge.Trigger = 0
+				}
+			}
+			if c := cost[node] + edge.Cost; c < cost[edge.To] {
+				cost[edge.To], from[edge.To], steps[edge.To] = c, node, edge
+				heap.Push(open, navItem{edge.To, c + navHeuristic(edge.To, goal)})
+			}
+		}
+	}
+	if math.IsInf(cost[goal], 1) {
+		return nil, false
+	}
+
+	var nodes []int
+	for node := goal; node != start; node = from[node] {
+		nodes = append(nodes, node)
+	}
+	center := func(node int) (float64, float64) {
+		return float64(node%gridWidth)*blockSize + blockSize/2, float64(node/gridWidth)*blockSize + blockSize/2
+	}
+	var path []Waypoint
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node, step := nodes[i], steps[nodes[i]]
+		// Blocks in the middle of a straight run of plain steps are left out, but not the
+		// block before a trigger, where movers wait for it
+		if i > 0 {
+			next := steps[nodes[i-1]]
+			if step.Trigger == 0 && !step.Pad && next.Trigger == 0 && !next.Pad && node-from[node] == nodes[i-1]-node {
+				continue
+			}
+		}
+		waypoint := Waypoint{TriggerID: step.Trigger, TriggerOn: step.On && step.Trigger != 0, Teleport: step.Pad}
+		if step.Pad {
+			waypoint.X, waypoint.Y = center(step.Via)
+		} else {
+			waypoint.X, waypoint.Y = center(node)
+		}
+		path = append(path, waypoint)
+	}
+	// The path ends at the goal itself unless it was moved to a walkable block nearby
+	if node, _ := navNode(x2, y2); node == goal {
+		if last := len(path) - 1; last < 0 || path[last].TriggerID != 0 || path[last].Teleport {
+			path = append(path, Waypoint{X: x2, Y: y2})
+		} else {
+			path[len(path)-1].X, path[len(path)-1].Y = x2, y2
+		}
+	}
+	return path, true
+}
+
+// FindPath returns waypoints from one point to another on the grid as loaded, going
+// through doors and riding elevators that can be set off but not through thins
+func (g *Grid) FindPath(x1, y1, x2, y2 float64) ([]Waypoint, bool) {
+	return g.NavGraph().findPath(x1, y1, x2, y2, func(edge *navEdge) (bool, bool) {
+		def := g.triggerDef(edge.Trigger)
+		switch {
+		case edge.Thin != 0 || def == nil:
+			return false, false
+		case def.InitialOn == edge.On:
+			return true, false
+		}
+		return def.Enabled, true
+	})
+}
+
+// FindPath returns waypoints from one point to another in the arena as it is now: through
+// broken thins and open doors, and through closed doors and onto elevators that can be
+// set off, marking where they have to be. It returns false when there is no path or the
+// arena has no grid
+func (a *Arena) FindPath(x1, y1, x2, y2 float64) ([]Waypoint, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.findPathLocked(x1, y1, x2, y2)
+}
+
+// findPathLocked is FindPath for callers holding a.mu
+func (a *Arena) findPathLocked(x1, y1, x2, y2 float64) ([]Waypoint, bool) {
+	if a.Grid == nil {
+		return nil, false
+	}
+	return a.Grid.NavGraph().findPath(x1, y1, x2, y2, func(edge *navEdge) (bool, bool) {
+		if edge.Thin != 0 {
+			thin, exists := a.Thins[edge.Thin]
+			return exists && !thin.Standing(), false
+		}
+		trigger, exists := a.Triggers[edge.Trigger]
+		switch {
+		case !exists:
+			return false, false
+		case trigger.On == edge.On:
+			// Doors and elevators still moving are waited for
+			return true, trigger.Position != trigger.target()
+		}
+		return trigger.Def.Enabled, true
+	})
+}
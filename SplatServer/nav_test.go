@@ -0,0 +1,188 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newNavArena returns an arena on the test grid with the test triggers and a wall along
+// column 10 that only the door in row 1 goes through, and another along column 30 that
+// only destructible thin 6 at x=1920 in row 1 goes through
+func newNavArena(t *testing.T) *Arena {
+	withThinHitPoints(t, 200)
+	arena := newTriggerArena(t)
+	grid := newTestGrid()
+	grid.Triggers = arena.Grid.Triggers
+	grid.Thins = append(append([]Thin(nil), arena.Grid.Thins...), Thin{ID: 6, X1: 1920, Y1: 64, X2: 1920, Y2: 128, Tall: 128, BlockPlayers: true})
+	for row := 0; row < gridWidth; row++ {
+		for _, column := range []int{10, 30} {
+			if row != 1 {
+				grid.Block(column, row).MidBoxBottomZ, grid.Block(column, row).MidBoxTopZ = 0, 500
+			}
+		}
+	}
+	grid.nav = &navCache{}
+
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+	arena.Grid = grid.arenaCopy()
+	arena.Geometry = arena.Grid
+	arena.resetGridLocked()
+	return arena
+}
+
+// pathTriggers returns the waypoints along a path that wait for a trigger
+func pathTriggers(path []Waypoint) []Waypoint {
+	var waypoints []Waypoint
+	for _, waypoint := range path {
+		if waypoint.TriggerID != 0 {
+			waypoints = append(waypoints, waypoint)
+		}
+	}
+	return waypoints
+}
+
+func TestNavGraphSteps(t *testing.T) {
+	grid := newTestGrid()
+	graph := grid.NavGraph()
+	if graph != grid.NavGraph() {
+		t.Error("Expected the graph to be built once")
+	}
+	tests := []struct {
+		name     string
+		x, y     float64
+		walkable bool
+	}{
+		{"open block", 32, 32, true},
+		{"solid block", 3*64 + 32, 32, false},
+		{"low ceiling", 32, 4*64 + 32, false},
+		{"ledge", 2*64 + 32, 2*64 + 32, true},
+		{"outside", -32, 32, false},
+	}
+	for _, tt := range tests {
+		if got := graph.Walkable(tt.x, tt.y); got != tt.walkable {
+			t.Errorf("%s: expected walkable %v, got %v", tt.name, tt.walkable, got)
+		}
+	}
+
+	// Around the solid block, every leg is clear to walk
+	path, ok := grid.FindPath(2*64+32, 32, 4*64+32, 32)
+	if !ok || len(path) < 2 {
+		t.Fatalf("Expected a path around the solid block, got %v %+v", ok, path)
+	}
+	x, y := 2*64+32.0, 32.0
+	for _, waypoint := range path {
+		if _, blocked := grid.SweepCircle(x, y, waypoint.X, waypoint.Y, playerRadius); blocked {
+			t.Errorf("Expected the leg from (%.0f, %.0f) to (%.0f, %.0f) to be clear", x, y, waypoint.X, waypoint.Y)
+		}
+		x, y = waypoint.X, waypoint.Y
+	}
+	if last := path[len(path)-1]; last.X != 4*64+32 || last.Y != 32 {
+		t.Errorf("Expected the path to end at the goal, got %+v", last)
+	}
+
+	if _, ok := grid.FindPath(32, 2*64+32, 64+32, 2*64+32); !ok {
+		t.Error("Expected the low step to be climbable")
+	}
+	if _, ok := grid.FindPath(64+32, 2*64+32, 2*64+32, 2*64+32); ok {
+		t.Error("Expected the ledge to be too high to climb")
+	}
+	if _, ok := grid.FindPath(2*64+32, 2*64+32, 64+32, 2*64+32); !ok {
+		t.Error("Expected a drop off the ledge")
+	}
+}
+
+func TestArenaFindPath(t *testing.T) {
+	arena := newNavArena(t)
+
+	// The closed door has to be opened on the way through the wall
+	path, ok := arena.FindPath(300, 96, 800, 96)
+	if !ok {
+		t.Fatal("Expected a path through the door")
+	}
+	if waits := pathTriggers(path); len(waits) != 1 || waits[0].TriggerID != 1 || !waits[0].TriggerOn {
+		t.Errorf("Expected to wait for the door to open, got %+v", path)
+	}
+	if _, ok := arena.Grid.FindPath(300, 96, 800, 96); !ok {
+		t.Error("Expected the grid as loaded to have a path through the door")
+	}
+
+	arena.mu.Lock()
+	arena.Triggers[1].set(true, time.Now())
+	arena.Triggers[1].Position = 1
+	arena.applyTriggersLocked()
+	arena.mu.Unlock()
+	if path, ok := arena.FindPath(300, 96, 800, 96); !ok || len(pathTriggers(path)) != 0 {
+		t.Errorf("Expected to walk through the open door, got %v %+v", ok, path)
+	}
+
+	// With the elevator up its block can only be reached once it comes down
+	arena.mu.Lock()
+	arena.Triggers[2].set(true, time.Now())
+	arena.Triggers[2].Position = 1
+	arena.applyTriggersLocked()
+	arena.mu.Unlock()
+	path, ok = arena.FindPath(10*64+32, 96, 12*64+32, 96)
+	if waits := pathTriggers(path); !ok || len(waits) != 1 || waits[0].TriggerID != 2 || waits[0].TriggerOn {
+		t.Errorf("Expected to wait for the elevator to come down, got %v %+v", ok, path)
+	}
+
+	// The destructible thin blocks the wall until it is broken
+	if _, ok := arena.FindPath(1800, 96, 2000, 96); ok {
+		t.Error("Expected the standing thin to block the path")
+	}
+	if _, ok := arena.Grid.FindPath(1800, 96, 2000, 96); ok {
+		t.Error("Expected the grid as loaded to be blocked by the thin")
+	}
+	arena.mu.Lock()
+	arena.Thins[6].HitPoints = 0
+	arena.applyTriggersLocked()
+	arena.mu.Unlock()
+	if _, ok := arena.FindPath(1800, 96, 2000, 96); !ok {
+		t.Error("Expected a path through the broken thin")
+	}
+
+	// Walking over the teleporter pad carries on from its destination
+	path, ok = arena.FindPath(900, 96, 1500, 96)
+	teleported := false
+	for _, waypoint := range path {
+		teleported = teleported || waypoint.Teleport
+	}
+	if !ok || !teleported {
+		t.Errorf("Expected the path to go through the teleporter, got %v %+v", ok, path)
+	}
+
+	arena.mu.Lock()
+	arena.Grid = nil
+	arena.mu.Unlock()
+	if _, ok := arena.FindPath(300, 96, 800, 96); ok {
+		t.Error("Expected an arena without a grid to have no paths")
+	}
+}
+
+func TestFindPathShippedGrid(t *testing.T) {
+	grid := loadGrid(0)
+	world := loadGridWorld(0)
+	if grid == nil || world == nil {
+		t.Fatal("Grid 0 failed to load")
+	}
+	if copied := grid.arenaCopy(); copied.NavGraph() != grid.NavGraph() {
+		t.Error("Expected arena copies to share the grid's graph")
+	}
+
+	spawn := world.Spawns[TeamChaos]
+	if !grid.NavGraph().Walkable(spawn.Start.X, spawn.Start.Y) {
+		t.Fatal("Expected the chaos start point to be walkable")
+	}
+	path, ok := grid.FindPath(spawn.Raise.X, spawn.Raise.Y, spawn.Start.X, spawn.Start.Y)
+	if !ok {
+		t.Fatal("Expected a path from the raise point back to the start")
+	}
+	teleported := false
+	for _, waypoint := range path {
+		teleported = teleported || waypoint.Teleport
+	}
+	if !teleported {
+		t.Errorf("Expected the way out of Valhalla to use a teleporter, got %+v", path)
+	}
+}
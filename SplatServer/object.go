@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Objects.dat layout, as read by MageServer's Grid.LoadObjects
+const (
+	objectDefCount = 139
+	objectDefBytes = 116 // twenty-four little-endian int32 fields and a 20 character name
+	objectNameSize = 20
+)
+
+// ObjectDef is a prop definition from a grid's Objects.dat, such as "torch(left)" or
+// "tree1". Props with a radius and height are solid
+type ObjectDef struct {
+	ID      int // definition ID, which grid objects refer to
+	ImageID int
+	Radius  int // collision radius in world units, 0 for props players walk through
+	Height  int
+	Name    string
+}
+
+// Solid reports whether players and spells collide with the prop
+func (d *ObjectDef) Solid() bool {
+	return d.Radius > 0 && d.Height > 0
+}
+
+// LoadObjectFile reads the prop definitions from a grid's Objects.dat file
+func LoadObjectFile(path string) ([]ObjectDef, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	defs, err := ParseObjectDefs(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return defs, nil
+}
+
+// ParseObjectDefs parses Objects.dat data, skipping empty slots, which have ID 0. Some
+// objects in use, such as Grid00's object 21, have no name
+func ParseObjectDefs(r io.Reader) ([]ObjectDef, error) {
+	data := make([]byte, objectDefCount*objectDefBytes)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("object data is truncated: %v", err)
+	}
+
+	var defs []ObjectDef
+	for i := 0; i < objectDefCount; i++ {
+		record := data[i*objectDefBytes : (i+1)*objectDefBytes]
+		field := func(n int) int {
+			return int(int32(binary.LittleEndian.Uint32(record[n*4:])))
+		}
+		name := record[objectDefBytes-objectNameSize:]
+		if end := strings.IndexByte(string(name), 0); end >= 0 {
+			name = name[:end]
+		}
+		def := ObjectDef{
+			ID:      field(0),
+			ImageID: field(1),
+			Radius:  field(5),
+			Height:  field(6),
+			Name:    strings.TrimSpace(string(name)),
+		}
+		if def.ID == 0 {
+			continue
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// ObjectDef returns the definition a grid object refers to, or nil when it has none
+func (g *Grid) ObjectDef(id int) *ObjectDef {
+	for i := range g.ObjectDefs {
+		if g.ObjectDefs[i].ID == id {
+			return &g.ObjectDefs[i]
+		}
+	}
+	return nil
+}
+
+// placeProps files the boxes of the grid's solid props under every block they touch.
+// Objects stand Z units above the floor beneath them
+func (g *Grid) placeProps() {
+	g.props = make(map[int][]OrientedBox)
+	for _, object := range g.Objects {
+		def := g.ObjectDef(object.ObjectID)
+		x, y := float64(object.X), float64(object.Y)
+		floor, inside := g.FloorHeight(x, y)
+		if def == nil || !def.Solid() || !inside {
+			continue
+		}
+
+		radius, height := float64(def.Radius), float64(def.Height)
+		box := OrientedBox{
+			Center:  Vec3{x, y, floor + float64(object.Z) + height/2},
+			Extents: Vec3{radius, radius, height / 2},
+		}
+		for row := int(math.Floor((y - radius) / blockSize)); row <= int(math.Floor((y+radius)/blockSize)); row++ {
+			for column := int(math.Floor((x - radius) / blockSize)); column <= int(math.Floor((x+radius)/blockSize)); column++ {
+				if g.Block(column, row) != nil {
+					index := row*gridWidth + column
+					g.props[index] = append(g.props[index], box)
+				}
+			}
+		}
+	}
+}
+
+// propsIn returns the solid props touching a block, or nil outside the grid
+func (g *Grid) propsIn(column, row int) []OrientedBox {
+	if g.Block(column, row) == nil {
+		return nil
+	}
+	return g.props[row*gridWidth+column]
+}
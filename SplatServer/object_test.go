@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLoadShippedObjects(t *testing.T) {
+	path, err := findContentFile(contentDir(), "Grids", "Grid00", "Objects.dat")
+	if err != nil {
+		t.Fatalf("Objects.dat for grid 0 not found: %v", err)
+	}
+	defs, err := LoadObjectFile(path)
+	if err != nil {
+		t.Fatalf("LoadObjectFile failed: %v", err)
+	}
+	if len(defs) < 21 {
+		t.Fatalf("Expected at least 21 definitions, got %d", len(defs))
+	}
+	if torch := defs[0]; torch.ID != 1 || torch.Name != "torch(left)" || torch.Radius != 19 || torch.Height != 32 {
+		t.Errorf("Unexpected first definition %+v", torch)
+	}
+	// Object 21 is placed in the grid without a name
+	if unnamed := defs[20]; unnamed.ID != 21 || unnamed.Name != "" {
+		t.Errorf("Expected the unnamed object 21 to be kept, got %+v", unnamed)
+	}
+
+	// Splat Lake's trees are solid and stand on the floor beneath them
+	grid := loadGrid(9)
+	if grid == nil {
+		t.Fatal("Grid 9 failed to load")
+	}
+	if tree := grid.ObjectDef(1); tree == nil || tree.Name != "tree1" || !tree.Solid() {
+		t.Fatalf("Unexpected tree definition %+v", tree)
+	}
+	if tulips := grid.ObjectDef(4); tulips == nil || tulips.Solid() {
+		t.Errorf("Expected tulips not to be solid, got %+v", tulips)
+	}
+	floor, _ := grid.FloorHeight(2481, 1672)
+	if !grid.PointSolid(Vec3{2481, 1672, floor + 64}) || !grid.IsSolid(2481, 1672, playerRadius) {
+		t.Error("Expected the tree at (2481, 1672) to be solid")
+	}
+}
+
+func TestParseObjectDefsTruncated(t *testing.T) {
+	if _, err := ParseObjectDefs(bytes.NewReader(make([]byte, objectDefBytes*10))); err == nil {
+		t.Error("Expected truncated object data to fail")
+	}
+}
+
+func TestPropCollision(t *testing.T) {
+	grid := newTestGrid()
+	grid.ObjectDefs = []ObjectDef{
+		{ID: 1, Name: "tree", Radius: 12, Height: 128},
+		{ID: 2, Name: "barrel", Radius: 12, Height: 32},
+		{ID: 3, Name: "tulips"},
+	}
+	// The tree straddles blocks (4, 1) and (5, 1); the barrel, the tulips and a crate
+	// with no definition stand in the open
+	grid.Objects = []GridObject{
+		{ObjectID: 1, X: 320, Y: 96},
+		{ObjectID: 2, X: 100, Y: 96},
+		{ObjectID: 3, X: 100, Y: 160},
+		{ObjectID: 4, X: 160, Y: 160},
+	}
+	grid.placeProps()
+
+	if !grid.PointSolid(Vec3{316, 96, 100}) || !grid.PointSolid(Vec3{324, 96, 100}) {
+		t.Error("Expected the tree to be solid on both sides of the block edge")
+	}
+	if !grid.PointSolid(Vec3{100, 96, 16}) || grid.PointSolid(Vec3{100, 96, 40}) {
+		t.Error("Expected the barrel to be solid up to its height")
+	}
+	if !grid.IsSolid(320, 80, playerRadius) {
+		t.Error("Expected the tree to block players")
+	}
+	if grid.IsSolid(100, 96, playerRadius) || grid.IsSolid(100, 160, playerRadius) || grid.IsSolid(160, 160, playerRadius) {
+		t.Error("Expected players to step over the barrel and walk through the rest")
+	}
+	if _, hit := grid.SweepCircle(200, 96, 400, 96, playerRadius); !hit {
+		t.Error("Expected the tree to stop a move through it")
+	}
+	if _, hit := grid.TraceSegment(200, 96, 400, 96); !hit {
+		t.Error("Expected the tree to block sight")
+	}
+	if _, hit := grid.TraceSegment(32, 96, 200, 96); hit {
+		t.Error("Expected sight to pass over the barrel")
+	}
+	if !grid.arenaCopy().IsSolid(320, 96, playerRadius) {
+		t.Error("Expected an arena's copy of the grid to keep the tree")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
 )
 
 // PacketType defines the type of packet
@@ -21,6 +22,18 @@ const (
 	PacketProjectile    PacketType = 8
 	PacketSpellCast     PacketType = 9
 	PacketGameState     PacketType = 10
+	PacketArenaSnapshot PacketType = 11
+	PacketBolt          PacketType = 12
+	PacketScoreboard    PacketType = 13
+	PacketLeaderboard   PacketType = 14
+	PacketPlayerDeath   PacketType = 15
+	PacketPlayerRespawn PacketType = 16
+	PacketOrbState      PacketType = 17
+	PacketShrineBias    PacketType = 18
+	PacketPoolBias      PacketType = 19
+	PacketTriggerState  PacketType = 20
+	PacketThinDamage    PacketType = 21
+	PacketInterest      PacketType = 22
 )
 
 // Packet represents a network packet
@@ -108,6 +121,249 @@ func BuildPlayerUpdatePacket(player *Player) *Packet {
 	return NewPacket(PacketPlayerUpdate, buf.Bytes())
 }
 
+// BuildArenaUpdatePacket describes where a player in an arena is and how they are
+func BuildArenaUpdatePacket(arenaID int, player *ArenaPlayer) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(player.PlayerID))
+	binary.Write(buf, binary.LittleEndian, player.X)
+	binary.Write(buf, binary.LittleEndian, player.Y)
+	binary.Write(buf, binary.LittleEndian, player.Angle)
+	binary.Write(buf, binary.LittleEndian, int32(player.Health))
+	binary.Write(buf, binary.LittleEndian, player.Dead)
+	return NewPacket(PacketArenaUpdate, buf.Bytes())
+}
+
+// BuildInterestPacket tells a client that a player has entered or left its interest, and
+// so will be updated every tick or only now and then
+func BuildInterestPacket(arenaID, playerID int, entered bool) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(playerID))
+	binary.Write(buf, binary.LittleEndian, entered)
+	return NewPacket(PacketInterest, buf.Bytes())
+}
+
+// BuildArenaSnapshotPacket describes an arena's players and world objects
+// so a joining player can catch up with the current state
+func BuildArenaSnapshotPacket(arena *Arena) *Packet {
+	arena.mu.RLock()
+	defer arena.mu.RUnlock()
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arena.ID))
+	binary.Write(buf, binary.LittleEndian, uint8(arena.State))
+
+	binary.Write(buf, binary.LittleEndian, uint16(len(arena.Players)))
+	for _, player := range arena.Players {
+		binary.Write(buf, binary.LittleEndian, int32(player.PlayerID))
+		binary.Write(buf, binary.LittleEndian, uint8(player.Team))
+		binary.Write(buf, binary.LittleEndian, player.X)
+		binary.Write(buf, binary.LittleEndian, player.Y)
+		binary.Write(buf, binary.LittleEndian, player.Angle)
+		binary.Write(buf, binary.LittleEndian, int32(player.Health))
+	}
+
+	now := time.Now()
+	binary.Write(buf, binary.LittleEndian, uint16(len(arena.Walls)))
+	for _, wall := range arena.Walls {
+		remaining := int32(-1) // permanent
+		if !wall.ExpiresAt.IsZero() {
+			remaining = int32(wall.ExpiresAt.Sub(now).Milliseconds())
+		}
+		binary.Write(buf, binary.LittleEndian, wall.ID)
+		binary.Write(buf, binary.LittleEndian, int32(wall.SpellID))
+		binary.Write(buf, binary.LittleEndian, int32(wall.OwnerID))
+		binary.Write(buf, binary.LittleEndian, wall.X)
+		binary.Write(buf, binary.LittleEndian, wall.Y)
+		binary.Write(buf, binary.LittleEndian, wall.Angle)
+		binary.Write(buf, binary.LittleEndian, int32(wall.HitPoints))
+		binary.Write(buf, binary.LittleEndian, remaining)
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint16(len(arena.Runes)))
+	for _, r := range arena.Runes {
+		binary.Write(buf, binary.LittleEndian, r.ID)
+		binary.Write(buf, binary.LittleEndian, int32(r.SpellID))
+		binary.Write(buf, binary.LittleEndian, int32(r.OwnerID))
+		binary.Write(buf, binary.LittleEndian, uint8(r.Team))
+		binary.Write(buf, binary.LittleEndian, r.X)
+		binary.Write(buf, binary.LittleEndian, r.Y)
+		binary.Write(buf, binary.LittleEndian, int32(r.ExpiresAt.Sub(now).Milliseconds()))
+	}
+
+	triggers := arena.triggerListLocked()
+	binary.Write(buf, binary.LittleEndian, uint16(len(triggers)))
+	for _, trigger := range triggers {
+		writeTriggerState(buf, trigger)
+	}
+
+	thins := arena.thinListLocked()
+	binary.Write(buf, binary.LittleEndian, uint16(len(thins)))
+	for _, thin := range thins {
+		binary.Write(buf, binary.LittleEndian, int32(thin.Thin.ID))
+		binary.Write(buf, binary.LittleEndian, int32(thin.HitPoints))
+	}
+
+	return NewPacket(PacketArenaSnapshot, buf.Bytes())
+}
+
+// BuildBoltPacket describes a resolved bolt so clients can draw the beam
+func BuildBoltPacket(arenaID int, result BoltResult) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(result.CasterID))
+	binary.Write(buf, binary.LittleEndian, int32(result.SpellID))
+	binary.Write(buf, binary.LittleEndian, result.StartX)
+	binary.Write(buf, binary.LittleEndian, result.StartY)
+	binary.Write(buf, binary.LittleEndian, result.EndX)
+	binary.Write(buf, binary.LittleEndian, result.EndY)
+	binary.Write(buf, binary.LittleEndian, int32(result.TargetID))
+	return NewPacket(PacketBolt, buf.Bytes())
+}
+
+// BuildScoreboardPacket lists each player's score and combat statistics in scoreboard order
+func BuildScoreboardPacket(arenaID int, scoreboard []ScoreEntry) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, uint16(len(scoreboard)))
+	for _, entry := range scoreboard {
+		s := entry.Statistics
+		binary.Write(buf, binary.LittleEndian, int32(entry.PlayerID))
+		binary.Write(buf, binary.LittleEndian, uint8(entry.Team))
+		binary.Write(buf, binary.LittleEndian, int32(entry.Score))
+		for _, counter := range []int{s.Kills, s.Deaths, s.Raises, s.DamageDone, s.DamageTaken, s.HealingDone, s.HealingTaken} {
+			binary.Write(buf, binary.LittleEndian, int32(counter))
+		}
+	}
+	return NewPacket(PacketScoreboard, buf.Bytes())
+}
+
+// BuildPlayerDeathPacket announces a player's death, where their corpse lies and
+// how long until they respawn
+func BuildPlayerDeathPacket(arenaID int, victim *ArenaPlayer, killerID int, respawnIn time.Duration) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(victim.PlayerID))
+	binary.Write(buf, binary.LittleEndian, int32(killerID))
+	binary.Write(buf, binary.LittleEndian, victim.X)
+	binary.Write(buf, binary.LittleEndian, victim.Y)
+	binary.Write(buf, binary.LittleEndian, int32(respawnIn.Milliseconds()))
+	return NewPacket(PacketPlayerDeath, buf.Bytes())
+}
+
+// BuildPlayerRespawnPacket announces a player returning to life, either raised by
+// another player or respawned at their raise point when raisedBy is 0
+func BuildPlayerRespawnPacket(arenaID int, player *ArenaPlayer, raisedBy int) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(player.PlayerID))
+	binary.Write(buf, binary.LittleEndian, int32(raisedBy))
+	binary.Write(buf, binary.LittleEndian, player.X)
+	binary.Write(buf, binary.LittleEndian, player.Y)
+	binary.Write(buf, binary.LittleEndian, player.Angle)
+	binary.Write(buf, binary.LittleEndian, int32(player.Health))
+	return NewPacket(PacketPlayerRespawn, buf.Bytes())
+}
+
+// BuildOrbStatePacket describes where a capture-the-flag orb is and who carries it
+func BuildOrbStatePacket(arenaID int, orb *CTFOrb) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, uint8(orb.Team))
+	binary.Write(buf, binary.LittleEndian, uint8(orb.State))
+	binary.Write(buf, binary.LittleEndian, int32(orb.CarrierID))
+	binary.Write(buf, binary.LittleEndian, orb.X)
+	binary.Write(buf, binary.LittleEndian, orb.Y)
+	return NewPacket(PacketOrbState, buf.Bytes())
+}
+
+// BuildShrineBiasPacket describes a shrine's bias after a player biased it, or with
+// playerID 0 when nobody did
+func BuildShrineBiasPacket(arenaID int, shrine *Shrine, playerID int) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, uint8(shrine.ID))
+	binary.Write(buf, binary.LittleEndian, uint8(shrine.Team))
+	binary.Write(buf, binary.LittleEndian, uint8(shrine.Bias))
+	binary.Write(buf, binary.LittleEndian, uint8(shrine.MaxBias))
+	binary.Write(buf, binary.LittleEndian, int32(playerID))
+	return NewPacket(PacketShrineBias, buf.Bytes())
+}
+
+// BuildPoolBiasPacket describes a pool's owner and bias after a player biased it, or
+// with playerID 0 when nobody did
+func BuildPoolBiasPacket(arenaID int, pool *Pool, playerID int) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, uint8(pool.ID))
+	binary.Write(buf, binary.LittleEndian, uint8(pool.Team))
+	binary.Write(buf, binary.LittleEndian, uint8(pool.Bias))
+	binary.Write(buf, binary.LittleEndian, uint8(pool.MaxBias))
+	binary.Write(buf, binary.LittleEndian, int32(playerID))
+	return NewPacket(PacketPoolBias, buf.Bytes())
+}
+
+// writeTriggerState writes a trigger's ID, type, whether it is on and enabled, and how
+// far its door or elevator has moved
+func writeTriggerState(buf *bytes.Buffer, trigger *Trigger) {
+	binary.Write(buf, binary.LittleEndian, int32(trigger.Def.ID))
+	binary.Write(buf, binary.LittleEndian, uint8(trigger.Def.Type))
+	binary.Write(buf, binary.LittleEndian, trigger.On)
+	binary.Write(buf, binary.LittleEndian, trigger.Def.Enabled)
+	binary.Write(buf, binary.LittleEndian, trigger.Position)
+}
+
+// BuildTriggerStatePacket announces a trigger being set off or resetting, with the player
+// who used it and where they are now, which for a teleporter is its destination. The
+// player is nil when a trigger resets itself
+func BuildTriggerStatePacket(arenaID int, trigger *Trigger, player *ArenaPlayer) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	writeTriggerState(buf, trigger)
+	var playerID int32
+	var x, y float64
+	if player != nil {
+		playerID, x, y = int32(player.PlayerID), player.X, player.Y
+	}
+	binary.Write(buf, binary.LittleEndian, playerID)
+	binary.Write(buf, binary.LittleEndian, x)
+	binary.Write(buf, binary.LittleEndian, y)
+	return NewPacket(PacketTriggerState, buf.Bytes())
+}
+
+// BuildThinDamagePacket announces a destructible thin's health after a spell hit it, 0
+// once it is destroyed, and who cast the spell
+func BuildThinDamagePacket(arenaID int, thin *ArenaThin, casterID int) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(arenaID))
+	binary.Write(buf, binary.LittleEndian, int32(thin.Thin.ID))
+	binary.Write(buf, binary.LittleEndian, int32(thin.HitPoints))
+	binary.Write(buf, binary.LittleEndian, int32(casterID))
+	return NewPacket(PacketThinDamage, buf.Bytes())
+}
+
+// BuildLeaderboardPacket describes one page of a leaderboard
+func BuildLeaderboardPacket(page LeaderboardPage) *Packet {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint8(page.Key.Stat))
+	binary.Write(buf, binary.LittleEndian, int8(page.Key.Class))
+	binary.Write(buf, binary.LittleEndian, uint8(page.Key.Window))
+	binary.Write(buf, binary.LittleEndian, uint16(page.Page))
+	binary.Write(buf, binary.LittleEndian, uint16(page.TotalPages))
+	binary.Write(buf, binary.LittleEndian, uint8(len(page.Entries)))
+	for _, entry := range page.Entries {
+		binary.Write(buf, binary.LittleEndian, uint16(entry.Rank))
+		binary.Write(buf, binary.LittleEndian, int32(entry.PlayerID))
+		binary.Write(buf, binary.LittleEndian, uint8(entry.Class))
+		binary.Write(buf, binary.LittleEndian, uint8(entry.Level))
+		binary.Write(buf, binary.LittleEndian, entry.Value)
+		buf.WriteString(entry.Name)
+		buf.WriteByte(0) // null terminator
+	}
+	return NewPacket(PacketLeaderboard, buf.Bytes())
+}
+
 // Packet parsers
 func ParseLoginPacket(data []byte) (string, error) {
 	if len(data) == 0 {
@@ -183,3 +439,34 @@ func ParseCastSpellPacket(data []byte) (int, float64, float64, int, error) {
 	binary.Read(buf, binary.LittleEndian, &targetID)
 	return spellID, targetX, targetY, targetID, nil
 }
+
+// ParseBiasPacket reads a request to bias a shrine (kind 0) or a pool (kind 1)
+func ParseBiasPacket(data []byte) (uint8, int, error) {
+	if len(data) < 2 {
+		return 0, 0, fmt.Errorf("insufficient bias data")
+	}
+	return data[0], int(data[1]), nil
+}
+
+// ParseTriggerPacket reads the ID of a trigger a player is using
+func ParseTriggerPacket(data []byte) (int, error) {
+	if len(data) < 2 {
+		return 0, fmt.Errorf("insufficient trigger data")
+	}
+	return int(binary.LittleEndian.Uint16(data)), nil
+}
+
+func ParseLeaderboardPacket(data []byte) (LeaderboardKey, int, error) {
+	if len(data) < 5 {
+		return LeaderboardKey{}, 0, fmt.Errorf("insufficient leaderboard data")
+	}
+	var stat, window uint8
+	var class int8
+	var page uint16
+	buf := bytes.NewReader(data)
+	binary.Read(buf, binary.LittleEndian, &stat)
+	binary.Read(buf, binary.LittleEndian, &class)
+	binary.Read(buf, binary.LittleEndian, &window)
+	binary.Read(buf, binary.LittleEndian, &page)
+	return LeaderboardKey{Stat: LeaderboardStat(stat), Class: int(class), Window: LeaderboardWindow(window)}, int(page), nil
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stat is a character attribute that stat points can be spent on
+type Stat int
+
+const (
+	StatConstitution Stat = iota // raises max health
+	StatEmpathy                  // raises max power
+	StatDiscipline               // raises health and power regeneration
+)
+
+const (
+	baseStatValue      = 50 // every stat starts here; allocated points are added on top
+	maxStatValue       = 100
+	statPointsPerLevel = 5
+	baseRegenPerTick   = 1.0
+
+	arenaRegenInterval = 750 * time.Millisecond // how often arena health regenerates, as in MageServer
+	arenaRegenShare    = 0.01                   // share of max health regenerated each interval at base discipline
+)
+
+// statNames maps stat names to stats
+var statNames = map[string]Stat{
+	"constitution": StatConstitution,
+	"empathy":      StatEmpathy,
+	"discipline":   StatDiscipline,
+}
+
+// String returns the stat name
+func (s Stat) String() string {
+	for name, stat := range statNames {
+		if stat == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// ParseStat parses a stat name
+func ParseStat(name string) (Stat, error) {
+	stat, ok := statNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return StatConstitution, fmt.Errorf("unknown stat %q", name)
+	}
+	return stat, nil
+}
+
+// CharacterStats holds the stat points a character has allocated above the base value
+type CharacterStats struct {
+	Constitution int
+	Empathy      int
+	Discipline   int
+}
+
+// Value returns a stat's effective value
+func (s CharacterStats) Value(stat Stat) int {
+	switch stat {
+	case StatConstitution:
+		return baseStatValue + s.Constitution
+	case StatEmpathy:
+		return baseStatValue + s.Empathy
+	case StatDiscipline:
+		return baseStatValue + s.Discipline
+	}
+	return baseStatValue
+}
+
+// Spent returns the number of stat points allocated
+func (s CharacterStats) Spent() int {
+	return s.Constitution + s.Empathy + s.Discipline
+}
+
+// defaultLevelExp is MageServer's experience table, before its x100 scale
+var defaultLevelExp = []int{
+	0, 5, 50, 150, 300, 500, 800, 1200, 1700, 2300, 3000, 3800, 4700, 5700, 6800,
+	8000, 9300, 10700, 12200, 13800, 15500, 17300, 19200, 21200, 23300, 25500, 27800, 30200, 32700, 35300,
+}
+
+// LevelCurve holds the total experience needed for each level; entry 0 is level 1
+type LevelCurve []int
+
+// DefaultLevelCurve returns MageServer's level curve, capped at level 30
+func DefaultLevelCurve() LevelCurve {
+	curve := make(LevelCurve, len(defaultLevelExp))
+	for i, exp := range defaultLevelExp {
+		curve[i] = exp * 100
+	}
+	return curve
+}
+
+// ParseLevelCurve parses a comma separated list of experience totals, one per level starting at level 1
+func ParseLevelCurve(s string) (LevelCurve, error) {
+	var curve LevelCurve
+	for _, part := range strings.Split(s, ",") {
+		exp, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid experience value %q", part)
+		}
+		if len(curve) == 0 && exp != 0 {
+			return nil, fmt.Errorf("level 1 must need 0 experience, got %d", exp)
+		}
+		if len(curve) > 0 && exp <= curve[len(curve)-1] {
+			return nil, fmt.Errorf("level %d needs %d experience, which is not more than level %d", len(curve)+1, exp, len(curve))
+		}
+		curve = append(curve, exp)
+	}
+	return curve, nil
+}
+
+// MaxLevel returns the highest level on the curve
+func (c LevelCurve) MaxLevel() int {
+	return len(c)
+}
+
+// Required returns the total experience needed to reach a level
+func (c LevelCurve) Required(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	if level > len(c) {
+		level = len(c)
+	}
+	return c[level-1]
+}
+
+// LevelFor returns the level reached with an experience total
+func (c LevelCurve) LevelFor(experience int) int {
+	level := 1
+	for level < len(c) && experience >= c[level] {
+		level++
+	}
+	return level
+}
+
+// levelCurve and expMultiplier are the server-wide progression settings
+var (
+	levelCurve    = loadLevelCurve()
+	expMultiplier = loadExpMultiplier()
+)
+
+// loadLevelCurve reads LEVEL_CURVE, falling back to the default curve
+func loadLevelCurve() LevelCurve {
+	value := getEnv("LEVEL_CURVE", "")
+	if value == "" {
+		return DefaultLevelCurve()
+	}
+	curve, err := ParseLevelCurve(value)
+	if err != nil {
+		fmt.Printf("Invalid LEVEL_CURVE, using the default: %v\n", err)
+		return DefaultLevelCurve()
+	}
+	return curve
+}
+
+// loadExpMultiplier reads EXP_MULTIPLIER, falling back to 1
+func loadExpMultiplier() float64 {
+	multiplier, err := strconv.ParseFloat(getEnv("EXP_MULTIPLIER", "1"), 64)
+	if err != nil || multiplier < 0 {
+		fmt.Printf("Invalid EXP_MULTIPLIER %q, using 1\n", getEnv("EXP_MULTIPLIER", "1"))
+		return 1
+	}
+	return multiplier
+}
+
+// Experience rewards, following MageServer's formulas
+func damageExp(damage int) float64 { return float64(damage) }
+
+func healingExp(healed int) float64 { return math.Ceil(float64(healed) * 2.4) }
+
+func killExp(killerLevel, victimLevel int) float64 {
+	return 75 + float64(victimLevel*14) + math.Max(0, float64((killerLevel-victimLevel)*18))
+}
+
+func raiseExp(targetLevel, healed int) float64 {
+	return 25 + float64(healed) + float64(targetLevel*5)
+}
+
+func captureExp(level, teamPlayers int) float64 {
+	return float64(level) * 0.013 * float64(teamPlayers) * 50
+}
+
+func shrineExp(level, teamPlayers, bias int) float64 {
+	return float64(level) * 0.05 * float64(teamPlayers*bias)
+}
+
+func shrineDamageExp(level, teamPlayers, bias int) float64 {
+	return float64(level) * 0.07 * float64(teamPlayers*bias)
+}
+
+func poolExp(level, bias int) float64 {
+	return (float64(level)/9.2 + 0.48) * float64(bias)
+}
+
+// effectiveLevel treats an unset level as level 1
+func effectiveLevel(level int) int {
+	return max(1, level)
+}
+
+// class returns the player's class, defaulting to magician before a spellbook is loaded
+func (p *Player) class() PlayerClass {
+	if p.Spellbook == nil {
+		return ClassMagician
+	}
+	p.Spellbook.mu.RLock()
+	defer p.Spellbook.mu.RUnlock()
+	return p.Spellbook.Class
+}
+
+// progressionOf returns a player's level and max health, which the tick changes under
+// gs.mu as it credits experience, for callers outside the tick
+func progressionOf(gs *GameState, player *Player) (int, int) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return player.Level, player.MaxHealth()
+}
+
+// MaxHealth returns the player's maximum health from class, level and constitution.
+// The growth per level is MageServer's, scaled so a level 1 magician has 100 health.
+func (p *Player) MaxHealth() int {
+	level := effectiveLevel(p.Level)
+	var base int
+	switch p.class() {
+	case ClassArcanist, ClassMentalist:
+		base = 120 + (level-1)*30
+	case ClassCleric:
+		base = 140 + (level-1)*35
+	default:
+		base = 100 + (level-1)*25
+	}
+	return base + int(math.Floor(float64(base)*0.01*float64(p.Stats.Value(StatConstitution)-baseStatValue)*0.5))
+}
+
+// MaxPower returns the player's maximum power from level and empathy
+func (p *Player) MaxPower() int {
+	level := effectiveLevel(p.Level)
+	base := int(float64(level+1) * 12.5)
+	return base + int(math.Floor(float64(base)*0.01*float64(p.Stats.Value(StatEmpathy)-baseStatValue)*0.5))
+}
+
+// RegenPerTick returns the health and power regenerated each tick from discipline
+func (p *Player) RegenPerTick() float64 {
+	return baseRegenPerTick * (1 + float64(p.Stats.Value(StatDiscipline)-baseStatValue)*0.02)
+}
+
+// Regenerate restores one tick of health and power scaled by the arena's rules,
+// carrying fractions between ticks
+func (p *Player) Regenerate(scale float64) {
+	rate := p.RegenPerTick() * scale
+
+	p.healthRegen += rate
+	gained := int(p.healthRegen)
+	p.healthRegen -= float64(gained)
+	p.Health = min(p.MaxHealth(), p.Health+gained)
+
+	p.powerRegen += rate
+	gained = int(p.powerRegen)
+	p.powerRegen -= float64(gained)
+	p.Power = min(p.MaxPower(), p.Power+gained)
+}
+
+// AvailableStatPoints returns the stat points the player has not allocated
+func (p *Player) AvailableStatPoints() int {
+	return (effectiveLevel(p.Level)-1)*statPointsPerLevel - p.Stats.Spent()
+}
+
+// AllocateStat spends stat points on a stat
+func (p *Player) AllocateStat(stat Stat, points int) error {
+	if points <= 0 {
+		return fmt.Errorf("points must be positive")
+	}
+	if points > p.AvailableStatPoints() {
+		return fmt.Errorf("only %d stat points available", p.AvailableStatPoints())
+	}
+	if p.Stats.Value(stat)+points > maxStatValue {
+		return fmt.Errorf("%s cannot go above %d", stat, maxStatValue)
+	}
+
+	switch stat {
+	case StatConstitution:
+		p.Stats.Constitution += points
+	case StatEmpathy:
+		p.Stats.Empathy += points
+	case StatDiscipline:
+		p.Stats.Discipline += points
+	}
+	return nil
+}
+
+// AddExperience credits experience and levels the player up along the level curve,
+// returning the number of levels gained
+func (p *Player) AddExperience(amount int) int {
+	if amount <= 0 {
+		return 0
+	}
+	p.Experience += amount
+
+	oldLevel := effectiveLevel(p.Level)
+	newLevel := max(oldLevel, levelCurve.LevelFor(p.Experience))
+	p.Level = newLevel
+	if newLevel == oldLevel {
+		return 0
+	}
+
+	if p.Conn != nil {
+		p.Conn.Write([]byte(fmt.Sprintf("You have reached level %d! You have %d stat points to spend\n",
+			newLevel, p.AvailableStatPoints())))
+	}
+	return newLevel - oldLevel
+}
+
+// giveExpLocked scales base experience by the server multiplier and the arena's bonus
+// and holds it on the arena player until it is credited; the caller must hold a.mu
+func (a *Arena) giveExpLocked(player *ArenaPlayer, base float64) {
+	if player == nil || base <= 0 {
+		return
+	}
+	amount := int(math.Round(base * (expMultiplier + a.ExpBonus)))
+	player.PendingExp += amount
+	player.Statistics.Experience += amount
+}
+
+// GiveExp awards experience to a player in the arena
+func (a *Arena) GiveExp(playerID int, base float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.giveExpLocked(a.Players[playerID], base)
+}
+
+// takePendingExp returns and clears the experience earned by each player since the last call
+func (a *Arena) takePendingExp() map[int]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pending := make(map[int]int)
+	for id, player := range a.Players {
+		if player.PendingExp > 0 {
+			pending[id] = player.PendingExp
+			player.PendingExp = 0
+		}
+	}
+	return pending
+}
+
+// creditArenaExp moves experience earned in an arena onto the players' characters and
+// returns snapshots of those who gained a level, to be saved once the tick lets go of gs.mu.
+// It reads gs.Players directly because it runs on the tick, which holds gs.mu.
+func creditArenaExp(gs *GameState, arena *Arena) []Player {
+	var levelled []Player
+	for id, amount := range arena.takePendingExp() {
+		player, exists := gs.Players[id]
+		if !exists {
+			continue
+		}
+		if player.AddExperience(amount) > 0 {
+			arena.SetPlayerProgression(id, player.Level, player.MaxHealth())
+			levelled = append(levelled, progressionSnapshot(player))
+		}
+	}
+	return levelled
+}
+
+// progressionSnapshot copies the fields SaveProgression writes, so a character can be
+// saved without holding gs.mu
+func progressionSnapshot(player *Player) Player {
+	return Player{ID: player.ID, Level: player.Level, Experience: player.Experience, Stats: player.Stats}
+}
+
+// saveProgressions saves the progression in snapshots taken on the tick, logging failures
+func saveProgressions(snapshots []Player) {
+	for i := range snapshots {
+		if err := SaveProgression(&snapshots[i]); err != nil {
+			fmt.Printf("Failed to save progression for player %d: %v\n", snapshots[i].ID, err)
+		}
+	}
+}
+
+// regenerateArena restores a share of max health to the players in an arena every
+// arenaRegenInterval, scaled by each character's discipline as RegenPerTick is and by
+// the arena's rules, which stop it for the dead. It reads gs.Players directly because it runs on the tick, which holds gs.mu.
+func regenerateArena(gs *GameState, arena *Arena, now time.Time) {
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+
+	if now.Before(arena.nextRegen) {
+		return
+	}
+	arena.nextRegen = now.Add(arenaRegenInterval)
+	for id, player := range arena.Players {
+		character, exists := gs.Players[id]
+		scale := arena.regenScaleLocked(player)
+		if !exists || scale == 0 || player.Health >= player.maxHealth() {
+			continue
+		}
+		amount := math.Ceil(float64(player.maxHealth()) * arenaRegenShare * scale * character.RegenPerTick() / baseRegenPerTick)
+		player.Health = min(player.maxHealth(), player.Health+int(amount))
+	}
+}
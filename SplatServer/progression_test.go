@@ -0,0 +1,287 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelCurve(t *testing.T) {
+	curve := DefaultLevelCurve()
+	if curve.MaxLevel() != 30 {
+		t.Errorf("Expected max level 30, got %d", curve.MaxLevel())
+	}
+	if curve.Required(2) != 500 || curve.Required(30) != 3530000 {
+		t.Errorf("Unexpected requirements: level 2 %d, level 30 %d", curve.Required(2), curve.Required(30))
+	}
+	for exp, level := range map[int]int{0: 1, 499: 1, 500: 2, 5000: 3, 10000000: 30} {
+		if got := curve.LevelFor(exp); got != level {
+			t.Errorf("LevelFor(%d): expected %d, got %d", exp, level, got)
+		}
+	}
+
+	custom, err := ParseLevelCurve("0, 100, 300")
+	if err != nil {
+		t.Fatalf("ParseLevelCurve failed: %v", err)
+	}
+	if custom.MaxLevel() != 3 || custom.LevelFor(299) != 2 {
+		t.Errorf("Unexpected custom curve %v", custom)
+	}
+	for _, bad := range []string{"10,100", "0,100,50", "0,x"} {
+		if _, err := ParseLevelCurve(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestAddExperienceLevelsUp(t *testing.T) {
+	conn := &captureConn{}
+	player := &Player{ID: 1, Level: 1, Conn: conn}
+
+	if gained := player.AddExperience(5000); gained != 2 || player.Level != 3 {
+		t.Errorf("Expected to gain 2 levels to 3, gained %d to %d", gained, player.Level)
+	}
+	if !strings.Contains(conn.written.String(), "level 3") {
+		t.Errorf("Expected level up message, got %q", conn.written.String())
+	}
+	if player.AvailableStatPoints() != 10 {
+		t.Errorf("Expected 10 stat points, got %d", player.AvailableStatPoints())
+	}
+}
+
+func TestStatsFeedMaxHealthPowerAndRegen(t *testing.T) {
+	player := &Player{ID: 1, Level: 3}
+	if player.MaxHealth() != 150 || player.MaxPower() != 50 || player.RegenPerTick() != 1 {
+		t.Errorf("Unexpected base values: health %d, power %d, regen %.2f",
+			player.MaxHealth(), player.MaxPower(), player.RegenPerTick())
+	}
+
+	if err := player.AllocateStat(StatConstitution, 6); err != nil {
+		t.Fatalf("AllocateStat failed: %v", err)
+	}
+	if err := player.AllocateStat(StatDiscipline, 5); err == nil {
+		t.Error("Expected allocation beyond available points to fail")
+	}
+	if err := player.AllocateStat(StatDiscipline, 4); err != nil {
+		t.Fatalf("AllocateStat failed: %v", err)
+	}
+	if player.MaxHealth() != 154 {
+		t.Errorf("Expected constitution to raise max health to 154, got %d", player.MaxHealth())
+	}
+	if math.Abs(player.RegenPerTick()-1.08) > 1e-9 {
+		t.Errorf("Expected discipline to raise regeneration to 1.08, got %.2f", player.RegenPerTick())
+	}
+
+	player.Spellbook = NewSpellbook(ClassCleric)
+	player.Stats = CharacterStats{}
+	if player.MaxHealth() != 210 {
+		t.Errorf("Expected level 3 cleric max health 210, got %d", player.MaxHealth())
+	}
+}
+
+func TestCombatExperienceCredited(t *testing.T) {
+	gs := NewGameState()
+	caster := addTestCaster(gs, 1)
+	victim := &Player{ID: 2, Name: "Victim", Health: 100, Level: 1}
+	gs.AddPlayer(victim)
+
+	arena := newTestArena(1)
+	arena.ExpBonus = 0.5
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+
+	spell := &Spell{ID: 1, Name: "Test", Damage: 40}
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], spell)
+	arena.mu.Unlock()
+
+	// 40 damage: caster 40*1.8, target 40*0.7, both scaled by 1 + 0.5
+	creditArenaExp(gs, arena)
+	if caster.Experience != 108 || victim.Experience != 42 {
+		t.Errorf("Expected experience 108 and 42, got %d and %d", caster.Experience, victim.Experience)
+	}
+
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 2, Name: "Finisher", Damage: 100})
+	arena.mu.Unlock()
+	creditArenaExp(gs, arena)
+
+	// 60 damage and a level 1 kill: 108 * 1.5 + 89 * 1.5, each rounded
+	if caster.Experience != 108+162+134 {
+		t.Errorf("Expected kill experience, got %d", caster.Experience)
+	}
+	if arena.Players[1].PendingExp != 0 {
+		t.Error("Expected pending experience to be cleared")
+	}
+}
+
+func TestProgressionPersistence(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	player := &Player{ID: 7, Name: "Veteran", Health: 100, Level: 4, Experience: 15000,
+		Stats: CharacterStats{Constitution: 3, Empathy: 2, Discipline: 1}}
+	if err := SavePlayer(player); err != nil {
+		t.Fatalf("SavePlayer failed: %v", err)
+	}
+
+	loaded, err := LoadPlayer(7)
+	if err != nil {
+		t.Fatalf("LoadPlayer failed: %v", err)
+	}
+	if loaded.Level != 4 || loaded.Experience != 15000 || loaded.Stats != player.Stats {
+		t.Errorf("Unexpected loaded progression: level %d, exp %d, stats %+v",
+			loaded.Level, loaded.Experience, loaded.Stats)
+	}
+}
+
+func TestLevelSavedOnTheTick(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	player := &Player{ID: 8, Name: "Climber", Health: 100, Level: 1, LastSeen: time.Now()}
+	gs.AddPlayer(player)
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(8, TeamChaos)
+
+	arena.mu.Lock()
+	arena.Players[8].PendingExp = 5000
+	arena.mu.Unlock()
+	UpdateGameState(gs)
+
+	loaded := &Player{ID: 8}
+	if err := LoadProgression(loaded); err != nil {
+		t.Fatalf("LoadProgression failed: %v", err)
+	}
+	if loaded.Level != player.Level || loaded.Level <= 1 || loaded.Experience != 5000 {
+		t.Errorf("Expected level %d and 5000 experience saved, got level %d and %d",
+			player.Level, loaded.Level, loaded.Experience)
+	}
+}
+
+func TestAllocateCommand(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	player := addTestCaster(gs, 1)
+	player.Level = 2
+	conn := player.Conn.(*captureConn)
+
+	handleChatCommand("!allocate empathy 3", player, gs)
+	if player.Stats.Empathy != 3 || !strings.Contains(conn.written.String(), "empathy is now 53") {
+		t.Errorf("Unexpected allocation reply %q", conn.written.String())
+	}
+
+	conn.written.Reset()
+	handleChatCommand("!stats", player, gs)
+	if !strings.Contains(conn.written.String(), "2 stat points available") {
+		t.Errorf("Unexpected stats reply %q", conn.written.String())
+	}
+}
+
+func TestAllocateWhileTicking(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	player := addTestCaster(gs, 1)
+	player.Level, player.LastSeen = 3, time.Now()
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 50; i++ {
+			UpdateGameState(gs)
+		}
+		done <- true
+	}()
+	for i := 0; i < 10; i++ {
+		commandAllocate([]string{"discipline"}, player, gs)
+	}
+	<-done
+
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if player.Stats.Discipline != 10 {
+		t.Errorf("Expected every point allocated, got %d", player.Stats.Discipline)
+	}
+}
+
+func TestSpellbookCommandsWhileLevelling(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	gs.SpellSystem.SpellManager = loadTestSpellManager(t)
+	player := addTestCaster(gs, 1)
+	player.Spellbook = NewSpellbook(ClassMagician)
+	player.LastSeen = time.Now()
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 50; i++ {
+			arena.mu.Lock()
+			arena.Players[1].PendingExp += 500
+			arena.mu.Unlock()
+			UpdateGameState(gs)
+		}
+		done <- true
+	}()
+	for i := 0; i < 10; i++ {
+		commandTrain([]string{"1"}, player, gs)
+		commandLearn([]string{"1"}, player, gs)
+		commandSpellbook(nil, player, gs)
+		commandRespec(nil, player, gs)
+	}
+	<-done
+
+	if level, _ := progressionOf(gs, player); level <= 1 {
+		t.Errorf("Expected the player to level while the commands ran, got level %d", level)
+	}
+}
+
+func TestArenaRegenFromDiscipline(t *testing.T) {
+	gs := NewGameState()
+	steady := &Player{ID: 1, Name: "Steady", Level: 6, LastSeen: time.Now()}
+	disciplined := &Player{ID: 2, Name: "Disciplined", Level: 6, Stats: CharacterStats{Discipline: 25}, LastSeen: time.Now()}
+	gs.AddPlayer(steady)
+	gs.AddPlayer(disciplined)
+
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	for _, player := range []*Player{steady, disciplined} {
+		arena.AddPlayer(player.ID, TeamChaos)
+		arena.SetPlayerProgression(player.ID, player.Level, 200)
+		arena.Players[player.ID].Health = 100
+	}
+
+	// 1% of 200 max health, half as much again with 25 more discipline
+	UpdateGameState(gs)
+	if arena.GetPlayer(1).Health != 102 || arena.GetPlayer(2).Health != 103 {
+		t.Fatalf("Expected arena health 102 and 103, got %d and %d", arena.GetPlayer(1).Health, arena.GetPlayer(2).Health)
+	}
+	UpdateGameState(gs)
+	if arena.GetPlayer(1).Health != 102 {
+		t.Errorf("Expected no more regeneration within %v, got %d", arenaRegenInterval, arena.GetPlayer(1).Health)
+	}
+
+	gs.mu.Lock()
+	regenerateArena(gs, arena, time.Now().Add(arenaRegenInterval))
+	gs.mu.Unlock()
+	if arena.GetPlayer(1).Health != 104 {
+		t.Errorf("Expected another 2 health after %v, got %d", arenaRegenInterval, arena.GetPlayer(1).Health)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +26,10 @@ const (
 	MsgArenaUpdate MessageType = 9
 	MsgCastSpell   MessageType = 10
 	MsgSpellList   MessageType = 11
+	MsgStats       MessageType = 12
+	MsgLeaderboard MessageType = 13
+	MsgBias        MessageType = 14
+	MsgTrigger     MessageType = 15
 )
 
 // DebugPacketCapture represents a captured unhandled packet
@@ -112,6 +118,14 @@ func HandleMessage(msg *Message, player *Player, gs *GameState) {
 		handleCastSpell(msg, player, gs)
 	case MsgSpellList:
 		handleSpellList(msg, player, gs)
+	case MsgStats:
+		handleStats(msg, player, gs)
+	case MsgLeaderboard:
+		handleLeaderboard(msg, player, gs)
+	case MsgBias:
+		handleBias(msg, player, gs)
+	case MsgTrigger:
+		handleTrigger(msg, player, gs)
 	default:
 		handleUnknownMessage(msg, player)
 	}
@@ -132,6 +146,9 @@ func handleLogin(msg *Message, player *Player, gs *GameState) {
 		player.X = existingPlayer.X
 		player.Y = existingPlayer.Y
 		player.Health = existingPlayer.Health
+		player.Level = existingPlayer.Level
+		player.Experience = existingPlayer.Experience
+		player.Stats = existingPlayer.Stats
 		fmt.Printf("Loaded existing player %s\n", player.Name)
 	} else {
 		// New player, save initial data
@@ -140,6 +157,12 @@ func handleLogin(msg *Message, player *Player, gs *GameState) {
 		}
 	}
 
+	if book, err := LoadSpellbook(player.ID); err == nil {
+		player.Spellbook = book
+	} else {
+		player.Spellbook = NewSpellbook(ClassMagician)
+	}
+
 	fmt.Printf("Player %d logged in as %s\n", player.ID, player.Name)
 	// TODO: Authenticate, load player data, etc.
 }
@@ -166,16 +189,19 @@ func handleChat(msg *Message, player *Player, gs *GameState) {
 	}
 
 	// Check for debug commands
-	if handleDebugCommand(chatMsg, player) {
+	if handleDebugCommand(chatMsg, player, gs) {
 		return // Command was handled, don't broadcast as regular chat
 	}
+	if handleChatCommand(chatMsg, player, gs) {
+		return
+	}
 
 	fmt.Printf("Player %d said: %s\n", player.ID, chatMsg)
 	// TODO: Broadcast chat to other players
 }
 
 // handleDebugCommand processes debug commands
-func handleDebugCommand(message string, player *Player) bool {
+func handleDebugCommand(message string, player *Player, gs *GameState) bool {
 	if !strings.HasPrefix(message, "/debug") {
 		return false
 	}
@@ -201,6 +227,8 @@ func handleDebugCommand(message string, player *Player) bool {
 	case "clear":
 		debugLogger.ClearCapturedPackets()
 		player.Conn.Write([]byte("Debug packets cleared\n"))
+	case "minimap":
+		sendArenaMinimap(parts[2:], player, gs)
 	case "help":
 		sendDebugHelp(player)
 	default:
@@ -244,7 +272,7 @@ func handleJoinArena(msg *Message, player *Player, gs *GameState) {
 		return
 	}
 
-	if arena.IsFull() {
+	if !gs.Bots.MakeRoom(gs, arena) {
 		fmt.Printf("Arena %d is full\n", arenaID)
 		return
 	}
@@ -255,7 +283,17 @@ func handleJoinArena(msg *Message, player *Player, gs *GameState) {
 		return
 	}
 
+	arena.SetPlayerConn(player.ID, player.Conn)
+	level, maxHealth := progressionOf(gs, player)
+	arena.SetPlayerProgression(player.ID, level, maxHealth)
 	fmt.Printf("Player %d joined arena %d as team %d\n", player.ID, arenaID, team)
+	player.Conn.Write(BuildArenaSnapshotPacket(arena).Serialize())
+	for _, packet := range arena.ObjectivePackets() {
+		player.Conn.Write(packet.Serialize())
+	}
+	for _, packet := range arena.OrbPackets() {
+		player.Conn.Write(packet.Serialize())
+	}
 }
 
 // handleLeaveArena processes a leave arena message
@@ -272,6 +310,9 @@ func handleLeaveArena(msg *Message, player *Player, gs *GameState) {
 		return
 	}
 
+	if sheet, ok := arena.TakeMatchStatistics(player.ID); ok {
+		RecordMatchStatistics(player.ID, arena.ID, sheet)
+	}
 	arena.RemovePlayer(player.ID)
 	fmt.Printf("Player %d left arena %d\n", player.ID, arenaID)
 }
@@ -284,16 +325,34 @@ func handleArenaList(msg *Message, player *Player, gs *GameState) {
 		arenas = append(arenas, arena)
 	}
 	gs.ArenaManager.mu.RUnlock()
+	sort.Slice(arenas, func(i, j int) bool { return arenas[i].ID < arenas[j].ID })
 
 	// Send arena list to player
 	response := fmt.Sprintf("Available arenas:\n")
 	for _, arena := range arenas {
-		response += fmt.Sprintf("- %s (ID: %d, Players: %d/%d)\n",
-			arena.Name, arena.ID, arena.GetPlayerCount(), arena.MaxPlayers)
+		name := arena.Name
+		if arena.ShortName != "" && arena.ShortName != arena.Name {
+			name += " (" + arena.ShortName + ")"
+		}
+		timeLimit, expBonus := arena.GetMatchLimits()
+		response += fmt.Sprintf("- [%s] %s (ID: %d, Players: %d/%d, Grid: %d, Time limit: %s, Exp bonus: %+.0f%%)\n",
+			arena.GetRuleset().Mode.Tag(), name, arena.ID, arena.GetPlayerCount(), arena.MaxPlayers,
+			arena.GridID, describeTimeLimit(timeLimit), expBonus*100)
+		if arena.Description != "" {
+			response += fmt.Sprintf("  %s\n", arena.Description)
+		}
 	}
 	player.Conn.Write([]byte(response))
 }
 
+// describeTimeLimit formats a match time limit for the arena list
+func describeTimeLimit(limit time.Duration) string {
+	if limit <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d min", int(limit.Minutes()))
+}
+
 // handleArenaUpdate processes an arena update message
 func handleArenaUpdate(msg *Message, player *Player, gs *GameState) {
 	arenaID, x, y, err := ParseArenaUpdatePacket(msg.Data)
@@ -308,7 +367,10 @@ func handleArenaUpdate(msg *Message, player *Player, gs *GameState) {
 		return
 	}
 
-	arena.UpdatePlayerPosition(player.ID, x, y)
+	if !arena.UpdatePlayerPosition(player.ID, x, y) {
+		fmt.Printf("Player %d move to (%.2f, %.2f) in arena %d was blocked\n", player.ID, x, y, arenaID)
+		return
+	}
 	fmt.Printf("Player %d updated position in arena %d: (%.2f, %.2f)\n", player.ID, arenaID, x, y)
 }
 
@@ -320,7 +382,7 @@ func handleCastSpell(msg *Message, player *Player, gs *GameState) {
 		return
 	}
 
-	spellInstance, err := gs.SpellSystem.CastSpell(player.ID, spellID, targetX, targetY, targetID)
+	spellInstance, err := gs.CastSpell(player.ID, spellID, targetX, targetY, targetID)
 	if err != nil {
 		fmt.Printf("Failed to cast spell %d: %v\n", spellID, err)
 		return
@@ -332,14 +394,79 @@ func handleCastSpell(msg *Message, player *Player, gs *GameState) {
 
 // handleSpellList processes a spell list request
 func handleSpellList(msg *Message, player *Player, gs *GameState) {
-	spells := gs.SpellSystem.SpellManager.GetAllSpells()
+	if player.Spellbook == nil {
+		player.Conn.Write([]byte("You have no spellbook. Log in to load your character\n"))
+		return
+	}
+
+	// Send the player's spellbook and unlocked spells
+	player.Conn.Write([]byte(describeSpellbook(player, gs) + "\n"))
+}
+
+// handleStats sends the player's match and lifetime combat statistics
+func handleStats(msg *Message, player *Player, gs *GameState) {
+	player.Conn.Write([]byte(describeStatistics(player, gs) + "\n"))
+}
 
-	// Send spell list to player
-	response := "Available spells:\n"
-	for _, spell := range spells {
-		response += fmt.Sprintf("- %s (ID: %d): %s\n", spell.Name, spell.ID, spell.Description)
+// handleLeaderboard sends a page of a leaderboard. It runs on the connection's
+// goroutine, so a ranking query never holds up the game loop.
+func handleLeaderboard(msg *Message, player *Player, gs *GameState) {
+	key, page, err := ParseLeaderboardPacket(msg.Data)
+	if err != nil {
+		fmt.Printf("Failed to parse leaderboard request: %v\n", err)
+		return
+	}
+
+	result, err := gs.Leaderboards.Page(key, page)
+	if err != nil {
+		player.Conn.Write([]byte("Leaderboard is unavailable\n"))
+		return
+	}
+
+	player.Conn.Write(BuildLeaderboardPacket(result).Serialize())
+}
+
+// handleBias processes a request to bias a shrine or pool
+func handleBias(msg *Message, player *Player, gs *GameState) {
+	kind, id, err := ParseBiasPacket(msg.Data)
+	if err != nil {
+		fmt.Printf("Failed to parse bias: %v\n", err)
+		return
+	}
+
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		player.Conn.Write([]byte("You are not in an arena\n"))
+		return
+	}
+
+	if kind == biasKindPool {
+		err = arena.BiasPool(player.ID, id)
+	} else {
+		err = arena.BiasShrine(player.ID, id)
+	}
+	if err != nil {
+		player.Conn.Write([]byte(fmt.Sprintf("Cannot bias: %v\n", err)))
+	}
+}
+
+// handleTrigger processes a player using a door, switch or teleporter pad
+func handleTrigger(msg *Message, player *Player, gs *GameState) {
+	triggerID, err := ParseTriggerPacket(msg.Data)
+	if err != nil {
+		fmt.Printf("Failed to parse trigger: %v\n", err)
+		return
+	}
+
+	arena := gs.ArenaManager.FindPlayerArena(player.ID)
+	if arena == nil {
+		player.Conn.Write([]byte("You are not in an arena\n"))
+		return
+	}
+
+	if err := arena.ActivateTrigger(player.ID, triggerID); err != nil {
+		player.Conn.Write([]byte(fmt.Sprintf("Cannot use trigger: %v\n", err)))
 	}
-	player.Conn.Write([]byte(response))
 }
 
 // handleUnknownMessage captures unhandled packets for debugging
@@ -435,12 +562,49 @@ func sendDebugStats(player *Player) {
 	player.Conn.Write([]byte(response))
 }
 
+// sendArenaMinimap writes a PNG minimap of a live arena to MINIMAP_DIR, replacing the
+// arena's last one, and tells the player where it is. Only admins may write minimaps
+func sendArenaMinimap(args []string, player *Player, gs *GameState) {
+	if !isAdmin(player) {
+		player.Conn.Write([]byte("Only admins can write minimaps\n"))
+		return
+	}
+	if len(args) != 1 {
+		player.Conn.Write([]byte("Usage: /debug minimap <arena id>\n"))
+		return
+	}
+	arenaID, err := strconv.Atoi(args[0])
+	arena := gs.ArenaManager.GetArena(arenaID)
+	if err != nil || arena == nil {
+		player.Conn.Write([]byte(fmt.Sprintf("Unknown arena %s\n", args[0])))
+		return
+	}
+
+	path := filepath.Join(getEnv("MINIMAP_DIR", os.TempDir()), fmt.Sprintf("arena%02d.png", arenaID))
+	f, err := os.Create(path)
+	if err != nil {
+		player.Conn.Write([]byte(fmt.Sprintf("Failed to write minimap: %v\n", err)))
+		return
+	}
+	err = arena.RenderMinimap(f, defaultMinimapScale)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		player.Conn.Write([]byte(fmt.Sprintf("Failed to write minimap: %v\n", err)))
+		return
+	}
+	player.Conn.Write([]byte(fmt.Sprintf("Minimap of %s written to %s\n", arena.Name, path)))
+}
+
 // sendDebugHelp sends help information for debug commands
 func sendDebugHelp(player *Player) {
 	help := `Debug Commands:
 /debug packets - Show all captured unhandled packets
 /debug stats - Show statistics of captured packets by type
 /debug clear - Clear all captured packets
+/debug minimap <arena id> - Write a PNG minimap of a live arena to MINIMAP_DIR
 /debug help - Show this help message
 
 Example: /debug packets
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArenaMode is a preset match type, as in MageServer's ArenaRuleset
+type ArenaMode int
+
+const (
+	ArenaModeNormal ArenaMode = iota
+	ArenaModeTwoTeams
+	ArenaModeFreeForAll
+	ArenaModeCaptureTheFlag
+	ArenaModeDeathmatch
+	ArenaModeExpEvent
+	ArenaModeCustom
+)
+
+// arenaModeNames holds each mode's name and the short tag MageServer shows in game names
+var arenaModeNames = map[ArenaMode][2]string{
+	ArenaModeNormal:         {"normal", "N"},
+	ArenaModeTwoTeams:       {"twoteams", "2T"},
+	ArenaModeFreeForAll:     {"freeforall", "FFA"},
+	ArenaModeCaptureTheFlag: {"capturetheflag", "CTF"},
+	ArenaModeDeathmatch:     {"deathmatch", "DM"},
+	ArenaModeExpEvent:       {"expevent", "EXP"},
+	ArenaModeCustom:         {"custom", "C"},
+}
+
+// arenaModeAliases maps the names accepted by !mode to modes
+var arenaModeAliases = map[string]ArenaMode{
+	"normal": ArenaModeNormal, "norm": ArenaModeNormal, "n": ArenaModeNormal,
+	"twoteams": ArenaModeTwoTeams, "2teams": ArenaModeTwoTeams, "2t": ArenaModeTwoTeams,
+	"freeforall": ArenaModeFreeForAll, "free": ArenaModeFreeForAll, "ffa": ArenaModeFreeForAll,
+	"capturetheflag": ArenaModeCaptureTheFlag, "flag": ArenaModeCaptureTheFlag, "ctf": ArenaModeCaptureTheFlag,
+	"deathmatch": ArenaModeDeathmatch, "dm": ArenaModeDeathmatch,
+	"expevent": ArenaModeExpEvent, "exp": ArenaModeExpEvent, "event": ArenaModeExpEvent,
+	"custom": ArenaModeCustom, "c": ArenaModeCustom,
+}
+
+// String returns the mode's name
+func (m ArenaMode) String() string {
+	if names, ok := arenaModeNames[m]; ok {
+		return names[0]
+	}
+	return fmt.Sprintf("mode %d", int(m))
+}
+
+// Tag returns the mode's short tag, such as FFA
+func (m ArenaMode) Tag() string {
+	return arenaModeNames[m][1]
+}
+
+// ParseArenaMode looks up a mode by name or alias
+func ParseArenaMode(name string) (ArenaMode, error) {
+	if mode, ok := arenaModeAliases[strings.ToLower(name)]; ok {
+		return mode, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q", name)
+}
+
+// ArenaRule is a set of flags that change how a match is played, valued as in MageServer
+type ArenaRule int
+
+const (
+	ArenaRuleNone             ArenaRule = 0
+	ArenaRuleNoHinder         ArenaRule = 0x1 // hindering spells cannot be cast
+	ArenaRuleNoTapping        ArenaRule = 0x2
+	ArenaRuleNoRaiseCall      ArenaRule = 0x4 // dead players cannot be raised or call for a raise
+	ArenaRuleNoPoolBiasing    ArenaRule = 0x8
+	ArenaRuleNoShrineBiasing  ArenaRule = 0x10
+	ArenaRuleNoTeams          ArenaRule = 0x20 // every player fights alone
+	ArenaRuleNoRegen          ArenaRule = 0x40
+	ArenaRuleNoSolidWalls     ArenaRule = 0x80 // walls stop spells but not players
+	ArenaRuleTwoTeams         ArenaRule = 0x100
+	ArenaRuleFastRegen        ArenaRule = 0x200
+	ArenaRuleNoFriendlyOther  ArenaRule = 0x400 // friendly spells only work on the caster
+	ArenaRuleGuildRules       ArenaRule = 0x800
+	ArenaRuleExpEvent         ArenaRule = 0x1000
+	ArenaRuleCaptureTheFlag   ArenaRule = 0x2000
+	ArenaRuleFriendlyFire     ArenaRule = 0x4000 // damage hurts teammates too
+	ArenaRuleShrineProtection ArenaRule = 0x8000
+)
+
+// arenaRuleNames lists each rule flag in flag order with its name and the
+// short names MageServer accepts for it
+var arenaRuleNames = []struct {
+	rule    ArenaRule
+	name    string
+	aliases []string
+}{
+	{ArenaRuleNoHinder, "nohinder", []string{"nohind", "nopara", "nh"}},
+	{ArenaRuleNoTapping, "notapping", []string{"notap", "nt"}},
+	{ArenaRuleNoRaiseCall, "noraisecall", []string{"noraise", "nocall", "norez", "nr"}},
+	{ArenaRuleNoPoolBiasing, "nopoolbiasing", []string{"nopool", "np"}},
+	{ArenaRuleNoShrineBiasing, "noshrinebiasing", []string{"noshrine", "ns"}},
+	{ArenaRuleNoTeams, "noteams", []string{"noteam", "ntm"}},
+	{ArenaRuleNoRegen, "noregen", []string{"nrgn"}},
+	{ArenaRuleNoSolidWalls, "nosolidwalls", []string{"nosolid", "nsw"}},
+	{ArenaRuleTwoTeams, "twoteams", []string{"2teams", "2t"}},
+	{ArenaRuleFastRegen, "fastregen", []string{"fr"}},
+	{ArenaRuleNoFriendlyOther, "nohealother", []string{"noho"}},
+	{ArenaRuleGuildRules, "guildrules", []string{"guild", "gr"}},
+	{ArenaRuleExpEvent, "expevent", []string{"exp", "event"}},
+	{ArenaRuleCaptureTheFlag, "capturetheflag", []string{"ctf", "flag"}},
+	{ArenaRuleFriendlyFire, "friendlyfire", []string{"ff"}},
+	{ArenaRuleShrineProtection, "shrineprotection", []string{"prot", "sp"}},
+}
+
+// Has reports whether every flag in rule is set
+func (r ArenaRule) Has(rule ArenaRule) bool {
+	return r&rule == rule
+}
+
+// String lists the set flags by name
+func (r ArenaRule) String() string {
+	var names []string
+	for _, entry := range arenaRuleNames {
+		if r.Has(entry.rule) {
+			names = append(names, entry.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseArenaRule looks up a rule flag by name or alias
+func ParseArenaRule(name string) (ArenaRule, error) {
+	name = strings.ToLower(name)
+	for _, entry := range arenaRuleNames {
+		if entry.name == name {
+			return entry.rule, nil
+		}
+		for _, alias := range entry.aliases {
+			if alias == name {
+				return entry.rule, nil
+			}
+		}
+	}
+	return ArenaRuleNone, fmt.Errorf("unknown rule %q", name)
+}
+
+// Ruleset is an arena's match type and the rules it plays by
+type Ruleset struct {
+	Mode  ArenaMode
+	Rules ArenaRule
+}
+
+// NewRuleset returns the rules MageServer plays a mode with
+func NewRuleset(mode ArenaMode) Ruleset {
+	rules := ArenaRuleNone
+	switch mode {
+	case ArenaModeTwoTeams:
+		rules = ArenaRuleTwoTeams
+	case ArenaModeFreeForAll:
+		rules = ArenaRuleFastRegen | ArenaRuleNoPoolBiasing | ArenaRuleNoShrineBiasing |
+			ArenaRuleNoTeams | ArenaRuleNoRaiseCall | ArenaRuleNoFriendlyOther
+	case ArenaModeCaptureTheFlag:
+		rules = ArenaRuleCaptureTheFlag
+	case ArenaModeDeathmatch:
+		rules = ArenaRuleNoTapping | ArenaRuleNoShrineBiasing | ArenaRuleNoRaiseCall
+	case ArenaModeExpEvent:
+		rules = ArenaRuleExpEvent
+	}
+	return Ruleset{Mode: mode, Rules: rules}
+}
+
+// CustomRuleset returns a custom ruleset with the given rules
+func CustomRuleset(rules ArenaRule) Ruleset {
+	return Ruleset{Mode: ArenaModeCustom, Rules: rules}
+}
+
+// String describes the mode and rules
+func (r Ruleset) String() string {
+	return fmt.Sprintf("%s (rules: %s)", r.Mode, r.Rules)
+}
+
+// fastRegenScale is how much faster players regenerate under the FastRegen rule,
+// matching MageServer's 3% against 1% of max health
+const fastRegenScale = 3.0
+
+// regenScaleLocked returns how fast a player regenerates in this arena, 0 for not at all;
+// the caller must hold a.mu
+func (a *Arena) regenScaleLocked(player *ArenaPlayer) float64 {
+	switch {
+	case player.Dead || a.Ruleset.Rules.Has(ArenaRuleNoRegen):
+		return 0
+	case a.Ruleset.Rules.Has(ArenaRuleFastRegen):
+		return fastRegenScale
+	}
+	return 1
+}
+
+// RegenScales returns the regeneration scale of every player in an arena;
+// players who are not in one regenerate normally
+func (am *ArenaManager) RegenScales() map[int]float64 {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	scales := make(map[int]float64)
+	for _, arena := range am.Arenas {
+		arena.mu.RLock()
+		for id, player := range arena.Players {
+			scales[id] = arena.regenScaleLocked(player)
+		}
+		arena.mu.RUnlock()
+	}
+	return scales
+}
+
+// alliesLocked reports whether two players are teammates; nobody is under NoTeams.
+// The caller must hold a.mu
+func (a *Arena) alliesLocked(x, y *ArenaPlayer) bool {
+	if x == nil {
+		return false
+	}
+	return a.teamAlliesLocked(x.Team, y)
+}
+
+// teamAlliesLocked reports whether a player is on a team; nobody is under NoTeams.
+// The caller must hold a.mu
+func (a *Arena) teamAlliesLocked(team Team, y *ArenaPlayer) bool {
+	if y == nil || a.Ruleset.Rules.Has(ArenaRuleNoTeams) {
+		return false
+	}
+	return team != TeamNone && team == y.Team
+}
+
+// isHinder reports whether a spell hinders its target
+func isHinder(spell *Spell) bool {
+	if spell == nil {
+		return false
+	}
+	switch spell.EffectType {
+	case SpellEffectHinder, SpellEffectSlow, SpellEffectStun:
+		return true
+	}
+	return false
+}
+
+// AllowsSpell checks a spell, and any spell it applies, against the arena's rules
+func (a *Arena) AllowsSpell(spell *Spell, applied ...*Spell) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.Ruleset.Rules.Has(ArenaRuleNoHinder) {
+		for _, s := range append([]*Spell{spell}, applied...) {
+			if isHinder(s) {
+				return fmt.Errorf("%s is not allowed in %s: hindering spells are disabled", spell.Name, a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// teamAllowedLocked reports whether players may join a team under the arena's rules;
+// the caller must hold a.mu
+func (a *Arena) teamAllowedLocked(team Team) bool {
+	return !a.Ruleset.Rules.Has(ArenaRuleTwoTeams) || team != a.DisabledTeam
+}
+
+// SetRuleset changes the arena's ruleset before its match starts, moving players
+// off teams the new rules do not use
+func (a *Arena) SetRuleset(ruleset Ruleset) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.State != ArenaStateWaiting && a.State != ArenaStateCountdown {
+		return fmt.Errorf("the rules of %s cannot change once the match has started", a.Name)
+	}
+	a.applyRulesetLocked(ruleset)
+	return nil
+}
+
+// applyRulesetLocked sets the ruleset and arranges the teams for it: everyone is
+// unaligned under NoTeams, under TwoTeams the least populated team sits out, and
+// unaligned players join the smallest team playing. The caller must hold a.mu
+func (a *Arena) applyRulesetLocked(ruleset Ruleset) {
+	a.Ruleset = ruleset
+	a.DisabledTeam = TeamNone
+
+	switch {
+	case ruleset.Rules.Has(ArenaRuleNoTeams):
+		for _, player := range a.Players {
+			a.setTeamLocked(player, TeamNone)
+		}
+	case ruleset.Rules.Has(ArenaRuleTwoTeams):
+		counts := a.teamCountsLocked()
+		a.DisabledTeam = TeamBalance
+		for _, team := range []Team{TeamChaos, TeamOrder} {
+			if counts[team] < counts[a.DisabledTeam] {
+				a.DisabledTeam = team
+			}
+		}
+		for _, player := range a.Players {
+			if player.Team == a.DisabledTeam || player.Team == TeamNone {
+				a.setTeamLocked(player, a.smallestTeamLocked())
+			}
+		}
+	default:
+		for _, player := range a.Players {
+			if player.Team == TeamNone {
+				a.setTeamLocked(player, a.smallestTeamLocked())
+			}
+		}
+	}
+}
+
+// smallestTeamLocked returns the team playing in the arena with the fewest players;
+// the caller must hold a.mu
+func (a *Arena) smallestTeamLocked() Team {
+	counts := a.teamCountsLocked()
+	smallest := TeamNone
+	for _, team := range []Team{TeamChaos, TeamBalance, TeamOrder} {
+		if !a.teamAllowedLocked(team) {
+			continue
+		}
+		if smallest == TeamNone || counts[team] < counts[smallest] {
+			smallest = team
+		}
+	}
+	return smallest
+}
+
+// teamCountsLocked counts the players on each team; the caller must hold a.mu
+func (a *Arena) teamCountsLocked() map[Team]int {
+	counts := make(map[Team]int)
+	for _, player := range a.Players {
+		counts[player.Team]++
+	}
+	return counts
+}
+
+// setTeamLocked moves a player to a team and its start position; the caller must hold a.mu
+func (a *Arena) setTeamLocked(player *ArenaPlayer, team Team) {
+	if player.Team == team {
+		return
+	}
+	player.Team = team
+	if spawn, ok := a.spawnLocked(team); ok {
+		a.movePlayerLocked(player, spawn.Start.X, spawn.Start.Y)
+		player.Angle = spawn.Start.Angle
+	}
+}
+
+// GetRuleset returns the arena's ruleset
+func (a *Arena) GetRuleset() Ruleset {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Ruleset
+}
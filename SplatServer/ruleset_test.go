@@ -0,0 +1,174 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRulesetModesAndParsing(t *testing.T) {
+	ffa := NewRuleset(ArenaModeFreeForAll)
+	if !ffa.Rules.Has(ArenaRuleNoTeams|ArenaRuleFastRegen|ArenaRuleNoRaiseCall) || ffa.Rules.Has(ArenaRuleTwoTeams) {
+		t.Errorf("Unexpected free-for-all rules %s", ffa.Rules)
+	}
+	if ffa.Mode.Tag() != "FFA" {
+		t.Errorf("Expected FFA tag, got %s", ffa.Mode.Tag())
+	}
+
+	if mode, err := ParseArenaMode("DM"); err != nil || mode != ArenaModeDeathmatch {
+		t.Errorf("Expected deathmatch, got %v, %v", mode, err)
+	}
+	if rule, err := ParseArenaRule("ff"); err != nil || rule != ArenaRuleFriendlyFire {
+		t.Errorf("Expected friendly fire, got %v, %v", rule, err)
+	}
+	if _, err := ParseArenaRule("nothing"); err == nil {
+		t.Error("Expected an unknown rule to be rejected")
+	}
+	if got := (ArenaRuleNoHinder | ArenaRuleNoRegen).String(); got != "nohinder, noregen" {
+		t.Errorf("Unexpected rule names %q", got)
+	}
+}
+
+func TestTeamAssignmentRules(t *testing.T) {
+	am := NewArenaManager()
+
+	ffa := am.CreateArenaWithRuleset(1, "Melee", 8, 1, NewRuleset(ArenaModeFreeForAll))
+	ffa.AddPlayer(1, TeamChaos)
+	ffa.AddPlayer(2, TeamChaos)
+	if ffa.GetPlayer(1).Team != TeamNone {
+		t.Errorf("Expected free-for-all players to join without a team, got %s", ffa.GetPlayer(1).Team)
+	}
+
+	// Without teams everyone is an enemy and nobody can heal anyone else
+	ffa.mu.Lock()
+	ffa.applySpellLocked(1, ffa.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 30})
+	ffa.applySpellLocked(1, ffa.Players[2], &Spell{ID: 2, Name: "Heal", Healing: 10})
+	ffa.mu.Unlock()
+	if ffa.GetPlayer(2).Health != 70 {
+		t.Errorf("Expected former teammates to fight, got health %d", ffa.GetPlayer(2).Health)
+	}
+
+	// Switching back to a team mode puts the unaligned players on the smallest teams
+	if err := ffa.SetRuleset(NewRuleset(ArenaModeNormal)); err != nil {
+		t.Fatalf("SetRuleset failed: %v", err)
+	}
+	first, second := ffa.GetPlayer(1).Team, ffa.GetPlayer(2).Team
+	if first == TeamNone || second == TeamNone || first == second {
+		t.Errorf("Expected the players to join different teams, got %s and %s", first, second)
+	}
+
+	twoTeams := am.CreateArenaWithRuleset(2, "Duel", 8, 1, NewRuleset(ArenaModeTwoTeams))
+	if twoTeams.DisabledTeam != TeamBalance {
+		t.Errorf("Expected Balance to sit out, got %s", twoTeams.DisabledTeam)
+	}
+	if err := twoTeams.AddPlayer(1, TeamBalance); err == nil {
+		t.Error("Expected joining the team that sits out to fail")
+	}
+
+	// Switching to two teams moves players off the team that sits out
+	normal := am.CreateArena(3, "Normal", 8, 1)
+	normal.AddPlayer(1, TeamChaos)
+	normal.AddPlayer(2, TeamBalance)
+	normal.AddPlayer(3, TeamBalance)
+	normal.AddPlayer(4, TeamOrder)
+	normal.AddPlayer(5, TeamOrder)
+	if err := normal.SetRuleset(NewRuleset(ArenaModeTwoTeams)); err != nil {
+		t.Fatalf("SetRuleset failed: %v", err)
+	}
+	if normal.DisabledTeam != TeamChaos || normal.GetPlayer(1).Team == TeamChaos {
+		t.Errorf("Expected Chaos to sit out and its player to move, got %s and %s",
+			normal.DisabledTeam, normal.GetPlayer(1).Team)
+	}
+
+	normal.StartArena()
+	if err := normal.SetRuleset(NewRuleset(ArenaModeNormal)); err == nil {
+		t.Error("Expected the rules to be fixed once the match starts")
+	}
+}
+
+func TestFriendlyFireRule(t *testing.T) {
+	arena := newTestArena(1)
+	arena.Ruleset = CustomRuleset(ArenaRuleFriendlyFire)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamChaos)
+
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 30})
+	arena.applySpellLocked(1, arena.Players[1], &Spell{ID: 1, Name: "Hit", Damage: 30})
+	arena.mu.Unlock()
+	if arena.GetPlayer(2).Health != 70 || arena.GetPlayer(1).Health != 100 {
+		t.Errorf("Expected friendly fire to hurt teammates but not the caster, got %d and %d",
+			arena.GetPlayer(2).Health, arena.GetPlayer(1).Health)
+	}
+}
+
+func TestRegenRules(t *testing.T) {
+	gs := NewGameState()
+	slow := &Player{ID: 1, Name: "Slow", Health: 50, LastSeen: time.Now()}
+	fast := &Player{ID: 2, Name: "Fast", Health: 50, LastSeen: time.Now()}
+	gs.AddPlayer(slow)
+	gs.AddPlayer(fast)
+
+	noRegen := gs.ArenaManager.CreateArenaWithRuleset(1, "No Regen", 8, 1, CustomRuleset(ArenaRuleNoRegen))
+	fastRegen := gs.ArenaManager.CreateArenaWithRuleset(2, "Fast Regen", 8, 1, CustomRuleset(ArenaRuleFastRegen))
+	noRegen.AddPlayer(1, TeamChaos)
+	fastRegen.AddPlayer(2, TeamChaos)
+	noRegen.Players[1].Health, fastRegen.Players[2].Health = 50, 50
+
+	UpdateGameState(gs)
+	if slow.Health != 50 || fast.Health != 53 {
+		t.Errorf("Expected health 50 and 53, got %d and %d", slow.Health, fast.Health)
+	}
+	if noRegen.GetPlayer(1).Health != 50 || fastRegen.GetPlayer(2).Health != 53 {
+		t.Errorf("Expected arena health 50 and 53, got %d and %d", noRegen.GetPlayer(1).Health, fastRegen.GetPlayer(2).Health)
+	}
+}
+
+func TestNoHinderRule(t *testing.T) {
+	gs := NewGameState()
+	hinder := &Spell{ID: 400, Name: "Hinder", Type: SpellTypeTarget, FriendlyType: SpellFriendlyEnemy, TargetSpellID: 401}
+	effect := &Spell{ID: 401, Name: "Hinder Effect", Type: SpellTypeEffect, EffectType: SpellEffectHinder}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{hinder, effect})
+	addTestCaster(gs, 1, 400)
+
+	arena := gs.ArenaManager.CreateArenaWithRuleset(1, "Test Arena", 8, 1, CustomRuleset(ArenaRuleNoHinder))
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+
+	if _, err := gs.CastSpell(1, 400, 0, 0, 2); err == nil || !strings.Contains(err.Error(), "hindering") {
+		t.Errorf("Expected the hinder to be refused, got %v", err)
+	}
+}
+
+func TestRulesCommands(t *testing.T) {
+	gs := NewGameState()
+	player := addTestCaster(gs, 1)
+	conn := player.Conn.(*captureConn)
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+
+	handleChatCommand("!mode ffa", player, gs)
+	if arena.GetRuleset().Mode != ArenaModeNormal || !strings.Contains(conn.written.String(), "Only admins") {
+		t.Errorf("Expected a non-admin to be refused, got %s and %q", arena.GetRuleset(), conn.written.String())
+	}
+	conn.written.Reset()
+	handleChatCommand("!rules", player, gs)
+	if !strings.Contains(conn.written.String(), "Test Arena is playing") {
+		t.Errorf("Expected anyone to see the rules, got %q", conn.written.String())
+	}
+
+	player.Admin = true
+	handleChatCommand("!mode ffa", player, gs)
+	if arena.GetRuleset().Mode != ArenaModeFreeForAll || arena.GetPlayer(1).Team != TeamNone {
+		t.Errorf("Expected a free-for-all arena, got %s", arena.GetRuleset())
+	}
+
+	conn.written.Reset()
+	handleChatCommand("!rules nh ff", player, gs)
+	if got := arena.GetRuleset(); got.Mode != ArenaModeCustom || got.Rules != ArenaRuleNoHinder|ArenaRuleFriendlyFire {
+		t.Errorf("Expected custom rules, got %s", got)
+	}
+	if !strings.Contains(conn.written.String(), "nohinder, friendlyfire") {
+		t.Errorf("Unexpected rules reply %q", conn.written.String())
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	runeArmingDelay   = 1 * time.Second // time before a placed rune can trigger
+	maxRunesPerCaster = 3               // oldest rune is removed when a caster places more
+	minRuneRadius     = 8.0
+	defaultRuneLife   = 60 * time.Second
+	runeDispelRadius  = 64.0
+)
+
+// Rune represents a sign placed in an arena that fires a spell at the
+// first enemy to step on it
+type Rune struct {
+	ID        int64
+	SpellID   int
+	OwnerID   int
+	Team      Team
+	X, Y      float64
+	Radius    float64 // trigger radius
+	Blast     float64 // area hit when triggered, 0 for just the triggering player
+	Trigger   *Spell  // spell applied to the player who triggers the rune
+	CreatedAt time.Time
+	ArmedAt   time.Time
+	ExpiresAt time.Time
+}
+
+// NewRune places a rune in front of a caster facing angle
+func NewRune(spell, trigger *Spell, ownerID int, team Team, x, y, angle float64) *Rune {
+	now := time.Now()
+	duration := spell.Duration
+	if duration <= 0 {
+		duration = defaultRuneLife
+	}
+
+	return &Rune{
+		ID:        generateSpellID(),
+		SpellID:   spell.ID,
+		OwnerID:   ownerID,
+		Team:      team,
+		X:         x + spell.CastDistance*math.Cos(angle),
+		Y:         y + spell.CastDistance*math.Sin(angle),
+		Radius:    math.Max(spell.Width/2, minRuneRadius),
+		Blast:     spell.Radius,
+		Trigger:   trigger,
+		CreatedAt: now,
+		ArmedAt:   now.Add(runeArmingDelay),
+		ExpiresAt: now.Add(duration),
+	}
+}
+
+// IsArmed checks if the rune's arming delay has passed
+func (r *Rune) IsArmed(now time.Time) bool {
+	return !now.Before(r.ArmedAt)
+}
+
+// IsExpired checks if the rune's lifetime has run out
+func (r *Rune) IsExpired(now time.Time) bool {
+	return now.After(r.ExpiresAt)
+}
+
+// triggeredBy checks if a player would set the rune off
+func (r *Rune) triggeredBy(player *ArenaPlayer) bool {
+	if player.PlayerID == r.OwnerID || player.Dead {
+		return false
+	}
+	if r.Team != TeamNone && player.Team == r.Team {
+		return false
+	}
+	return math.Hypot(player.X-r.X, player.Y-r.Y) <= r.Radius+playerRadius
+}
+
+// AddRune places a rune in the arena, removing the caster's oldest rune
+// once they have reached the cap
+func (a *Arena) AddRune(r *Rune) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Runes == nil {
+		a.Runes = make(map[int64]*Rune)
+	}
+
+	var owned []*Rune
+	for _, existing := range a.Runes {
+		if existing.OwnerID == r.OwnerID {
+			owned = append(owned, existing)
+		}
+	}
+	for len(owned) >= maxRunesPerCaster {
+		oldest := 0
+		for i := range owned {
+			if owned[i].CreatedAt.Before(owned[oldest].CreatedAt) {
+				oldest = i
+			}
+		}
+		a.removeRuneLocked(owned[oldest].ID)
+		owned = append(owned[:oldest], owned[oldest+1:]...)
+	}
+
+	a.Runes[r.ID] = r
+	a.spatialLocked().Update(EntityKey{EntityRune, r.ID}, r.X, r.Y, r.Radius)
+}
+
+// RemoveRune removes a rune from the arena
+func (a *Arena) RemoveRune(id int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.removeRuneLocked(id)
+}
+
+// removeRuneLocked removes a rune from the arena and its spatial index; the caller must
+// hold a.mu
+func (a *Arena) removeRuneLocked(id int64) {
+	delete(a.Runes, id)
+	a.spatialLocked().Remove(EntityKey{EntityRune, id})
+}
+
+// GetRunes returns the runes currently placed in the arena
+func (a *Arena) GetRunes() []*Rune {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	runes := make([]*Rune, 0, len(a.Runes))
+	for _, r := range a.Runes {
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// DispelRunes removes every rune within radius of a point and returns how many were removed
+func (a *Arena) DispelRunes(x, y, radius float64) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var dispelled []int64
+	a.spatialLocked().QueryRadius(EntityRune, x, y, radius, func(key EntityKey) {
+		if r, exists := a.Runes[key.ID]; exists && math.Hypot(r.X-x, r.Y-y) <= radius+r.Radius {
+			dispelled = append(dispelled, r.ID)
+		}
+	})
+	for _, id := range dispelled {
+		a.removeRuneLocked(id)
+	}
+	return len(dispelled)
+}
+
+// updateRunesLocked expires runes and fires any that an enemy is standing on;
+// the caller must hold a.mu
+func (a *Arena) updateRunesLocked(now time.Time) {
+	for id, r := range a.Runes {
+		if r.IsExpired(now) {
+			a.removeRuneLocked(id)
+			continue
+		}
+		if !r.IsArmed(now) {
+			continue
+		}
+
+		for _, player := range a.playersNearLocked(r.X, r.Y, r.Radius+playerRadius) {
+			if !r.triggeredBy(player) {
+				continue
+			}
+			a.fireRuneLocked(r, player)
+			a.removeRuneLocked(id)
+			break
+		}
+	}
+}
+
+// fireRuneLocked applies a rune's trigger spell to the player who set it off
+// and anyone else caught in its blast; the caller must hold a.mu
+func (a *Arena) fireRuneLocked(r *Rune, triggeredBy *ArenaPlayer) {
+	if r.Trigger == nil {
+		return
+	}
+
+	a.applyTeamSpellLocked(r.OwnerID, r.Team, triggeredBy, r.Trigger)
+	if r.Blast <= 0 {
+		return
+	}
+	for _, player := range a.playersNearLocked(r.X, r.Y, r.Blast) {
+		if player == triggeredBy || player.PlayerID == r.OwnerID {
+			continue
+		}
+		if math.Hypot(player.X-r.X, player.Y-r.Y) <= r.Blast {
+			a.applyTeamSpellLocked(r.OwnerID, r.Team, player, r.Trigger)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func testRuneSpell() *Spell {
+	return &Spell{
+		ID:           200,
+		Name:         "Test Rune",
+		Type:         SpellTypeRune,
+		Damage:       30,
+		Width:        16,
+		CastDistance: 32,
+		Duration:     time.Minute,
+	}
+}
+
+func armedRune(spell *Spell, ownerID int, team Team, x, y float64) *Rune {
+	r := NewRune(spell, spell, ownerID, team, x, y, 0)
+	r.ArmedAt = time.Now().Add(-time.Second)
+	return r
+}
+
+func TestRuneTriggersOnEnemy(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamChaos)
+	arena.UpdatePlayerPosition(2, 500, 500)
+	arena.UpdatePlayerPosition(3, 500, 500)
+
+	// Rune sits 32 units in front of the owner at (32, 0)
+	arena.AddRune(armedRune(testRuneSpell(), 1, TeamChaos, 0, 0))
+
+	arena.UpdatePlayerPosition(3, 32, 0)
+	UpdateArena(arena)
+	if len(arena.GetRunes()) != 1 {
+		t.Fatal("Expected a teammate not to trigger the rune")
+	}
+
+	arena.UpdatePlayerPosition(2, 40, 0)
+	UpdateArena(arena)
+	if len(arena.GetRunes()) != 0 {
+		t.Error("Expected the rune to be consumed by an enemy")
+	}
+	if p := arena.GetPlayer(2); p.Health != 70 {
+		t.Errorf("Expected enemy health 70, got %d", p.Health)
+	}
+}
+
+func TestRuneBlastSparesOwnersTeamAfterTheyLeave(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamChaos)
+	arena.UpdatePlayerPosition(2, 500, 500)
+	arena.UpdatePlayerPosition(3, 60, 0)
+
+	spell := testRuneSpell()
+	spell.Radius = 100
+	arena.AddRune(armedRune(spell, 1, TeamChaos, 0, 0))
+	arena.RemovePlayer(1)
+
+	arena.UpdatePlayerPosition(2, 40, 0)
+	UpdateArena(arena)
+	if p := arena.GetPlayer(2); p.Health != 70 {
+		t.Errorf("Expected enemy health 70, got %d", p.Health)
+	}
+	if p := arena.GetPlayer(3); p.Health != 100 {
+		t.Errorf("Expected the owner's teammate to be spared by the blast, got health %d", p.Health)
+	}
+}
+
+func TestRuneArmingDelay(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 32, 0)
+
+	spell := testRuneSpell()
+	arena.AddRune(NewRune(spell, spell, 1, TeamChaos, 0, 0, 0))
+	UpdateArena(arena)
+
+	if len(arena.GetRunes()) != 1 {
+		t.Error("Expected an unarmed rune not to trigger")
+	}
+}
+
+func TestRuneCapPerCaster(t *testing.T) {
+	arena := newTestArena(1)
+	spell := testRuneSpell()
+
+	var first *Rune
+	for i := 0; i <= maxRunesPerCaster; i++ {
+		r := NewRune(spell, spell, 1, TeamChaos, float64(i*100), 0, 0)
+		r.CreatedAt = time.Now().Add(time.Duration(i) * time.Millisecond)
+		if first == nil {
+			first = r
+		}
+		arena.AddRune(r)
+	}
+	arena.AddRune(NewRune(spell, spell, 2, TeamOrder, 0, 0, 0))
+
+	runes := arena.GetRunes()
+	if len(runes) != maxRunesPerCaster+1 {
+		t.Fatalf("Expected %d runes, got %d", maxRunesPerCaster+1, len(runes))
+	}
+	for _, r := range runes {
+		if r.ID == first.ID {
+			t.Error("Expected the oldest rune to be removed")
+		}
+	}
+}
+
+func TestRuneExpiryAndDispel(t *testing.T) {
+	arena := newTestArena(1)
+	spell := testRuneSpell()
+
+	expired := NewRune(spell, spell, 1, TeamChaos, 0, 0, 0)
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	arena.AddRune(expired)
+	arena.AddRune(NewRune(spell, spell, 2, TeamOrder, 1000, 1000, 0))
+	arena.AddRune(NewRune(spell, spell, 3, TeamOrder, 2000, 2000, 0))
+
+	UpdateArena(arena)
+	if len(arena.GetRunes()) != 2 {
+		t.Fatalf("Expected expired rune to be removed, got %d runes", len(arena.GetRunes()))
+	}
+
+	if n := arena.DispelRunes(1032, 1000, runeDispelRadius); n != 1 {
+		t.Errorf("Expected 1 rune dispelled, got %d", n)
+	}
+	if len(arena.GetRunes()) != 1 {
+		t.Errorf("Expected 1 rune left, got %d", len(arena.GetRunes()))
+	}
+}
+
+func TestCastRuneAndDispel(t *testing.T) {
+	gs := NewGameState()
+	dispel := &Spell{ID: 201, Name: "Test Dispel", Type: SpellTypeDispell, Range: 400}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{testRuneSpell(), dispel})
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	addTestCaster(gs, 1, 200)
+	addTestCaster(gs, 2, 201)
+
+	if _, err := gs.CastSpell(1, 200, 100, 0, 0); err != nil {
+		t.Fatalf("Failed to cast rune: %v", err)
+	}
+	runes := arena.GetRunes()
+	if len(runes) != 1 || runes[0].X != 32 {
+		t.Fatalf("Expected one rune at x=32, got %+v", runes)
+	}
+
+	if _, err := gs.CastSpell(2, 201, 32, 0, 0); err != nil {
+		t.Fatalf("Failed to cast dispel: %v", err)
+	}
+	if len(arena.GetRunes()) != 0 {
+		t.Error("Expected the dispel to clear the rune")
+	}
+}
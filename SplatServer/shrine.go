@@ -0,0 +1,441 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	objectiveRadius      = 96.0        // distance at which a player stands at a shrine or pool
+	objectiveInterval    = time.Second // how often standing players bias and guild points accrue
+	standingBias         = 5           // bias each player standing at a shrine or pool applies per interval
+	castBiasBase         = 20          // bias a player applies when they bias a shrine or pool directly
+	biasCooldown         = time.Second // how often a player can bias a shrine or pool directly
+	orbCaptureShrineBias = 20          // shrine bias lost when the team's orb is captured
+	guildPointsPerSecond = 1.0         // guild points each living shrine earns per interval
+	indestructibleShrine = -1          // shrine power that cannot be biased or damaged
+	defaultObjectiveBias = 100
+)
+
+// How often a guild rules match announces its guild points: every ten minutes, then
+// every two once fewer than ten are left
+const (
+	guildStandingsEvery = 10 * time.Minute
+	guildStandingsLate  = 2 * time.Minute
+)
+
+// Bias request kinds in MsgBias
+const (
+	biasKindShrine = 0
+	biasKindPool   = 1
+)
+
+// Shrine is a team's shrine, as in MageServer's Shrine. A shrine with no bias left is dead
+type Shrine struct {
+	ID          int
+	Team        Team
+	Power       int // 0 disables the shrine, -1 makes it indestructible
+	MaxBias     int
+	Bias        int
+	Disabled    bool
+	GuildPoints float64
+	X, Y        float64
+	HasPosition bool // placed from the grid's shrine blocks rather than the team's start point
+}
+
+// IsDead reports whether the shrine has no bias left or is out of play
+func (s *Shrine) IsDead() bool {
+	return s.Bias <= 0 || s.Disabled
+}
+
+// IsIndestructible reports whether the shrine is out of reach of biasing and damage
+func (s *Shrine) IsIndestructible() bool {
+	return s.Power == indestructibleShrine
+}
+
+// Pool is an earthblood pool that teams bias toward themselves, as in MageServer's Pool.
+// Pools start neutral; positions come from the grid and pools without one can only be
+// biased directly
+type Pool struct {
+	ID          int
+	Team        Team // TeamNone while neutral
+	Power       int
+	MaxBias     int
+	Bias        int
+	X, Y        float64
+	HasPosition bool
+}
+
+// IsFullyBiased reports whether a team holds the pool completely
+func (p *Pool) IsFullyBiased() bool {
+	return p.Team != TeamNone && p.Bias >= p.MaxBias
+}
+
+// clampBias limits a bias to 0..max
+func clampBias(bias, max int) int {
+	return int(math.Max(0, math.Min(float64(bias), float64(max))))
+}
+
+// castBias returns the bias a player applies by biasing a shrine or pool directly
+func castBias(level int) int {
+	return castBiasBase + effectiveLevel(level)/2
+}
+
+// placeObjectivesLocked sets up the shrines and pools from the arena's World.dat for a new
+// match; the caller must hold a.mu. Shrines sit on their blocks in Grid.dat, or at their
+// team's start point when the grid does not mark them. Shrines with no power, of a team sitting out, or in an arena
+// without teams are disabled
+func (a *Arena) placeObjectivesLocked() {
+	a.Shrines = make(map[Team]*Shrine)
+	a.Pools = nil
+	a.nextObjectiveTick, a.nextGuildStandings = time.Time{}, time.Time{}
+	if a.World == nil {
+		return
+	}
+
+	for _, def := range a.World.Shrines {
+		shrine := def
+		shrine.GuildPoints = 0
+		shrine.Disabled = shrine.Power == 0 || !a.teamAllowedLocked(shrine.Team) || a.Ruleset.Rules.Has(ArenaRuleNoTeams)
+		if shrine.Disabled {
+			shrine.Bias = 0
+		}
+		if spawn, ok := a.spawnLocked(shrine.Team); ok && !shrine.HasPosition {
+			shrine.X, shrine.Y = spawn.Start.X, spawn.Start.Y
+		}
+		a.Shrines[shrine.Team] = &shrine
+	}
+	for _, def := range a.World.Pools {
+		pool := def
+		pool.Team, pool.Bias = TeamNone, 0
+		a.Pools = append(a.Pools, &pool)
+	}
+}
+
+// shrineAliveLocked reports whether a team's shrine is alive, treating arenas without
+// shrines as alive; the caller must hold a.mu
+func (a *Arena) shrineAliveLocked(team Team) bool {
+	shrine, exists := a.Shrines[team]
+	return !exists || !shrine.IsDead()
+}
+
+// updateObjectivesLocked applies the bias of players standing at shrines and pools and
+// awards guild points, once per objective interval; the caller must hold a.mu
+func (a *Arena) updateObjectivesLocked(now time.Time) {
+	if now.Before(a.nextObjectiveTick) {
+		return
+	}
+	a.nextObjectiveTick = now.Add(objectiveInterval)
+
+	for _, player := range a.Players {
+		if player.Dead || player.Team == TeamNone {
+			continue
+		}
+		for _, shrine := range a.Shrines {
+			if math.Hypot(player.X-shrine.X, player.Y-shrine.Y) <= objectiveRadius {
+				a.biasShrineLocked(player, shrine, standingBias)
+			}
+		}
+		for _, pool := range a.Pools {
+			if pool.HasPosition && math.Hypot(player.X-pool.X, player.Y-pool.Y) <= objectiveRadius {
+				a.biasPoolLocked(player, pool, standingBias)
+			}
+		}
+	}
+
+	a.awardGuildPointsLocked()
+}
+
+// awardGuildPointsLocked gives each living shrine its guild points for one interval: a
+// base amount plus a hundredth of the power of every pool its team fully holds. The
+// caller must hold a.mu
+func (a *Arena) awardGuildPointsLocked() {
+	for team, shrine := range a.Shrines {
+		if shrine.IsDead() {
+			continue
+		}
+		points := guildPointsPerSecond
+		for _, pool := range a.Pools {
+			if pool.Team == team && pool.IsFullyBiased() {
+				points += float64(pool.Power) / 100
+			}
+		}
+		shrine.GuildPoints += points
+	}
+}
+
+// announceGuildStandingsLocked tells a guild rules match each team's guild points and who
+// leads on them, as MageServer's guild match broadcasts do; the caller must hold a.mu
+func (a *Arena) announceGuildStandingsLocked(now time.Time) {
+	if !a.Ruleset.Rules.Has(ArenaRuleGuildRules) {
+		return
+	}
+	interval := guildStandingsEvery
+	if a.TimeLimit > 0 && a.StartTime.Add(a.TimeLimit).Sub(now) < guildStandingsEvery {
+		interval = guildStandingsLate
+	}
+	if a.nextGuildStandings.IsZero() {
+		a.nextGuildStandings = a.StartTime.Add(interval)
+	}
+	if now.Before(a.nextGuildStandings) {
+		return
+	}
+	a.nextGuildStandings = now.Add(interval)
+
+	counts := a.teamCountsLocked()
+	points := make(map[Team]float64)
+	for _, team := range []Team{TeamChaos, TeamOrder, TeamBalance} {
+		shrine, exists := a.Shrines[team]
+		if !exists || (counts[team] == 0 && shrine.IsDead()) {
+			continue
+		}
+		points[team] = shrine.GuildPoints
+		a.broadcastLocked(BuildChatPacket(fmt.Sprintf("[Guild Match] %s: %.2f", team, shrine.GuildPoints)))
+	}
+	leader := "None"
+	if team := bestTeam(points); team != TeamNone {
+		leader = team.String()
+	}
+	a.broadcastLocked(BuildChatPacket("[Guild Match] Winning Team: " + leader))
+}
+
+// shrineBiasAllowedLocked returns why a shrine cannot be biased, or nil; the caller must hold a.mu
+func (a *Arena) shrineBiasAllowedLocked(shrine *Shrine) error {
+	switch {
+	case a.Ruleset.Rules.Has(ArenaRuleNoShrineBiasing), a.Ruleset.Rules.Has(ArenaRuleCaptureTheFlag):
+		return fmt.Errorf("shrines cannot be biased in this arena")
+	case shrine.Disabled:
+		return fmt.Errorf("the %s shrine is not in play", shrine.Team)
+	case shrine.IsIndestructible():
+		return fmt.Errorf("the %s shrine cannot be biased", shrine.Team)
+	}
+	return nil
+}
+
+// shrineProtectedLocked reports whether a living defender stands at their shrine, which
+// keeps enemies from damaging it under the ShrineProtection rule; the caller must hold a.mu
+func (a *Arena) shrineProtectedLocked(shrine *Shrine) bool {
+	if !a.Ruleset.Rules.Has(ArenaRuleShrineProtection) {
+		return false
+	}
+	for _, player := range a.Players {
+		if !player.Dead && player.Team == shrine.Team && math.Hypot(player.X-shrine.X, player.Y-shrine.Y) <= objectiveRadius {
+			return true
+		}
+	}
+	return false
+}
+
+// biasShrineLocked applies a player's bias to a shrine: their own shrine is restored and
+// an enemy shrine is damaged, with objective experience either way. It reports whether
+// the bias changed; the caller must hold a.mu
+func (a *Arena) biasShrineLocked(player *ArenaPlayer, shrine *Shrine, amount int) bool {
+	if a.shrineBiasAllowedLocked(shrine) != nil {
+		return false
+	}
+
+	level := effectiveLevel(player.Level)
+	if player.Team == shrine.Team {
+		if shrine.Bias >= shrine.MaxBias {
+			return false
+		}
+		shrine.Bias = clampBias(shrine.Bias+amount, shrine.MaxBias)
+		a.giveExpLocked(player, shrineExp(level, a.teamCountsLocked()[player.Team], amount))
+	} else {
+		if shrine.Bias <= 0 || a.shrineProtectedLocked(shrine) {
+			return false
+		}
+		shrine.Bias = clampBias(shrine.Bias-amount, shrine.MaxBias)
+		a.giveExpLocked(player, shrineDamageExp(level, a.teamCountsLocked()[shrine.Team], amount))
+		if shrine.IsDead() {
+			a.broadcastLocked(BuildChatPacket(fmt.Sprintf("The %s shrine has been destroyed!", shrine.Team)))
+		}
+	}
+
+	a.broadcastLocked(BuildShrineBiasPacket(a.ID, shrine, player.PlayerID))
+	return true
+}
+
+// damageShrineLocked takes bias from a team's shrine outside of biasing, as when its orb
+// is captured; the caller must hold a.mu
+func (a *Arena) damageShrineLocked(team Team, amount int) {
+	shrine, exists := a.Shrines[team]
+	if !exists || shrine.IsDead() || shrine.IsIndestructible() {
+		return
+	}
+	shrine.Bias = clampBias(shrine.Bias-amount, shrine.MaxBias)
+	a.broadcastLocked(BuildShrineBiasPacket(a.ID, shrine, 0))
+	if shrine.IsDead() {
+		a.broadcastLocked(BuildChatPacket(fmt.Sprintf("The %s shrine has been destroyed!", shrine.Team)))
+	}
+}
+
+// biasPoolLocked applies a player's bias to a pool: a neutral or friendly pool is biased
+// toward their team, and an enemy pool loses bias until it turns neutral. It reports
+// whether the bias changed; the caller must hold a.mu
+func (a *Arena) biasPoolLocked(player *ArenaPlayer, pool *Pool, amount int) bool {
+	if a.Ruleset.Rules.Has(ArenaRuleNoPoolBiasing) {
+		return false
+	}
+
+	if pool.Team == player.Team || pool.Team == TeamNone {
+		if pool.Team == player.Team && pool.Bias >= pool.MaxBias {
+			return false
+		}
+		pool.Team = player.Team
+		pool.Bias = clampBias(pool.Bias+amount, pool.MaxBias)
+	} else {
+		pool.Bias = clampBias(pool.Bias-amount, pool.MaxBias)
+		if pool.Bias == 0 {
+			pool.Team = TeamNone
+		}
+	}
+
+	a.giveExpLocked(player, poolExp(effectiveLevel(player.Level), amount))
+	a.broadcastLocked(BuildPoolBiasPacket(a.ID, pool, player.PlayerID))
+	return true
+}
+
+// biasingPlayerLocked returns a player who may bias shrines and pools; the caller must hold a.mu
+func (a *Arena) biasingPlayerLocked(playerID int) (*ArenaPlayer, error) {
+	player, exists := a.Players[playerID]
+	switch {
+	case !exists:
+		return nil, fmt.Errorf("player %d is not in arena %d", playerID, a.ID)
+	case a.State != ArenaStateActive:
+		return nil, fmt.Errorf("the match has not started")
+	case player.Dead:
+		return nil, fmt.Errorf("player %d is dead", playerID)
+	case player.Team == TeamNone:
+		return nil, fmt.Errorf("players without a team cannot bias")
+	}
+	return player, nil
+}
+
+// biasReachLocked checks that a player stands at a shrine or pool at (x, y) and has waited
+// out biasCooldown since they last biased one; the caller must hold a.mu
+func (a *Arena) biasReachLocked(player *ArenaPlayer, x, y float64, now time.Time) error {
+	switch {
+	case math.Hypot(player.X-x, player.Y-y) > objectiveRadius:
+		return fmt.Errorf("player %d is too far away to bias it", player.PlayerID)
+	case now.Before(player.BiasReadyAt):
+		return fmt.Errorf("player %d biased too recently", player.PlayerID)
+	}
+	return nil
+}
+
+// BiasShrine has a player standing at a shrine bias it directly, restoring their own or
+// damaging an enemy's
+func (a *Arena) BiasShrine(playerID, shrineID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, err := a.biasingPlayerLocked(playerID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, shrine := range a.Shrines {
+		if shrine.ID != shrineID {
+			continue
+		}
+		if err := a.shrineBiasAllowedLocked(shrine); err != nil {
+			return err
+		}
+		if err := a.biasReachLocked(player, shrine.X, shrine.Y, now); err != nil {
+			return err
+		}
+		if !a.biasShrineLocked(player, shrine, castBias(player.Level)) {
+			return fmt.Errorf("the %s shrine cannot be biased any further", shrine.Team)
+		}
+		player.BiasReadyAt = now.Add(biasCooldown)
+		return nil
+	}
+	return fmt.Errorf("shrine %d does not exist", shrineID)
+}
+
+// BiasPool has a player standing at a placed pool bias it directly toward their team
+func (a *Arena) BiasPool(playerID, poolID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, err := a.biasingPlayerLocked(playerID)
+	if err != nil {
+		return err
+	}
+	if a.Ruleset.Rules.Has(ArenaRuleNoPoolBiasing) {
+		return fmt.Errorf("pools cannot be biased in this arena")
+	}
+	now := time.Now()
+	for _, pool := range a.Pools {
+		if pool.ID != poolID {
+			continue
+		}
+		if !pool.HasPosition {
+			return fmt.Errorf("pool %d has no place to stand at", poolID)
+		}
+		if err := a.biasReachLocked(player, pool.X, pool.Y, now); err != nil {
+			return err
+		}
+		if !a.biasPoolLocked(player, pool, castBias(player.Level)) {
+			return fmt.Errorf("pool %d is already fully biased", poolID)
+		}
+		player.BiasReadyAt = now.Add(biasCooldown)
+		return nil
+	}
+	return fmt.Errorf("pool %d does not exist", poolID)
+}
+
+// ObjectivePackets describes every shrine and pool in the arena, for players joining a
+// match in progress
+func (a *Arena) ObjectivePackets() []*Packet {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	packets := make([]*Packet, 0, len(a.Shrines)+len(a.Pools))
+	for _, shrine := range a.Shrines {
+		packets = append(packets, BuildShrineBiasPacket(a.ID, shrine, 0))
+	}
+	for _, pool := range a.Pools {
+		packets = append(packets, BuildPoolBiasPacket(a.ID, pool, 0))
+	}
+	return packets
+}
+
+// describeObjectives formats an arena's shrines and pools as text
+func describeObjectives(arena *Arena) string {
+	arena.mu.RLock()
+	defer arena.mu.RUnlock()
+
+	if len(arena.Shrines) == 0 && len(arena.Pools) == 0 {
+		return fmt.Sprintf("%s has no shrines or pools in play", arena.Name)
+	}
+
+	shrines := make([]*Shrine, 0, len(arena.Shrines))
+	for _, shrine := range arena.Shrines {
+		shrines = append(shrines, shrine)
+	}
+	sort.Slice(shrines, func(i, j int) bool { return shrines[i].ID < shrines[j].ID })
+
+	response := fmt.Sprintf("Shrines and pools in %s:", arena.Name)
+	for _, shrine := range shrines {
+		switch {
+		case shrine.Disabled:
+			response += fmt.Sprintf("\n- %s shrine: not in play", shrine.Team)
+		case shrine.IsIndestructible():
+			response += fmt.Sprintf("\n- %s shrine: indestructible, %.2f guild points", shrine.Team, shrine.GuildPoints)
+		default:
+			response += fmt.Sprintf("\n- %s shrine: %d/%d bias, %.2f guild points", shrine.Team, shrine.Bias, shrine.MaxBias, shrine.GuildPoints)
+		}
+	}
+	for _, pool := range arena.Pools {
+		if pool.Team == TeamNone {
+			response += fmt.Sprintf("\n- Pool %d (power %d): neutral", pool.ID, pool.Power)
+			continue
+		}
+		response += fmt.Sprintf("\n- Pool %d (power %d): %s, %d/%d bias", pool.ID, pool.Power, pool.Team, pool.Bias, pool.MaxBias)
+	}
+	return response
+}
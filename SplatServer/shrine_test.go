@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestObjectiveArena returns an active arena with Chaos and Order shrines 1000 units
+// apart, a Balance shrine with no power, and two pools, one of them placed at (500, 0)
+func newTestObjectiveArena(t *testing.T, rules ArenaRule) *Arena {
+	t.Helper()
+	arena := newTestArena(1)
+	arena.Ruleset = CustomRuleset(rules)
+	arena.World = &World{
+		Spawns: map[Team]TeamSpawn{
+			TeamChaos: {Start: SpawnPoint{X: 0, Y: 0}},
+			TeamOrder: {Start: SpawnPoint{X: 1000, Y: 0}},
+		},
+		Shrines: []Shrine{
+			{ID: 0, Team: TeamOrder, Power: 100, MaxBias: 100, Bias: 100},
+			{ID: 1, Team: TeamChaos, Power: 100, MaxBias: 100, Bias: 100},
+			{ID: 2, Team: TeamBalance, Power: 0, MaxBias: 100, Bias: 100},
+		},
+		Pools: []Pool{
+			{ID: 0, Power: 40, MaxBias: 100, X: 500, Y: 0, HasPosition: true},
+			{ID: 1, Power: 30, MaxBias: 100},
+		},
+	}
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.StartArena()
+	return arena
+}
+
+// tickObjectives runs one objective update
+func tickObjectives(arena *Arena) {
+	arena.mu.Lock()
+	arena.nextObjectiveTick = time.Time{}
+	arena.updateObjectivesLocked(time.Now())
+	arena.mu.Unlock()
+}
+
+func TestLoadWorldShrinesAndPools(t *testing.T) {
+	path, err := findContentFile(contentDir(), "Grids", "Grid09", "World.dat")
+	if err != nil {
+		t.Fatalf("World.dat not found: %v", err)
+	}
+	world, err := LoadWorldFile(path)
+	if err != nil {
+		t.Fatalf("LoadWorldFile failed: %v", err)
+	}
+
+	// [shrine00] power=0 alignment=order bias=0, [shrine01] power=100 alignment=chaos bias=100
+	if len(world.Shrines) != 3 {
+		t.Fatalf("Expected 3 shrines, got %d", len(world.Shrines))
+	}
+	if order := world.Shrines[0]; order.Team != TeamOrder || order.Power != 0 || order.Bias != 0 {
+		t.Errorf("Unexpected order shrine %+v", order)
+	}
+	if chaos := world.Shrines[1]; chaos.Team != TeamChaos || chaos.Power != 100 || chaos.Bias != 100 {
+		t.Errorf("Unexpected chaos shrine %+v", chaos)
+	}
+	if len(world.Pools) != 7 || world.Pools[0].Power != 40 || world.Pools[3].Power != 30 {
+		t.Errorf("Unexpected pools %+v", world.Pools)
+	}
+}
+
+func TestStandingBiasAndShrineDamage(t *testing.T) {
+	arena := newTestObjectiveArena(t, 0)
+	if !arena.Shrines[TeamBalance].Disabled {
+		t.Error("Expected a shrine without power to be out of play")
+	}
+
+	// Chaos stands in the Order shrine; level 20 * 0.07 * 1 defender * 5 bias = 7 experience
+	arena.SetPlayerProgression(1, 20, 100)
+	arena.UpdatePlayerPosition(1, 1000, 10)
+	arena.UpdatePlayerPosition(2, 300, 0)
+	conn := &captureConn{}
+	arena.SetPlayerConn(2, conn)
+	tickObjectives(arena)
+
+	order := arena.Shrines[TeamOrder]
+	if order.Bias != 100-standingBias {
+		t.Errorf("Expected the Order shrine to lose %d bias, got %d", standingBias, order.Bias)
+	}
+	if got := arena.GetPlayer(1).PendingExp; got != 7 {
+		t.Errorf("Expected 7 experience for damaging a shrine, got %d", got)
+	}
+	packet, err := DeserializePacket(bytes.NewReader(conn.written.Bytes()))
+	if err != nil || packet.Type != PacketShrineBias {
+		t.Errorf("Expected a shrine bias packet, got %+v, %v", packet, err)
+	}
+
+	// Order restores its own shrine
+	arena.UpdatePlayerPosition(1, 300, 0)
+	arena.UpdatePlayerPosition(2, 1000, 0)
+	tickObjectives(arena)
+	if order.Bias != 100 {
+		t.Errorf("Expected Order to restore its shrine, got %d", order.Bias)
+	}
+}
+
+func TestPoolBiasing(t *testing.T) {
+	arena := newTestObjectiveArena(t, 0)
+	pool := arena.Pools[0]
+
+	arena.UpdatePlayerPosition(1, 500, 0)
+	tickObjectives(arena)
+	if pool.Team != TeamChaos || pool.Bias != standingBias {
+		t.Fatalf("Expected Chaos to start biasing the pool, got %+v", pool)
+	}
+
+	// Order biases it back to neutral, then toward itself
+	arena.UpdatePlayerPosition(1, 0, 0)
+	arena.UpdatePlayerPosition(2, 500, 10)
+	if err := arena.BiasPool(2, 0); err != nil {
+		t.Fatalf("BiasPool failed: %v", err)
+	}
+	if pool.Team != TeamNone || pool.Bias != 0 {
+		t.Errorf("Expected the pool to turn neutral, got %+v", pool)
+	}
+	for i := 0; i < 5; i++ {
+		waitOutBiasCooldown(arena, 2)
+		arena.BiasPool(2, 0)
+	}
+	if !pool.IsFullyBiased() || pool.Team != TeamOrder {
+		t.Errorf("Expected Order to hold the pool, got %+v", pool)
+	}
+	waitOutBiasCooldown(arena, 2)
+	if err := arena.BiasPool(2, 0); err == nil {
+		t.Error("Expected biasing a full pool to fail")
+	}
+
+	// A team's shrine earns guild points for every pool it holds
+	order, chaos := arena.Shrines[TeamOrder].GuildPoints, arena.Shrines[TeamChaos].GuildPoints
+	tickObjectives(arena)
+	if got := arena.Shrines[TeamOrder].GuildPoints - order; got != guildPointsPerSecond+0.4 {
+		t.Errorf("Expected %.2f guild points, got %.2f", guildPointsPerSecond+0.4, got)
+	}
+	if got := arena.Shrines[TeamChaos].GuildPoints - chaos; got != guildPointsPerSecond {
+		t.Errorf("Expected %.2f guild points, got %.2f", guildPointsPerSecond, got)
+	}
+}
+
+func TestBiasNeedsReachAndCooldown(t *testing.T) {
+	arena := newTestObjectiveArena(t, 0)
+	pool := arena.Pools[0]
+
+	// From their start point Order is 500 units from the pool and 1000 from Chaos's shrine
+	if err := arena.BiasPool(2, 0); err == nil || pool.Bias != 0 {
+		t.Errorf("Expected an out of range pool bias to be refused, got %v and %+v", err, pool)
+	}
+	if err := arena.BiasShrine(2, 1); err == nil || arena.Shrines[TeamChaos].Bias != 100 {
+		t.Errorf("Expected an out of range shrine bias to be refused, got %v", err)
+	}
+	if err := arena.BiasPool(2, 1); err == nil {
+		t.Error("Expected a pool with no position to be refused")
+	}
+
+	// Standing at the pool, a second bias straight after the first is refused
+	arena.UpdatePlayerPosition(2, 500, 10)
+	if err := arena.BiasPool(2, 0); err != nil {
+		t.Fatalf("BiasPool failed: %v", err)
+	}
+	if err := arena.BiasPool(2, 0); err == nil || pool.Bias != castBias(1) {
+		t.Errorf("Expected a repeated bias to be refused, got %v and bias %d", err, pool.Bias)
+	}
+	waitOutBiasCooldown(arena, 2)
+	if err := arena.BiasPool(2, 0); err != nil || pool.Bias != 2*castBias(1) {
+		t.Errorf("Expected a bias after the cooldown, got %v and bias %d", err, pool.Bias)
+	}
+}
+
+// waitOutBiasCooldown lets a player bias again straight away
+func waitOutBiasCooldown(arena *Arena, playerID int) {
+	arena.mu.Lock()
+	arena.Players[playerID].BiasReadyAt = time.Time{}
+	arena.mu.Unlock()
+}
+
+func TestBiasingRules(t *testing.T) {
+	arena := newTestObjectiveArena(t, ArenaRuleNoPoolBiasing|ArenaRuleNoShrineBiasing)
+	if err := arena.BiasPool(1, 1); err == nil {
+		t.Error("Expected pool biasing to be refused")
+	}
+	if err := arena.BiasShrine(1, 0); err == nil {
+		t.Error("Expected shrine biasing to be refused")
+	}
+
+	protected := newTestObjectiveArena(t, ArenaRuleShrineProtection)
+	protected.UpdatePlayerPosition(1, 1000, 0)
+	protected.UpdatePlayerPosition(2, 1010, 0)
+	tickObjectives(protected)
+	if protected.Shrines[TeamOrder].Bias != 100 {
+		t.Error("Expected a defended shrine to be protected")
+	}
+	protected.UpdatePlayerPosition(2, 300, 0)
+	if err := protected.BiasShrine(1, 0); err != nil || protected.Shrines[TeamOrder].Bias != 100-castBias(1) {
+		t.Errorf("Expected an undefended shrine to be damaged, got %v", err)
+	}
+}
+
+func TestOrbCaptureDamagesShrine(t *testing.T) {
+	arena := newTestObjectiveArena(t, ArenaRuleCaptureTheFlag)
+	if err := arena.BiasShrine(1, 0); err == nil {
+		t.Error("Expected shrines not to be biased in capture the flag")
+	}
+
+	moveAndUpdateOrbs(arena, 1, 1000, 0)
+	moveAndUpdateOrbs(arena, 1, 0, 0)
+	if arena.Captures[TeamChaos] != 1 || arena.Shrines[TeamOrder].Bias != 100-orbCaptureShrineBias {
+		t.Errorf("Expected the capture to damage the Order shrine, got %d", arena.Shrines[TeamOrder].Bias)
+	}
+}
+
+func TestGuildStandings(t *testing.T) {
+	arena := newTestObjectiveArena(t, 0)
+	conn := &captureConn{}
+	arena.SetPlayerConn(1, conn)
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+	arena.Shrines[TeamChaos].GuildPoints = 12.5
+	arena.Shrines[TeamOrder].GuildPoints = 3
+
+	arena.announceGuildStandingsLocked(arena.StartTime.Add(time.Hour))
+	if conn.written.Len() != 0 {
+		t.Fatalf("Expected no standings without guild rules, got %q", conn.written.String())
+	}
+
+	arena.Ruleset.Rules = ArenaRuleGuildRules
+	arena.TimeLimit = time.Hour
+	arena.announceGuildStandingsLocked(arena.StartTime.Add(time.Minute))
+	if conn.written.Len() != 0 {
+		t.Fatal("Expected no standings before ten minutes have passed")
+	}
+	arena.announceGuildStandingsLocked(arena.StartTime.Add(guildStandingsEvery))
+	for _, want := range []string{"[Guild Match] Chaos: 12.50", "[Guild Match] Order: 3.00", "[Guild Match] Winning Team: Chaos"} {
+		if !strings.Contains(conn.written.String(), want) {
+			t.Errorf("Expected %q in %q", want, conn.written.String())
+		}
+	}
+	if strings.Contains(conn.written.String(), "Balance") {
+		t.Error("Expected the shrine out of play without players to be left out")
+	}
+
+	// In the last ten minutes the standings come every two
+	conn.written.Reset()
+	late := arena.StartTime.Add(55 * time.Minute)
+	arena.announceGuildStandingsLocked(late)
+	arena.announceGuildStandingsLocked(late.Add(time.Minute))
+	if count := strings.Count(conn.written.String(), "Winning Team"); count != 1 {
+		t.Errorf("Expected one announcement, got %d", count)
+	}
+	arena.announceGuildStandingsLocked(late.Add(guildStandingsLate))
+	if count := strings.Count(conn.written.String(), "Winning Team"); count != 2 {
+		t.Errorf("Expected a second announcement two minutes later, got %d", count)
+	}
+}
+
+func TestShrinesCommand(t *testing.T) {
+	gs := NewGameState()
+	player := addTestCaster(gs, 1)
+	conn := player.Conn.(*captureConn)
+	arena := newTestObjectiveArena(t, 0)
+	gs.ArenaManager.Arenas[arena.ID] = arena
+
+	handleChatCommand("!shrines", player, gs)
+	reply := conn.written.String()
+	if !strings.Contains(reply, "Chaos shrine: 100/100 bias") || !strings.Contains(reply, "Balance shrine: not in play") {
+		t.Errorf("Unexpected shrines reply %q", reply)
+	}
+}
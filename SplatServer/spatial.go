@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+)
+
+// spatialCellSize is the side of a spatial index cell in world units, four grid blocks
+const spatialCellSize = 256.0
+
+// EntityKind is the kind of entity a spatial index holds
+type EntityKind int
+
+const (
+	EntityPlayer EntityKind = iota
+	EntityWall
+	EntityRune
+)
+
+// EntityKey identifies an entity in a spatial index: a player ID, or a wall or rune ID
+type EntityKey struct {
+	Kind EntityKind
+	ID   int64
+}
+
+// spatialCell is a cell of a spatial index by column and row
+type spatialCell struct {
+	Column, Row int
+}
+
+// spatialBucket holds the entities of one kind filed under a cell
+type spatialBucket struct {
+	Kind EntityKind
+	Cell spatialCell
+}
+
+// spatialBounds is the range of cells an entity's bounds touch
+type spatialBounds struct {
+	Min, Max spatialCell
+}
+
+// SpatialIndex is a uniform grid over an arena that files each entity under every cell
+// its bounding circle touches, so queries only look at entities of the kind they want
+// near them. Entities are updated as they move and only change cells when they cross a
+// cell boundary
+type SpatialIndex struct {
+	cellSize float64
+	buckets  map[spatialBucket]map[EntityKey]struct{}
+	bounds   map[EntityKey]spatialBounds
+}
+
+// NewSpatialIndex creates an empty spatial index with cells of the given size
+func NewSpatialIndex(cellSize float64) *SpatialIndex {
+	return &SpatialIndex{
+		cellSize: cellSize,
+		buckets:  make(map[spatialBucket]map[EntityKey]struct{}),
+		bounds:   make(map[EntityKey]spatialBounds),
+	}
+}
+
+// cellRange returns the cells touched by the box from (x1, y1) to (x2, y2)
+func (s *SpatialIndex) cellRange(x1, y1, x2, y2 float64) spatialBounds {
+	return spatialBounds{
+		Min: spatialCell{int(math.Floor(math.Min(x1, x2) / s.cellSize)), int(math.Floor(math.Min(y1, y2) / s.cellSize))},
+		Max: spatialCell{int(math.Floor(math.Max(x1, x2) / s.cellSize)), int(math.Floor(math.Max(y1, y2) / s.cellSize))},
+	}
+}
+
+// Update files an entity with a bounding circle at a point, adding it if it is new and
+// moving it if it has left its cells
+func (s *SpatialIndex) Update(key EntityKey, x, y, radius float64) {
+	bounds := s.cellRange(x-radius, y-radius, x+radius, y+radius)
+	old, exists := s.bounds[key]
+	if exists && old == bounds {
+		return
+	}
+	if exists {
+		s.unfile(key, old)
+	}
+	s.bounds[key] = bounds
+	for row := bounds.Min.Row; row <= bounds.Max.Row; row++ {
+		for column := bounds.Min.Column; column <= bounds.Max.Column; column++ {
+			bucket := spatialBucket{key.Kind, spatialCell{column, row}}
+			if s.buckets[bucket] == nil {
+				s.buckets[bucket] = make(map[EntityKey]struct{})
+			}
+			s.buckets[bucket][key] = struct{}{}
+		}
+	}
+}
+
+// Remove takes an entity out of the index
+func (s *SpatialIndex) Remove(key EntityKey) {
+	if bounds, exists := s.bounds[key]; exists {
+		s.unfile(key, bounds)
+		delete(s.bounds, key)
+	}
+}
+
+// unfile removes an entity from the cells in a range, dropping cells left empty
+func (s *SpatialIndex) unfile(key EntityKey, bounds spatialBounds) {
+	for row := bounds.Min.Row; row <= bounds.Max.Row; row++ {
+		for column := bounds.Min.Column; column <= bounds.Max.Column; column++ {
+			bucket := spatialBucket{key.Kind, spatialCell{column, row}}
+			delete(s.buckets[bucket], key)
+			if len(s.buckets[bucket]) == 0 {
+				delete(s.buckets, bucket)
+			}
+		}
+	}
+}
+
+// Len returns the number of entities in the index
+func (s *SpatialIndex) Len() int {
+	return len(s.bounds)
+}
+
+// QueryBox calls fn once for each entity of a kind filed in the cells touched by the
+// box from (x1, y1) to (x2, y2). Entities near the box but outside it may be included,
+// so callers test the exact shapes
+func (s *SpatialIndex) QueryBox(kind EntityKind, x1, y1, x2, y2 float64, fn func(EntityKey)) {
+	query := s.cellRange(x1, y1, x2, y2)
+	for row := query.Min.Row; row <= query.Max.Row; row++ {
+		for column := query.Min.Column; column <= query.Max.Column; column++ {
+			for key := range s.buckets[spatialBucket{kind, spatialCell{column, row}}] {
+				// An entity spanning several cells is reported from the first cell
+				// it shares with the query
+				bounds := s.bounds[key]
+				if column == max(bounds.Min.Column, query.Min.Column) && row == max(bounds.Min.Row, query.Min.Row) {
+					fn(key)
+				}
+			}
+		}
+	}
+}
+
+// QueryRadius calls fn for the entities of a kind that may lie within radius of a point
+func (s *SpatialIndex) QueryRadius(kind EntityKind, x, y, radius float64, fn func(EntityKey)) {
+	s.QueryBox(kind, x-radius, y-radius, x+radius, y+radius, fn)
+}
+
+// QuerySegment calls fn for the entities of a kind that may lie within pad of a segment
+func (s *SpatialIndex) QuerySegment(kind EntityKind, x1, y1, x2, y2, pad float64, fn func(EntityKey)) {
+	s.QueryBox(kind, math.Min(x1, x2)-pad, math.Min(y1, y2)-pad, math.Max(x1, x2)+pad, math.Max(y1, y2)+pad, fn)
+}
+
+// spatialLocked returns the arena's spatial index, creating it for arenas built without
+// one; the caller must hold a.mu
+func (a *Arena) spatialLocked() *SpatialIndex {
+	if a.index == nil {
+		a.index = NewSpatialIndex(spatialCellSize)
+	}
+	return a.index
+}
+
+// movePlayerLocked puts a player at a point and refiles them in the spatial index; the
+// caller must hold a.mu
+func (a *Arena) movePlayerLocked(player *ArenaPlayer, x, y float64) {
+	player.X, player.Y = x, y
+	a.spatialLocked().Update(EntityKey{EntityPlayer, int64(player.PlayerID)}, x, y, playerRadius)
+}
+
+// playersNearLocked returns the players who may be within radius of a point, for hit
+// tests, area effects and area-of-interest filtering; the caller must hold a.mu
+func (a *Arena) playersNearLocked(x, y, radius float64) []*ArenaPlayer {
+	var players []*ArenaPlayer
+	a.spatialLocked().QueryRadius(EntityPlayer, x, y, radius, func(key EntityKey) {
+		if player, exists := a.Players[int(key.ID)]; exists {
+			players = append(players, player)
+		}
+	})
+	return players
+}
+
+// playersAlongLocked returns the players who may be within pad of a segment; the caller
+// must hold a.mu
+func (a *Arena) playersAlongLocked(x1, y1, x2, y2, pad float64) []*ArenaPlayer {
+	var players []*ArenaPlayer
+	a.spatialLocked().QuerySegment(EntityPlayer, x1, y1, x2, y2, pad, func(key EntityKey) {
+		if player, exists := a.Players[int(key.ID)]; exists {
+			players = append(players, player)
+		}
+	})
+	return players
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// queryKeys returns the IDs a query reports, sorted
+func queryKeys(query func(fn func(EntityKey))) []int64 {
+	var ids []int64
+	query(func(key EntityKey) {
+		ids = append(ids, key.ID)
+	})
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestSpatialIndex(t *testing.T) {
+	index := NewSpatialIndex(100)
+	index.Update(EntityKey{EntityPlayer, 1}, 50, 50, 10)
+	index.Update(EntityKey{EntityPlayer, 2}, 450, 50, 10)
+	index.Update(EntityKey{EntityRune, 3}, 60, 60, 10)
+
+	// A wall 300 units long spans three cells but is reported once
+	index.Update(EntityKey{EntityWall, 4}, 250, 150, 150)
+
+	near := queryKeys(func(fn func(EntityKey)) { index.QueryRadius(EntityPlayer, 60, 60, 20, fn) })
+	if len(near) != 1 || near[0] != 1 {
+		t.Errorf("Expected only player 1 near (60, 60), got %v", near)
+	}
+	if walls := queryKeys(func(fn func(EntityKey)) { index.QueryBox(EntityWall, 0, 0, 500, 500, fn) }); len(walls) != 1 {
+		t.Errorf("Expected the wall to be reported once, got %v", walls)
+	}
+
+	// Moving within a cell changes nothing; moving across a boundary refiles
+	index.Update(EntityKey{EntityPlayer, 1}, 55, 55, 10)
+	index.Update(EntityKey{EntityPlayer, 2}, 70, 40, 10)
+	near = queryKeys(func(fn func(EntityKey)) { index.QueryRadius(EntityPlayer, 60, 60, 20, fn) })
+	if len(near) != 2 {
+		t.Errorf("Expected both players near (60, 60) after the move, got %v", near)
+	}
+	if far := queryKeys(func(fn func(EntityKey)) { index.QueryRadius(EntityPlayer, 450, 50, 20, fn) }); len(far) != 0 {
+		t.Errorf("Expected player 2 to have left its old cell, got %v", far)
+	}
+
+	along := queryKeys(func(fn func(EntityKey)) { index.QuerySegment(EntityPlayer, 0, 120, 90, 120, 25, fn) })
+	if len(along) != 2 {
+		t.Errorf("Expected the segment's cells to hold both players, got %v", along)
+	}
+
+	index.Remove(EntityKey{EntityPlayer, 1})
+	index.Remove(EntityKey{EntityPlayer, 9})
+	if index.Len() != 3 {
+		t.Errorf("Expected 3 entities left, got %d", index.Len())
+	}
+}
+
+func TestArenaSpatialIndexTracksEntities(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 3000, 3000)
+
+	// The bolt finds the target where it moved to, far from where it joined
+	if result := arena.FireBolt(1, testBoltSpell(), 2800, 3000, 0); result.TargetID != 2 {
+		t.Errorf("Expected the bolt to hit player 2, got %+v", result)
+	}
+
+	arena.AddWall(NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0))
+	arena.AddRune(armedRune(testRuneSpell(), 1, TeamChaos, 1000, 1000))
+	if n := arena.spatialLocked().Len(); n != 4 {
+		t.Errorf("Expected 2 players, a wall and a rune in the index, got %d", n)
+	}
+	arena.RemovePlayer(2)
+	arena.DispelRunes(1032, 1000, runeDispelRadius)
+	if n := arena.spatialLocked().Len(); n != 2 {
+		t.Errorf("Expected the player and rune to leave the index, got %d", n)
+	}
+
+	arena.mu.Lock()
+	arena.resetLocked()
+	arena.mu.Unlock()
+	if n := arena.spatialLocked().Len(); n != 1 {
+		t.Errorf("Expected only the remaining player after a reset, got %d", n)
+	}
+}
+
+func TestTeamChangeMovesPlayerInIndex(t *testing.T) {
+	arena := newTestArena(1)
+	arena.World = &World{Spawns: map[Team]TeamSpawn{
+		TeamOrder: {Start: SpawnPoint{X: 3000, Y: 3000}},
+	}}
+	arena.AddPlayer(1, TeamChaos)
+
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+	arena.setTeamLocked(arena.Players[1], TeamOrder)
+	if near := arena.playersNearLocked(3000, 3000, playerRadius); len(near) != 1 || near[0].PlayerID != 1 {
+		t.Errorf("Expected the player filed at the Order start point, got %d players", len(near))
+	}
+	if near := arena.playersNearLocked(0, 0, playerRadius); len(near) != 0 {
+		t.Error("Expected the player gone from where they joined")
+	}
+}
+
+func TestResolveProjectilesByArena(t *testing.T) {
+	gs := NewGameState()
+	spell := &Spell{ID: 400, Name: "Test Projectile", Type: SpellTypeProjectile, Damage: 10, Speed: 600}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{spell})
+	arenas := []*Arena{newTestArena(1), newTestArena(2)}
+	for _, arena := range arenas {
+		arena.AddPlayer(1, TeamChaos)
+		arena.AddPlayer(2, TeamOrder)
+		arena.UpdatePlayerPosition(2, 110, 100)
+		instance, err := gs.SpellSystem.CastSpell(1, spell.ID, 0, 0, 0)
+		if err != nil {
+			t.Fatalf("CastSpell failed: %v", err)
+		}
+		gs.SpellSystem.launch(instance, arena.ID, 100, 100, 0, spell.Speed)
+	}
+	gs.SpellSystem.UpdateSpellSystem(16 * time.Millisecond)
+
+	active := gs.SpellSystem.ActiveSpellsByArena()
+	if len(active[1]) != 1 || len(active[2]) != 1 || active[1][0].ArenaID != 1 {
+		t.Fatalf("Expected one projectile in each arena, got %v", active)
+	}
+	ResolveProjectiles(gs, arenas[0], active[1])
+	if arenas[0].GetPlayer(2).Health != 90 || arenas[1].GetPlayer(2).Health != 100 {
+		t.Errorf("Expected only the first arena's projectile to hit, got health %d and %d",
+			arenas[0].GetPlayer(2).Health, arenas[1].GetPlayer(2).Health)
+	}
+	if remaining := gs.SpellSystem.ActiveSpellsByArena(); len(remaining[1]) != 0 || len(remaining[2]) != 1 {
+		t.Errorf("Expected the spent projectile removed, got %v", remaining)
+	}
+}
+
+// benchmarkArena returns a game with an arena of players spread over a grid-sized area
+// and projectiles flying across it, which do no damage so the players stay alive
+func benchmarkArena(b *testing.B, players, projectiles int) (*GameState, *Arena) {
+	rng := rand.New(rand.NewSource(1))
+	gs := NewGameState()
+	spell := &Spell{ID: 400, Name: "Bench Projectile", Type: SpellTypeProjectile, Speed: 300}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{spell})
+
+	arena := gs.ArenaManager.CreateArena(1, "Bench", players, 0)
+	for id := 1; id <= players; id++ {
+		arena.AddPlayer(id, Team(1+id%3))
+		arena.UpdatePlayerPosition(id, rng.Float64()*gridWidth*blockSize, rng.Float64()*gridWidth*blockSize)
+	}
+	for i := 0; i < projectiles; i++ {
+		instance, err := gs.SpellSystem.CastSpell(-i-1, spell.ID, 0, 0, 0)
+		if err != nil {
+			b.Fatalf("CastSpell failed: %v", err)
+		}
+		gs.SpellSystem.launch(instance, arena.ID, rng.Float64()*gridWidth*blockSize, rng.Float64()*gridWidth*blockSize, rng.Float64()*6.28, spell.Speed)
+	}
+	return gs, arena
+}
+
+// BenchmarkArenaTick measures one tick of a full arena: every player moves, then the
+// arena, spells and projectile hits update
+func BenchmarkArenaTick(b *testing.B) {
+	gs, arena := benchmarkArena(b, 100, 500)
+	rng := rand.New(rand.NewSource(2))
+	spellID := 400
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for id := 1; id <= 100; id++ {
+			player := arena.GetPlayer(id)
+			arena.UpdatePlayerPosition(id, player.X+rng.Float64()*8-4, player.Y+rng.Float64()*8-4)
+		}
+		UpdateArena(arena)
+		gs.SpellSystem.UpdateSpellSystem(16 * time.Millisecond)
+		ResolveProjectiles(gs, arena, gs.SpellSystem.ActiveSpellsByArena()[arena.ID])
+
+		// Relaunch projectiles that left the grid and replace spent ones so every tick
+		// has 500 in flight
+		b.StopTimer()
+		for _, instance := range gs.SpellSystem.GetActiveSpells() {
+			if instance.X < 0 || instance.Y < 0 || instance.X > gridWidth*blockSize || instance.Y > gridWidth*blockSize {
+				gs.SpellSystem.launch(instance, arena.ID, rng.Float64()*gridWidth*blockSize, rng.Float64()*gridWidth*blockSize, rng.Float64()*6.28, 300)
+			}
+		}
+		for n := len(gs.SpellSystem.GetActiveSpells()); n < 500; n++ {
+			instance := &SpellInstance{ID: generateSpellID(), SpellID: spellID, CasterID: -1}
+			gs.SpellSystem.ActiveSpells[instance.ID] = instance
+			gs.SpellSystem.launch(instance, arena.ID, rng.Float64()*gridWidth*blockSize, rng.Float64()*gridWidth*blockSize, rng.Float64()*6.28, 300)
+		}
+		b.StartTimer()
+	}
+}
+
+// BenchmarkPlayersNear measures an area query among 100 players
+func BenchmarkPlayersNear(b *testing.B) {
+	_, arena := benchmarkArena(b, 100, 0)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		arena.mu.Lock()
+		arena.playersNearLocked(float64(i%8192), 4096, 256)
+		arena.mu.Unlock()
+	}
+}
@@ -18,6 +18,8 @@ const (
 	SpellEffectShield
 	SpellEffectSlow
 	SpellEffectStun
+	SpellEffectResurrect
+	SpellEffectHinder
 )
 
 // SpellElementType defines the elemental type of a spell
@@ -43,6 +45,7 @@ const (
 	SpellFriendlyEnemy SpellFriendlyType = iota
 	SpellFriendlyAlly
 	SpellFriendlySelf
+	SpellFriendlyDead // dead allies, for raise spells
 )
 
 // SpellProjectileType defines the projectile behavior
@@ -55,6 +58,22 @@ const (
 	SpellProjectileWave
 )
 
+// SpellType defines how a spell is delivered, mirroring the type key in Spells.dat
+type SpellType int
+
+const (
+	SpellTypeNone SpellType = iota
+	SpellTypeProjectile
+	SpellTypeWall
+	SpellTypeHealing
+	SpellTypeEffect
+	SpellTypeBolt
+	SpellTypeTarget
+	SpellTypeDispell
+	SpellTypeTeleport
+	SpellTypeRune
+)
+
 // Spell represents a spell definition
 type Spell struct {
 	ID           int
@@ -70,11 +89,23 @@ type Spell struct {
 	Cooldown     time.Duration
 	Range        float64
 	Speed        float64
+	Type         SpellType
+	HitPoints    int     // wall health
+	Length       float64 // wall length along the facing's perpendicular
+	Thick        float64 // wall thickness along the facing
+	Height       float64 // wall height
+	CastDistance float64 // distance in front of the caster the spell appears
+	Width        float64 // rune footprint
+	Radius       float64 // area of effect around the impact or trigger point
+	TriggerSpellID int   // spell a rune fires when triggered, 0 to use the rune itself
+	TargetSpellID  int   // effect a targeted spell applies to its target
+	RaisePercent   int   // health a resurrect effect restores, as a percentage of max health
 }
 
 // SpellManager manages all spells
 type SpellManager struct {
 	Spells map[int]*Spell
+	Trees  *SpellTreeCatalog
 	mu     sync.RWMutex
 }
 
@@ -82,6 +113,7 @@ type SpellManager struct {
 func NewSpellManager() *SpellManager {
 	sm := &SpellManager{
 		Spells: make(map[int]*Spell),
+		Trees:  NewSpellTreeCatalog(),
 	}
 	sm.initializeSpells()
 	return sm
@@ -168,6 +200,17 @@ func (sm *SpellManager) GetSpell(id int) *Spell {
 	return sm.Spells[id]
 }
 
+// linkedSpell returns the spell a definition refers to by ID, or def when it is unset or unknown
+func (sm *SpellManager) linkedSpell(id int, def *Spell) *Spell {
+	if id <= 0 {
+		return def
+	}
+	if spell := sm.GetSpell(id); spell != nil {
+		return spell
+	}
+	return def
+}
+
 // GetAllSpells returns all available spells
 func (sm *SpellManager) GetAllSpells() []*Spell {
 	sm.mu.RLock()
@@ -191,6 +234,9 @@ type SpellInstance struct {
 	VelocityY  float64
 	StartTime  time.Time
 	Duration   time.Duration
+	ArenaID    int     // arena the spell was cast in, 0 outside arenas
+	PrevX      float64 // position before the last update, for swept hit tests
+	PrevY      float64
 }
 
 // SpellSystem manages active spells and effects
@@ -280,6 +326,7 @@ func (ss *SpellSystem) UpdateSpellSystem(deltaTime time.Duration) {
 		spellDef := ss.SpellManager.GetSpell(spell.SpellID)
 		if spellDef != nil && spellDef.Speed > 0 {
 			// Move projectile (simplified - would need proper direction calculation)
+			spell.PrevX, spell.PrevY = spell.X, spell.Y
 			spell.X += spell.VelocityX * deltaTime.Seconds()
 			spell.Y += spell.VelocityY * deltaTime.Seconds()
 		}
@@ -303,6 +350,21 @@ func (ss *SpellSystem) GetActiveSpells() []*SpellInstance {
 	return spells
 }
 
+// ActiveSpellsByArena returns the active spells launched in arenas, by arena ID, so each
+// arena's projectiles are resolved without going through every other arena's
+func (ss *SpellSystem) ActiveSpellsByArena() map[int][]*SpellInstance {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	spells := make(map[int][]*SpellInstance)
+	for _, spell := range ss.ActiveSpells {
+		if spell.ArenaID != 0 {
+			spells[spell.ArenaID] = append(spells[spell.ArenaID], spell)
+		}
+	}
+	return spells
+}
+
 // RemoveSpell removes a spell instance
 func (ss *SpellSystem) RemoveSpell(spellID int64) {
 	ss.mu.Lock()
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PlayerClass defines a character's class, which decides its spell trees
+type PlayerClass int
+
+const (
+	ClassMagician PlayerClass = iota
+	ClassArcanist
+	ClassMentalist
+	ClassCleric
+)
+
+const (
+	maxTreeLevel       = 49 // level01..level49 in a [spelllistNN] section
+	maxClassTrees      = 10 // list00..list09 in a class section
+	maxSpellbookSpells = 20
+)
+
+// playerClassNames maps class names to classes, matching the class sections of Spells.dat
+var playerClassNames = map[string]PlayerClass{
+	"magician":  ClassMagician,
+	"arcanist":  ClassArcanist,
+	"mentalist": ClassMentalist,
+	"cleric":    ClassCleric,
+}
+
+// String returns the class name
+func (c PlayerClass) String() string {
+	for name, class := range playerClassNames {
+		if class == c {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// ParsePlayerClass parses a class name
+func ParsePlayerClass(name string) (PlayerClass, error) {
+	class, ok := playerClassNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ClassMagician, fmt.Errorf("unknown class %q", name)
+	}
+	return class, nil
+}
+
+// SpellTree is a spell list from Spells.dat; each tree level unlocks at most one spell
+type SpellTree struct {
+	ID     int
+	Name   string
+	Levels map[int]int // tree level -> spell ID
+}
+
+// SpellTreeCatalog holds the spell trees and which trees each class may train
+type SpellTreeCatalog struct {
+	Trees      map[int]*SpellTree
+	ClassTrees map[PlayerClass][]int
+}
+
+// NewSpellTreeCatalog creates an empty catalog
+func NewSpellTreeCatalog() *SpellTreeCatalog {
+	return &SpellTreeCatalog{
+		Trees:      make(map[int]*SpellTree),
+		ClassTrees: make(map[PlayerClass][]int),
+	}
+}
+
+// parseSpellTrees reads the [listdefs], [spelllistNN] and class sections of Spells.dat
+func parseSpellTrees(ini *IniFile) (*SpellTreeCatalog, error) {
+	catalog := NewSpellTreeCatalog()
+
+	count := ini.Int("listdefs", "numlists", 0)
+	for id := 1; id <= count; id++ {
+		section := fmt.Sprintf("spelllist%02d", id)
+		if !ini.HasSection(section) {
+			return nil, fmt.Errorf("missing section [%s]", section)
+		}
+		tree := &SpellTree{ID: id, Name: ini.String(section, "name", section), Levels: make(map[int]int)}
+		for level := 1; level <= maxTreeLevel; level++ {
+			if spellID := ini.Int(section, fmt.Sprintf("level%02d", level), 0); spellID > 0 {
+				tree.Levels[level] = spellID
+			}
+		}
+		catalog.Trees[id] = tree
+	}
+
+	for name, class := range playerClassNames {
+		for slot := 0; slot < maxClassTrees; slot++ {
+			treeID := ini.Int(name, fmt.Sprintf("list%02d", slot), 0)
+			if treeID == 0 {
+				continue
+			}
+			if _, exists := catalog.Trees[treeID]; !exists {
+				return nil, fmt.Errorf("[%s] references unknown spell list %d", name, treeID)
+			}
+			catalog.ClassTrees[class] = append(catalog.ClassTrees[class], treeID)
+		}
+	}
+
+	return catalog, nil
+}
+
+// FindTree looks a class tree up by ID or case-insensitive name
+func (c *SpellTreeCatalog) FindTree(class PlayerClass, key string) *SpellTree {
+	id, _ := strconv.Atoi(key)
+	for _, treeID := range c.ClassTrees[class] {
+		tree := c.Trees[treeID]
+		if tree.ID == id || strings.EqualFold(tree.Name, key) {
+			return tree
+		}
+	}
+	return nil
+}
+
+// IsUnlocked checks whether a spell is available to a class at a character level with the given tree training.
+// As in MageServer the character level and the tree's trained level must both reach the spell's level.
+func (c *SpellTreeCatalog) IsUnlocked(class PlayerClass, characterLevel int, treeLevels map[int]int, spellID int) bool {
+	for _, treeID := range c.ClassTrees[class] {
+		for level, id := range c.Trees[treeID].Levels {
+			if id == spellID && level <= characterLevel && level <= treeLevels[treeID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnlockedSpells lists the spells available with the given tree training, in ascending ID order
+func (c *SpellTreeCatalog) UnlockedSpells(class PlayerClass, characterLevel int, treeLevels map[int]int) []int {
+	seen := make(map[int]bool)
+	var spells []int
+	for _, treeID := range c.ClassTrees[class] {
+		for level, id := range c.Trees[treeID].Levels {
+			if level <= characterLevel && level <= treeLevels[treeID] && !seen[id] {
+				seen[id] = true
+				spells = append(spells, id)
+			}
+		}
+	}
+	sort.Ints(spells)
+	return spells
+}
+
+// Spellbook holds a character's class, tree training and equipped spells
+type Spellbook struct {
+	Class      PlayerClass
+	TreeLevels map[int]int // tree ID -> levels trained
+	Spells     []int       // equipped spell IDs
+	mu         sync.RWMutex
+}
+
+// NewSpellbook creates an empty spellbook for a class
+func NewSpellbook(class PlayerClass) *Spellbook {
+	return &Spellbook{
+		Class:      class,
+		TreeLevels: make(map[int]int),
+	}
+}
+
+// Has checks if a spell is equipped
+func (b *Spellbook) Has(spellID int) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, id := range b.Spells {
+		if id == spellID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSpells returns a copy of the equipped spell IDs
+func (b *Spellbook) GetSpells() []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]int(nil), b.Spells...)
+}
+
+// TreeLevel returns the levels trained in a spell tree
+func (b *Spellbook) TreeLevel(treeID int) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.TreeLevels[treeID]
+}
+
+// PointsSpent returns the number of tree levels trained
+func (b *Spellbook) PointsSpent() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.pointsSpentLocked()
+}
+
+func (b *Spellbook) pointsSpentLocked() int {
+	spent := 0
+	for _, level := range b.TreeLevels {
+		spent += level
+	}
+	return spent
+}
+
+// Train spends one of the character's tree points on a tree; characters get one point per level
+func (b *Spellbook) Train(catalog *SpellTreeCatalog, treeKey string, characterLevel int) (*SpellTree, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tree := catalog.FindTree(b.Class, treeKey)
+	if tree == nil {
+		return nil, fmt.Errorf("%s has no spell list %q", b.Class, treeKey)
+	}
+	if b.pointsSpentLocked() >= characterLevel {
+		return nil, fmt.Errorf("no spell list points left")
+	}
+	if b.TreeLevels[tree.ID] >= maxTreeLevel {
+		return nil, fmt.Errorf("%s is already at its maximum level", tree.Name)
+	}
+	b.TreeLevels[tree.ID]++
+	return tree, nil
+}
+
+// Learn equips an unlocked spell
+func (b *Spellbook) Learn(catalog *SpellTreeCatalog, spellID int, characterLevel int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range b.Spells {
+		if id == spellID {
+			return fmt.Errorf("spell %d is already in your spellbook", spellID)
+		}
+	}
+	if !catalog.IsUnlocked(b.Class, characterLevel, b.TreeLevels, spellID) {
+		return fmt.Errorf("spell %d is not unlocked", spellID)
+	}
+	if len(b.Spells) >= maxSpellbookSpells {
+		return fmt.Errorf("spellbook is full")
+	}
+	b.Spells = append(b.Spells, spellID)
+	return nil
+}
+
+// Forget removes a spell from the spellbook
+func (b *Spellbook) Forget(spellID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, id := range b.Spells {
+		if id == spellID {
+			b.Spells = append(b.Spells[:i], b.Spells[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Respec clears all tree training and equipped spells, optionally changing class
+func (b *Spellbook) Respec(class PlayerClass) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Class = class
+	b.TreeLevels = make(map[int]int)
+	b.Spells = nil
+}
+
+// encodeTreeLevels serialises tree training as "id:level,id:level" for storage
+func encodeTreeLevels(levels map[int]int) string {
+	ids := make([]int, 0, len(levels))
+	for id := range levels {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%d:%d", id, levels[id]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeTreeLevels parses the output of encodeTreeLevels
+func decodeTreeLevels(s string) (map[int]int, error) {
+	levels := make(map[int]int)
+	if s == "" {
+		return levels, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		var id, level int
+		if _, err := fmt.Sscanf(part, "%d:%d", &id, &level); err != nil {
+			return nil, fmt.Errorf("invalid tree level %q", part)
+		}
+		levels[id] = level
+	}
+	return levels, nil
+}
+
+// encodeSpellIDs serialises equipped spells as a comma separated list
+func encodeSpellIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeSpellIDs parses the output of encodeSpellIDs
+func decodeSpellIDs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spell id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// addTestCaster adds a connected player whose spellbook holds the given spells
+func addTestCaster(gs *GameState, id int, spells ...int) *Player {
+	book := NewSpellbook(ClassMagician)
+	book.Spells = spells
+	player := &Player{ID: id, Name: "Caster", Health: 100, Level: 1, Spellbook: book, Conn: &captureConn{}}
+	gs.AddPlayer(player)
+	return player
+}
+
+func loadTestSpellManager(t *testing.T) *SpellManager {
+	t.Helper()
+	path, err := findContentFile(contentDir(), "Spells.dat")
+	if err != nil {
+		t.Fatalf("Spells.dat not found: %v", err)
+	}
+	sm := NewSpellManager()
+	if err := sm.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	return sm
+}
+
+func TestParseSpellTrees(t *testing.T) {
+	trees := loadTestSpellManager(t).GetTrees()
+
+	if len(trees.Trees) != 50 {
+		t.Errorf("Expected 50 spell lists, got %d", len(trees.Trees))
+	}
+	for class, expected := range map[PlayerClass][]int{
+		ClassMagician:  {1, 2, 3, 4},
+		ClassCleric:    {13, 5, 7, 6, 8},
+		ClassMentalist: {12, 10, 11, 9},
+		ClassArcanist:  {15, 14, 16, 17},
+	} {
+		got := trees.ClassTrees[class]
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected lists %v, got %v", class, expected, got)
+			continue
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%s: expected lists %v, got %v", class, expected, got)
+				break
+			}
+		}
+	}
+
+	fireLaw := trees.FindTree(ClassMagician, "fire law")
+	if fireLaw == nil || fireLaw.ID != 1 || fireLaw.Levels[1] != 159 || fireLaw.Levels[3] != 13 {
+		t.Errorf("Unexpected Fire Law tree: %+v", fireLaw)
+	}
+	if trees.FindTree(ClassCleric, "Fire Law") != nil {
+		t.Error("Expected Fire Law not to be a cleric list")
+	}
+}
+
+func TestSpellbookTrainAndLearn(t *testing.T) {
+	trees := loadTestSpellManager(t).GetTrees()
+	book := NewSpellbook(ClassMagician)
+
+	// Fire Law level 1 is spell 159, level 3 is spell 13
+	if err := book.Learn(trees, 159, 3); err == nil {
+		t.Error("Expected untrained spell to be rejected")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := book.Train(trees, "1", 3); err != nil {
+			t.Fatalf("Train failed: %v", err)
+		}
+	}
+	if _, err := book.Train(trees, "1", 3); err == nil {
+		t.Error("Expected training past the character level to fail")
+	}
+	if book.TreeLevel(1) != 3 {
+		t.Errorf("Expected Fire Law at level 3, got %d", book.TreeLevel(1))
+	}
+	if err := book.Learn(trees, 159, 3); err != nil {
+		t.Errorf("Expected level 1 spell to be learnable: %v", err)
+	}
+	if err := book.Learn(trees, 13, 2); err == nil {
+		t.Error("Expected spell above character level to be rejected")
+	}
+	if err := book.Learn(trees, 13, 3); err != nil {
+		t.Errorf("Expected level 3 spell to be learnable: %v", err)
+	}
+	if !book.Has(159) || !book.Has(13) {
+		t.Error("Expected both spells to be equipped")
+	}
+
+	book.Respec(ClassCleric)
+	if book.Has(159) || book.PointsSpent() != 0 || book.Class != ClassCleric {
+		t.Error("Expected respec to clear spells and training")
+	}
+}
+
+func TestCastSpellRequiresSpellbook(t *testing.T) {
+	gs := NewGameState()
+	addTestCaster(gs, 1, 1)
+
+	if _, err := gs.CastSpell(1, 2, 0, 0, 0); err == nil {
+		t.Error("Expected spell outside the spellbook to be rejected")
+	}
+	if _, err := gs.CastSpell(1, 1, 0, 0, 0); err != nil {
+		t.Errorf("Expected spellbook spell to be cast: %v", err)
+	}
+	if _, err := gs.CastSpell(9, 1, 0, 0, 0); err == nil {
+		t.Error("Expected unknown caster to be rejected")
+	}
+}
+
+func TestSpellbookPersistence(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	book := NewSpellbook(ClassMentalist)
+	book.TreeLevels[12] = 3
+	book.TreeLevels[10] = 1
+	book.Spells = []int{40, 41}
+
+	if err := SaveSpellbook(5, book); err != nil {
+		t.Fatalf("SaveSpellbook failed: %v", err)
+	}
+	book.Spells = []int{42}
+	if err := SaveSpellbook(5, book); err != nil {
+		t.Fatalf("SaveSpellbook update failed: %v", err)
+	}
+
+	loaded, err := LoadSpellbook(5)
+	if err != nil {
+		t.Fatalf("LoadSpellbook failed: %v", err)
+	}
+	if loaded.Class != ClassMentalist || loaded.TreeLevels[12] != 3 || loaded.TreeLevels[10] != 1 {
+		t.Errorf("Unexpected loaded spellbook: %+v", loaded)
+	}
+	if len(loaded.Spells) != 1 || loaded.Spells[0] != 42 {
+		t.Errorf("Expected equipped spells [42], got %v", loaded.Spells)
+	}
+}
+
+func TestSpellbookChatCommands(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	gs.SpellSystem.SpellManager = loadTestSpellManager(t)
+	player := addTestCaster(gs, 1)
+	player.Level = 2
+	conn := player.Conn.(*captureConn)
+
+	for _, command := range []string{"!train Fire Law", "!learn 159", "!respec"} {
+		conn.written.Reset()
+		if !handleChatCommand(command, player, gs) {
+			t.Fatalf("Expected %s to be handled", command)
+		}
+		if command == "!learn 159" && !strings.Contains(conn.written.String(), "Added") {
+			t.Errorf("Unexpected reply to %s: %s", command, conn.written.String())
+		}
+	}
+	if player.Spellbook.Has(159) {
+		t.Error("Expected !respec to clear the spellbook")
+	}
+
+	if handleChatCommand("hello", player, gs) {
+		t.Error("Expected plain chat not to be treated as a command")
+	}
+	conn.written.Reset()
+	handleChatCommand("!bogus", player, gs)
+	if !strings.Contains(conn.written.String(), "Unknown command") {
+		t.Errorf("Expected unknown command reply, got %s", conn.written.String())
+	}
+}
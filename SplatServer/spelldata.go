@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// spellTypeNames maps the type key in Spells.dat to a SpellType
+var spellTypeNames = map[string]SpellType{
+	"projectile": SpellTypeProjectile,
+	"wall":       SpellTypeWall,
+	"healing":    SpellTypeHealing,
+	"effect":     SpellTypeEffect,
+	"bolt":       SpellTypeBolt,
+	"target":     SpellTypeTarget,
+	"dispell":    SpellTypeDispell,
+	"teleport":   SpellTypeTeleport,
+	"rune":       SpellTypeRune,
+}
+
+// Effect keys in Spells.dat, numbered as MageServer's SpellEffectType
+const (
+	spellDataEffectHinder    = 11
+	spellDataEffectResurrect = 14
+)
+
+// defaultProjectileLifetime bounds the range of projectiles, which Spells.dat leaves open-ended
+const defaultProjectileLifetime = 2 * time.Second
+
+// LoadSpellFile parses spell definitions from a Spells.dat file
+func LoadSpellFile(path string) ([]*Spell, error) {
+	ini, err := LoadIniFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpellDefs(ini)
+}
+
+// parseSpellDefs builds spells from the [spelldefs] and [spellNN] sections
+func parseSpellDefs(ini *IniFile) ([]*Spell, error) {
+	count := ini.Int("spelldefs", "numspells", 0)
+	if count <= 0 {
+		return nil, fmt.Errorf("no spells defined in [spelldefs]")
+	}
+
+	spells := make([]*Spell, 0, count)
+	for id := 1; id <= count; id++ {
+		section := fmt.Sprintf("spell%02d", id)
+		if !ini.HasSection(section) {
+			return nil, fmt.Errorf("missing section [%s]", section)
+		}
+		spell, err := parseSpell(ini, section, id)
+		if err != nil {
+			return nil, err
+		}
+		spells = append(spells, spell)
+	}
+	return spells, nil
+}
+
+// parseSpell converts one [spellNN] section into a Spell
+func parseSpell(ini *IniFile, section string, id int) (*Spell, error) {
+	typeName := strings.ToLower(ini.String(section, "type", ""))
+	spellType, ok := spellTypeNames[typeName]
+	if !ok {
+		return nil, fmt.Errorf("[%s] unknown spell type %q", section, typeName)
+	}
+
+	spell := &Spell{
+		ID:           id,
+		Name:         ini.String(section, "name", section),
+		Type:         spellType,
+		Duration:     time.Duration(ini.Int(section, "duration_timer", 0)) * time.Millisecond,
+		Cooldown:     time.Duration(ini.Int(section, "fatigue", 0)) * time.Millisecond,
+		Speed:        ini.Float(section, "velocity", 0),
+		Range:        ini.Float(section, "range", 0),
+		HitPoints:    ini.Int(section, "hit_points", 0),
+		Length:       ini.Float(section, "length", 0),
+		Thick:        ini.Float(section, "thick", 0),
+		Height:       ini.Float(section, "max_wallheight", 0),
+		CastDistance: ini.Float(section, "cast_distance", 0),
+		Width:        ini.Float(section, "width", 0),
+		Radius:       ini.Float(section, "effect_radius", 0),
+		Damage:       averageDamage(ini, section),
+	}
+	spell.Description = fmt.Sprintf("%s spell", typeName)
+
+	if element := ini.Int(section, "element", 0); element > 0 && element <= int(SpellElementMana) {
+		spell.ElementType = SpellElementType(element)
+	}
+	if spell.Height == 0 {
+		spell.Height = ini.Float(section, "wallheight", 0)
+	}
+
+	switch ini.Int(section, "friendly", 0) {
+	case 1:
+		spell.FriendlyType = SpellFriendlyAlly
+	case 2:
+		spell.FriendlyType = SpellFriendlyDead
+	default:
+		spell.FriendlyType = SpellFriendlyEnemy
+	}
+
+	switch spellType {
+	case SpellTypeProjectile:
+		spell.ProjectileType = SpellProjectileBall
+		if spell.Range == 0 {
+			spell.Range = spell.Speed * defaultProjectileLifetime.Seconds()
+		}
+		if spell.Duration == 0 {
+			spell.Duration = defaultProjectileLifetime
+		}
+	case SpellTypeBolt:
+		spell.ProjectileType = SpellProjectileBolt
+	case SpellTypeRune:
+		spell.TriggerSpellID = ini.Int(section, "death_spell_effect", 0)
+	case SpellTypeTarget:
+		spell.TargetSpellID = ini.Int(section, "target_spell_effect", 0)
+	case SpellTypeEffect:
+		switch ini.Int(section, "effect", 0) {
+		case spellDataEffectHinder:
+			spell.EffectType = SpellEffectHinder
+		case spellDataEffectResurrect:
+			spell.EffectType = SpellEffectResurrect
+			spell.RaisePercent = ini.Int(section, "level", 0)
+		}
+	case SpellTypeHealing:
+		spell.FriendlyType = SpellFriendlyAlly
+		spell.Healing = (ini.Int(section, "min", 0) + ini.Int(section, "max", 0)) / 2
+	}
+
+	switch {
+	case spell.Healing > 0:
+		spell.EffectType = SpellEffectHealing
+	case spell.Damage > 0:
+		spell.EffectType = SpellEffectDamage
+	}
+
+	return spell, nil
+}
+
+// averageDamage returns the expected damage of a spell from its dice or min/max range
+func averageDamage(ini *IniFile, section string) int {
+	minDamage := ini.Int(section, "min_damage", 0)
+	maxDamage := ini.Int(section, "max_damage", 0)
+	if maxDamage > 0 {
+		return (minDamage + maxDamage) / 2
+	}
+
+	dice := ini.Int(section, "damage_dice", 0)
+	numDice := ini.Int(section, "damage_num_dice", 0)
+	return ini.Int(section, "damage_base", 0) + numDice*(dice+1)/2
+}
+
+// LoadSpells registers spells with the manager, replacing any with the same ID
+func (sm *SpellManager) LoadSpells(spells []*Spell) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for _, spell := range spells {
+		sm.Spells[spell.ID] = spell
+	}
+}
+
+// LoadFile loads spells and spell lists from a Spells.dat file
+func (sm *SpellManager) LoadFile(path string) error {
+	ini, err := LoadIniFile(path)
+	if err != nil {
+		return err
+	}
+	spells, err := parseSpellDefs(ini)
+	if err != nil {
+		return err
+	}
+	trees, err := parseSpellTrees(ini)
+	if err != nil {
+		return err
+	}
+
+	sm.LoadSpells(spells)
+	sm.SetTrees(trees)
+	return nil
+}
+
+// SetTrees replaces the spell tree catalog
+func (sm *SpellManager) SetTrees(trees *SpellTreeCatalog) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.Trees = trees
+}
+
+// GetTrees returns the spell tree catalog
+func (sm *SpellManager) GetTrees() *SpellTreeCatalog {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.Trees
+}
+
+// loadSpellContent replaces the built-in spells with Spells.dat when it is available
+func loadSpellContent(gs *GameState) {
+	path, err := findContentFile(contentDir(), "Spells.dat")
+	if err != nil {
+		fmt.Printf("SplatServer: Spells.dat not found, using built-in spells: %v\n", err)
+		return
+	}
+
+	if err := gs.SpellSystem.SpellManager.LoadFile(path); err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+		return
+	}
+	fmt.Println("SplatServer: Loaded", len(gs.SpellSystem.SpellManager.GetAllSpells()), "spells from", path)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func loadTestSpells(t *testing.T) []*Spell {
+	t.Helper()
+	path, err := findContentFile(contentDir(), "Spells.dat")
+	if err != nil {
+		t.Fatalf("Spells.dat not found: %v", err)
+	}
+	spells, err := LoadSpellFile(path)
+	if err != nil {
+		t.Fatalf("LoadSpellFile failed: %v", err)
+	}
+	return spells
+}
+
+func TestLoadSpellFile(t *testing.T) {
+	spells := loadTestSpells(t)
+	if len(spells) != 350 {
+		t.Fatalf("Expected 350 spells, got %d", len(spells))
+	}
+
+	counts := make(map[SpellType]int)
+	for _, spell := range spells {
+		counts[spell.Type]++
+	}
+	if counts[SpellTypeWall] != 20 {
+		t.Errorf("Expected 20 wall spells, got %d", counts[SpellTypeWall])
+	}
+	if counts[SpellTypeBolt] != 25 {
+		t.Errorf("Expected 25 bolt spells, got %d", counts[SpellTypeBolt])
+	}
+
+	fireBall := spells[0]
+	if fireBall.Name != "Fire Ball I" || fireBall.Type != SpellTypeProjectile {
+		t.Errorf("Unexpected first spell: %+v", fireBall)
+	}
+	// damage_base 27 plus 3d16 (8.5 each on average)
+	if fireBall.Damage != 52 {
+		t.Errorf("Expected Fire Ball I damage 52, got %d", fireBall.Damage)
+	}
+	if fireBall.ElementType != SpellElementFire {
+		t.Errorf("Expected fire element, got %d", fireBall.ElementType)
+	}
+
+	shield := spells[2]
+	if shield.Type != SpellTypeWall || shield.Length != 64 || shield.Duration != 10*time.Second {
+		t.Errorf("Unexpected Spell Shield definition: %+v", shield)
+	}
+
+	raise, raiseEffect := spells[286], spells[285]
+	if raise.Name != "Resurrect II" || raise.FriendlyType != SpellFriendlyDead || raise.TargetSpellID != 286 {
+		t.Errorf("Unexpected Resurrect II definition: %+v", raise)
+	}
+	if raiseEffect.EffectType != SpellEffectResurrect || raiseEffect.RaisePercent != 30 {
+		t.Errorf("Unexpected Resurrect II Effect definition: %+v", raiseEffect)
+	}
+}
+
+func TestSpellManagerLoadSpells(t *testing.T) {
+	sm := NewSpellManager()
+	sm.LoadSpells([]*Spell{{ID: 1, Name: "Replaced"}, {ID: 42, Name: "New"}})
+
+	if sm.GetSpell(1).Name != "Replaced" {
+		t.Error("Expected spell 1 to be replaced")
+	}
+	if sm.GetSpell(42) == nil {
+		t.Error("Expected spell 42 to be added")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StatisticSheet holds a player's combat counters, as in MageServer's StatisticSheet
+type StatisticSheet struct {
+	Kills        int
+	Deaths       int
+	Raises       int
+	DamageDone   int
+	DamageTaken  int
+	HealingDone  int
+	HealingTaken int
+	Experience   int // experience earned
+}
+
+// Add accumulates another sheet into this one
+func (s *StatisticSheet) Add(other StatisticSheet) {
+	s.Kills += other.Kills
+	s.Deaths += other.Deaths
+	s.Raises += other.Raises
+	s.DamageDone += other.DamageDone
+	s.DamageTaken += other.DamageTaken
+	s.HealingDone += other.HealingDone
+	s.HealingTaken += other.HealingTaken
+	s.Experience += other.Experience
+}
+
+// KillDeathRatio returns kills per death, counting no deaths as one
+func (s StatisticSheet) KillDeathRatio() float64 {
+	return float64(s.Kills) / float64(max(1, s.Deaths))
+}
+
+// LifetimeStatistics holds a character's combat totals across every match played
+type LifetimeStatistics struct {
+	StatisticSheet
+	Matches int
+}
+
+// killScore is the score awarded for each kill
+const killScore = 1
+
+// recordDamageLocked credits damage to both sides and a kill when the target drops;
+// the caller must hold a.mu
+func (a *Arena) recordDamageLocked(source, target *ArenaPlayer, damage int, killed bool) {
+	target.Statistics.DamageTaken += damage
+	if killed {
+		target.Statistics.Deaths++
+	}
+	if source == nil || source == target {
+		return
+	}
+	source.Statistics.DamageDone += damage
+	if killed {
+		source.Statistics.Kills++
+		source.Score += killScore
+	}
+}
+
+// recordHealingLocked credits healing to both sides; the caller must hold a.mu
+func (a *Arena) recordHealingLocked(source, target *ArenaPlayer, healed int) {
+	target.Statistics.HealingTaken += healed
+	if source != nil {
+		source.Statistics.HealingDone += healed
+	}
+}
+
+// recordRaiseLocked credits a raise to the player who cast it; the caller must hold a.mu
+func (a *Arena) recordRaiseLocked(source *ArenaPlayer) {
+	source.Statistics.Raises++
+}
+
+// ScoreEntry is a player's line on a match scoreboard
+type ScoreEntry struct {
+	PlayerID   int
+	Team       Team
+	Score      int
+	Statistics StatisticSheet
+	Bot        bool // bots' statistics are not saved
+}
+
+// Scoreboard returns the arena's players ordered by score, then kills, then fewest deaths
+func (a *Arena) Scoreboard() []ScoreEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.scoreboardLocked()
+}
+
+// scoreboardLocked builds the scoreboard; the caller must hold a.mu
+func (a *Arena) scoreboardLocked() []ScoreEntry {
+	entries := make([]ScoreEntry, 0, len(a.Players))
+	for _, player := range a.Players {
+		entries = append(entries, ScoreEntry{
+			PlayerID:   player.PlayerID,
+			Team:       player.Team,
+			Score:      player.Score,
+			Statistics: player.Statistics,
+			Bot:        player.Bot,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		x, y := entries[i], entries[j]
+		if x.Score != y.Score {
+			return x.Score > y.Score
+		}
+		if x.Statistics.Kills != y.Statistics.Kills {
+			return x.Statistics.Kills > y.Statistics.Kills
+		}
+		if x.Statistics.Deaths != y.Statistics.Deaths {
+			return x.Statistics.Deaths < y.Statistics.Deaths
+		}
+		return x.PlayerID < y.PlayerID
+	})
+	return entries
+}
+
+// TakeMatchStatistics returns a player's match statistics for recording, or false
+// if they were already handed out when the match ended
+func (a *Arena) TakeMatchStatistics(playerID int) (StatisticSheet, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, exists := a.Players[playerID]
+	if !exists || player.statisticsRecorded {
+		return StatisticSheet{}, false
+	}
+	player.statisticsRecorded = true
+	return player.Statistics, true
+}
+
+// RecordMatchStatistics saves a player's statistics for one match and adds them to
+// their lifetime totals, logging failures
+func RecordMatchStatistics(playerID, arenaID int, sheet StatisticSheet) {
+	if err := SaveMatchStatistics(playerID, arenaID, sheet); err != nil {
+		fmt.Printf("Failed to save match statistics for player %d: %v\n", playerID, err)
+	}
+}
+
+// EndMatch ends an arena's match and records its result and every player's statistics,
+// leaving out bots
+func EndMatch(arena *Arena) []ScoreEntry {
+	scoreboard := arena.EndArena()
+	for _, entry := range scoreboard {
+		if !entry.Bot {
+			RecordMatchStatistics(entry.PlayerID, arena.ID, entry.Statistics)
+		}
+	}
+	if result, ok := arena.takeResult(); ok {
+		RecordMatchResult(result)
+	}
+	return scoreboard
+}
+
+// describeStatistics formats a player's match and lifetime statistics as text
+func describeStatistics(player *Player, gs *GameState) string {
+	response := ""
+	if arena := gs.ArenaManager.FindPlayerArena(player.ID); arena != nil {
+		if arenaPlayer := arena.GetPlayer(player.ID); arenaPlayer != nil {
+			arena.mu.RLock()
+			sheet, score := arenaPlayer.Statistics, arenaPlayer.Score
+			arena.mu.RUnlock()
+			response += fmt.Sprintf("This match in %s (score %d):\n%s\n", arena.Name, score, formatStatisticSheet(sheet))
+		}
+	}
+
+	lifetime, err := LoadLifetimeStatistics(player.ID)
+	if err != nil {
+		fmt.Printf("Failed to load statistics for player %d: %v\n", player.ID, err)
+		return response + "Lifetime statistics are unavailable"
+	}
+	response += fmt.Sprintf("Lifetime (%d matches):\n%s", lifetime.Matches, formatStatisticSheet(lifetime.StatisticSheet))
+	return response
+}
+
+// formatStatisticSheet formats a sheet's counters as text
+func formatStatisticSheet(s StatisticSheet) string {
+	return fmt.Sprintf("  Kills %d, deaths %d (K/D %.2f), raises %d\n  Damage done %d, taken %d\n  Healing done %d, taken %d\n  Experience earned %d",
+		s.Kills, s.Deaths, s.KillDeathRatio(), s.Raises, s.DamageDone, s.DamageTaken, s.HealingDone, s.HealingTaken, s.Experience)
+}
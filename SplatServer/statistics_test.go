@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestCombatStatisticsRecorded(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamOrder)
+
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 30})
+	arena.applySpellLocked(3, arena.Players[2], &Spell{ID: 2, Name: "Heal", Healing: 20})
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 500})
+	arena.mu.Unlock()
+
+	attacker, victim, healer := arena.GetPlayer(1), arena.GetPlayer(2), arena.GetPlayer(3)
+	if attacker.Statistics.DamageDone != 120 || attacker.Statistics.Kills != 1 || attacker.Score != 1 {
+		t.Errorf("Unexpected attacker statistics %+v, score %d", attacker.Statistics, attacker.Score)
+	}
+	if victim.Statistics.DamageTaken != 120 || victim.Statistics.Deaths != 1 || victim.Statistics.HealingTaken != 20 {
+		t.Errorf("Unexpected victim statistics %+v", victim.Statistics)
+	}
+	if healer.Statistics.HealingDone != 20 {
+		t.Errorf("Unexpected healer statistics %+v", healer.Statistics)
+	}
+
+	scoreboard := arena.Scoreboard()
+	if len(scoreboard) != 3 || scoreboard[0].PlayerID != 1 || scoreboard[2].PlayerID != 2 {
+		t.Errorf("Unexpected scoreboard order %+v", scoreboard)
+	}
+}
+
+func TestEndArenaBroadcastsScoreboard(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	conn := &captureConn{}
+	arena.SetPlayerConn(1, conn)
+	arena.StartArena()
+
+	arena.mu.Lock()
+	arena.applySpellLocked(1, arena.Players[2], &Spell{ID: 1, Name: "Hit", Damage: 25})
+	arena.mu.Unlock()
+
+	scoreboard := arena.EndArena()
+	if len(scoreboard) != 2 {
+		t.Fatalf("Expected 2 scoreboard entries, got %d", len(scoreboard))
+	}
+
+	packet, err := DeserializePacket(bytes.NewReader(conn.written.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read scoreboard packet: %v", err)
+	}
+	if packet.Type != PacketScoreboard || len(packet.Data) != 6+2*37 {
+		t.Fatalf("Unexpected scoreboard packet type %d with %d bytes", packet.Type, len(packet.Data))
+	}
+	var damageDone int32
+	binary.Read(bytes.NewReader(packet.Data[6+9+12:]), binary.LittleEndian, &damageDone)
+	if damageDone != 25 {
+		t.Errorf("Expected first entry damage done 25, got %d", damageDone)
+	}
+
+	if _, ok := arena.TakeMatchStatistics(1); ok {
+		t.Error("Expected statistics handed out at match end not to be taken again")
+	}
+}
+
+func TestMatchStatisticsPersistence(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	RecordMatchStatistics(4, 1, StatisticSheet{Kills: 3, Deaths: 1, DamageDone: 200})
+	RecordMatchStatistics(4, 2, StatisticSheet{Kills: 1, Deaths: 2, HealingDone: 50})
+
+	lifetime, err := LoadLifetimeStatistics(4)
+	if err != nil {
+		t.Fatalf("LoadLifetimeStatistics failed: %v", err)
+	}
+	if lifetime.Matches != 2 || lifetime.Kills != 4 || lifetime.Deaths != 3 ||
+		lifetime.DamageDone != 200 || lifetime.HealingDone != 50 {
+		t.Errorf("Unexpected lifetime statistics %+v", lifetime)
+	}
+
+	if empty, err := LoadLifetimeStatistics(99); err != nil || empty.Matches != 0 {
+		t.Errorf("Expected empty statistics for a new player, got %+v, %v", empty, err)
+	}
+}
+
+func TestStatsMessage(t *testing.T) {
+	dbType = "sqlite"
+	InitDB()
+	CreateTables()
+	defer db.Close()
+
+	gs := NewGameState()
+	player := addTestCaster(gs, 1)
+	arena := gs.ArenaManager.CreateArena(1, "Stats Arena", 4, 0)
+	arena.AddPlayer(1, TeamChaos)
+	RecordMatchStatistics(1, 1, StatisticSheet{Kills: 2})
+
+	handleStats(&Message{Type: MsgStats}, player, gs)
+	reply := player.Conn.(*captureConn).written.String()
+	if !strings.Contains(reply, "This match in Stats Arena") || !strings.Contains(reply, "Lifetime (1 matches)") {
+		t.Errorf("Unexpected stats reply %q", reply)
+	}
+}
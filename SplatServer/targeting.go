@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// lineOfSightLocked reports whether nothing solid lies between two points;
+// the caller must hold a.mu
+func (a *Arena) lineOfSightLocked(x1, y1, x2, y2 float64) bool {
+	if a.Geometry != nil {
+		if _, hit := a.Geometry.TraceSegment(x1, y1, x2, y2); hit {
+			return false
+		}
+	}
+	wall, _ := a.firstWallOnSegmentLocked(x1, y1, x2, y2)
+	return wall == nil
+}
+
+// HasLineOfSight reports whether nothing solid lies between two points
+func (a *Arena) HasLineOfSight(x1, y1, x2, y2 float64) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lineOfSightLocked(x1, y1, x2, y2)
+}
+
+// inRange checks a distance against a spell's range, where 0 means unlimited
+func inRange(spell *Spell, x1, y1, x2, y2 float64) bool {
+	return spell.Range <= 0 || math.Hypot(x2-x1, y2-y1) <= spell.Range
+}
+
+// ValidateTarget checks that a targeted spell may be cast by one player on another:
+// the target must be in this arena, in range, visible and pass the friendly rule
+func (a *Arena) ValidateTarget(casterID int, spell *Spell, targetID int) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, err := a.validateTargetLocked(casterID, spell, targetID)
+	return err
+}
+
+// validateTargetLocked returns the validated target; the caller must hold a.mu
+func (a *Arena) validateTargetLocked(casterID int, spell *Spell, targetID int) (*ArenaPlayer, error) {
+	caster, exists := a.Players[casterID]
+	if !exists {
+		return nil, fmt.Errorf("player %d is not in arena %d", casterID, a.ID)
+	}
+	target, exists := a.Players[targetID]
+	if !exists {
+		return nil, fmt.Errorf("target %d is not in arena %d", targetID, a.ID)
+	}
+
+	if !inRange(spell, caster.X, caster.Y, target.X, target.Y) {
+		return nil, fmt.Errorf("target %d is out of range", targetID)
+	}
+	if targetID != casterID && !a.lineOfSightLocked(caster.X, caster.Y, target.X, target.Y) {
+		return nil, fmt.Errorf("target %d is not visible", targetID)
+	}
+
+	friendly := targetID == casterID || a.alliesLocked(caster, target)
+	if spell.FriendlyType != SpellFriendlyDead && target.Dead {
+		return nil, fmt.Errorf("target %d is dead", targetID)
+	}
+	switch spell.FriendlyType {
+	case SpellFriendlyEnemy:
+		if friendly {
+			return nil, fmt.Errorf("%s can only target enemies", spell.Name)
+		}
+	case SpellFriendlyAlly:
+		if !friendly {
+			return nil, fmt.Errorf("%s can only target allies", spell.Name)
+		}
+		if targetID != casterID && a.Ruleset.Rules.Has(ArenaRuleNoFriendlyOther) {
+			return nil, fmt.Errorf("%s can only target yourself in %s", spell.Name, a.Name)
+		}
+	case SpellFriendlySelf:
+		if targetID != casterID {
+			return nil, fmt.Errorf("%s can only target yourself", spell.Name)
+		}
+	case SpellFriendlyDead:
+		if !target.Dead || !friendly {
+			return nil, fmt.Errorf("%s can only target dead allies", spell.Name)
+		}
+		if a.Ruleset.Rules.Has(ArenaRuleNoRaiseCall) {
+			return nil, fmt.Errorf("raising is not allowed in %s", a.Name)
+		}
+	}
+
+	return target, nil
+}
+
+// ApplyTargetSpell validates a target and applies a spell's effect to it
+func (a *Arena) ApplyTargetSpell(casterID int, spell, effect *Spell, targetID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	target, err := a.validateTargetLocked(casterID, spell, targetID)
+	if err != nil {
+		return err
+	}
+	a.applySpellLocked(casterID, target, effect)
+	return nil
+}
+
+// ValidateTeleport checks that a player may teleport to a destination:
+// it must be in range, visible and clear of solid geometry and walls
+func (a *Arena) ValidateTeleport(playerID int, spell *Spell, x, y float64) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.validateTeleportLocked(playerID, spell, x, y)
+}
+
+// validateTeleportLocked checks a teleport destination; the caller must hold a.mu
+func (a *Arena) validateTeleportLocked(playerID int, spell *Spell, x, y float64) error {
+	player, exists := a.Players[playerID]
+	if !exists {
+		return fmt.Errorf("player %d is not in arena %d", playerID, a.ID)
+	}
+
+	if !inRange(spell, player.X, player.Y, x, y) {
+		return fmt.Errorf("destination (%.0f, %.0f) is out of range", x, y)
+	}
+	if !a.lineOfSightLocked(player.X, player.Y, x, y) {
+		return fmt.Errorf("destination (%.0f, %.0f) is not visible", x, y)
+	}
+	if a.Geometry != nil && a.Geometry.IsSolid(x, y, playerRadius) {
+		return fmt.Errorf("destination (%.0f, %.0f) is inside solid geometry", x, y)
+	}
+	if a.wallAtLocked(x, y, playerRadius) != nil {
+		return fmt.Errorf("destination (%.0f, %.0f) is inside a wall", x, y)
+	}
+	return nil
+}
+
+// TeleportPlayer validates a destination and moves the player there
+func (a *Arena) TeleportPlayer(playerID int, spell *Spell, x, y float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.validateTeleportLocked(playerID, spell, x, y); err != nil {
+		return err
+	}
+	a.movePlayerLocked(a.Players[playerID], x, y)
+	return nil
+}
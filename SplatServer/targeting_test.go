@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidateTarget(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamChaos)
+	arena.UpdatePlayerPosition(2, 100, 0)
+	arena.UpdatePlayerPosition(3, -100, 0)
+
+	harm := &Spell{Name: "Harm", Type: SpellTypeTarget, FriendlyType: SpellFriendlyEnemy, Range: 400}
+	heal := &Spell{Name: "Heal Other", Type: SpellTypeTarget, FriendlyType: SpellFriendlyAlly, Range: 400}
+
+	if err := arena.ValidateTarget(1, harm, 2); err != nil {
+		t.Errorf("Expected enemy target to be valid: %v", err)
+	}
+	if err := arena.ValidateTarget(1, harm, 3); err == nil {
+		t.Error("Expected enemy spell on a teammate to be rejected")
+	}
+	if err := arena.ValidateTarget(1, heal, 3); err != nil {
+		t.Errorf("Expected ally target to be valid: %v", err)
+	}
+	if err := arena.ValidateTarget(1, heal, 2); err == nil {
+		t.Error("Expected friendly spell on an enemy to be rejected")
+	}
+	if err := arena.ValidateTarget(1, harm, 99); err == nil {
+		t.Error("Expected missing target to be rejected")
+	}
+
+	arena.UpdatePlayerPosition(2, 1000, 0)
+	if err := arena.ValidateTarget(1, harm, 2); err == nil {
+		t.Error("Expected out of range target to be rejected")
+	}
+
+	arena.UpdatePlayerPosition(2, 100, 0)
+	arena.Geometry = blockAtX(50)
+	if err := arena.ValidateTarget(1, harm, 2); err == nil {
+		t.Error("Expected target behind geometry to be rejected")
+	}
+}
+
+func TestTeleportPlayer(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	teleport := &Spell{Name: "Teleport", Type: SpellTypeTeleport, Range: 500}
+
+	if err := arena.TeleportPlayer(1, teleport, 1000, 0); err == nil {
+		t.Error("Expected out of range destination to be rejected")
+	}
+
+	arena.Geometry = blockAtX(300)
+	if err := arena.TeleportPlayer(1, teleport, 290, 0); err == nil {
+		t.Error("Expected solid destination to be rejected")
+	}
+	if err := arena.TeleportPlayer(1, teleport, 400, 0); err == nil {
+		t.Error("Expected destination behind geometry to be rejected")
+	}
+
+	if err := arena.TeleportPlayer(1, teleport, 200, 50); err != nil {
+		t.Fatalf("Expected clear destination to be accepted: %v", err)
+	}
+	if p := arena.GetPlayer(1); p.X != 200 || p.Y != 50 {
+		t.Errorf("Expected player at (200, 50), got (%.0f, %.0f)", p.X, p.Y)
+	}
+}
+
+func TestCastTargetSpellKeepsCooldownOnFailure(t *testing.T) {
+	gs := NewGameState()
+	harm := &Spell{ID: 400, Name: "Harm", Type: SpellTypeTarget, FriendlyType: SpellFriendlyEnemy, Damage: 20, Range: 400}
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{harm})
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 100, 0)
+	addTestCaster(gs, 1, 400)
+
+	if _, err := gs.CastSpell(1, 400, 0, 0, 7); err == nil {
+		t.Fatal("Expected cast on a missing target to fail")
+	}
+	if !gs.SpellSystem.canCastSpell(1, 400) {
+		t.Error("Expected a rejected cast not to start the cooldown")
+	}
+
+	if _, err := gs.CastSpell(1, 400, 0, 0, 2); err != nil {
+		t.Fatalf("Failed to cast on a valid target: %v", err)
+	}
+	if arena.GetPlayer(2).Health != 80 {
+		t.Errorf("Expected target health 80, got %d", arena.GetPlayer(2).Health)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Misc.dat layout, as read by MageServer's Grid.LoadThins
+const (
+	thinCount = 250 // thin slots at the start of Misc.dat
+	thinBytes = 92  // twenty-three little-endian int32 fields per thin
+)
+
+// thinHitPoints is the health THIN_HIT_POINTS gives barrier thins, making them
+// destructible. Misc.dat records no health for thins and MageServer's ThinDamage only
+// ever damages spell walls, so by default barriers stand for good
+var thinHitPoints = loadThinHitPoints()
+
+// loadThinHitPoints reads THIN_HIT_POINTS, falling back to 0 for unbreakable barriers
+func loadThinHitPoints() int {
+	value := getEnv("THIN_HIT_POINTS", "0")
+	hitPoints, err := strconv.Atoi(value)
+	if err != nil || hitPoints < 0 {
+		fmt.Printf("Invalid THIN_HIT_POINTS %q, using 0\n", value)
+		return 0
+	}
+	return hitPoints
+}
+
+// Thin is a flat panel standing in a grid, such as a door, a switch or a teleporter
+// pad, as in MageServer's Thin. It runs from (X1, Y1) to (X2, Y2) and rises Tall units
+// from Z
+type Thin struct {
+	ID               int // slot in Misc.dat, from 1
+	X1, Y1, X2, Y2   int
+	Z, Tall          int
+	TextureID        int
+	TriggerID        int // trigger the thin opens or sets off, 0 for none
+	BlockPlayers     bool
+	BlockProjectiles bool
+}
+
+// Barrier reports whether the thin is a plain barrier: it blocks players or spells and
+// is not a door, switch or pad
+func (t *Thin) Barrier() bool {
+	return t.TriggerID == 0 && (t.BlockPlayers || t.BlockProjectiles)
+}
+
+// Box returns the box the panel fills where it stands
+func (t *Thin) Box() OrientedBox {
+	return panelBox(float64(t.X1), float64(t.Y1), float64(t.X2), float64(t.Y2), float64(t.Z), float64(t.Tall))
+}
+
+// panelBox returns the box filled by a panel from (x1, y1) to (x2, y2) rising tall units
+// from z, given doorThickness since panels are flat in Misc.dat
+func panelBox(x1, y1, x2, y2, z, tall float64) OrientedBox {
+	return OrientedBox{
+		Center:  Vec3{(x1 + x2) / 2, (y1 + y2) / 2, z + tall/2},
+		Extents: Vec3{math.Hypot(x2-x1, y2-y1) / 2, doorThickness / 2, tall / 2},
+		Angle:   math.Atan2(y2-y1, x2-x1),
+	}
+}
+
+// Length returns the length of the panel along the ground
+func (t *Thin) Length() float64 {
+	return math.Hypot(float64(t.X2-t.X1), float64(t.Y2-t.Y1))
+}
+
+// DistanceTo returns the distance along the ground from a point to the panel
+func (t *Thin) DistanceTo(x, y float64) float64 {
+	x1, y1 := float64(t.X1), float64(t.Y1)
+	dx, dy := float64(t.X2)-x1, float64(t.Y2)-y1
+	along := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		along = math.Max(0, math.Min(1, ((x-x1)*dx+(y-y1)*dy)/lengthSq))
+	}
+	return math.Hypot(x-(x1+along*dx), y-(y1+along*dy))
+}
+
+// Crosses reports whether a move from one point to another passes through the panel
+func (t *Thin) Crosses(x1, y1, x2, y2 float64) bool {
+	ax, ay := float64(t.X1), float64(t.Y1)
+	bx, by := float64(t.X2), float64(t.Y2)
+	side := func(px, py, qx, qy, rx, ry float64) float64 {
+		return (qx-px)*(ry-py) - (qy-py)*(rx-px)
+	}
+	d1, d2 := side(ax, ay, bx, by, x1, y1), side(ax, ay, bx, by, x2, y2)
+	d3, d4 := side(x1, y1, x2, y2, ax, ay), side(x1, y1, x2, y2, bx, by)
+	return d1*d2 < 0 && d3*d4 < 0
+}
+
+// LoadThinFile reads the thins from a grid's Misc.dat file
+func LoadThinFile(path string) ([]Thin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	thins, err := ParseThins(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return thins, nil
+}
+
+// ParseThins parses the thin slots of Misc.dat data, skipping empty ones. The rest of
+// the file is not read
+func ParseThins(r io.Reader) ([]Thin, error) {
+	data := make([]byte, thinCount*thinBytes)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("thin data is truncated: %v", err)
+	}
+
+	var thins []Thin
+	for i := 0; i < thinCount; i++ {
+		field := func(n int) int {
+			return int(int32(binary.LittleEndian.Uint32(data[i*thinBytes+n*4:])))
+		}
+		thin := Thin{
+			ID:               i + 1,
+			X1:               field(5),
+			Y1:               field(6),
+			X2:               field(7),
+			Y2:               field(8),
+			TextureID:        field(9),
+			Tall:             field(11),
+			TriggerID:        field(16),
+			Z:                field(19),
+			BlockPlayers:     field(21) > 0,
+			BlockProjectiles: field(22) > 0,
+		}
+		if thin.X1 == thin.X2 && thin.Y1 == thin.Y2 {
+			continue
+		}
+		thins = append(thins, thin)
+	}
+	return thins, nil
+}
+
+// ArenaThin is a destructible thin's state in one arena
+type ArenaThin struct {
+	Thin      *Thin
+	HitPoints int // 0 once destroyed
+}
+
+// Standing reports whether the thin has not been destroyed
+func (t *ArenaThin) Standing() bool {
+	return t.HitPoints > 0
+}
+
+// newArenaThins returns the grid's barrier thins at full health when THIN_HIT_POINTS
+// makes them destructible, and none otherwise
+func newArenaThins(grid *Grid) map[int]*ArenaThin {
+	thins := make(map[int]*ArenaThin)
+	if thinHitPoints <= 0 {
+		return thins
+	}
+	for i := range grid.Thins {
+		if thin := &grid.Thins[i]; thin.Barrier() {
+			thins[thin.ID] = &ArenaThin{Thin: thin, HitPoints: thinHitPoints}
+		}
+	}
+	return thins
+}
+
+// thinListLocked returns the arena's destructible thins in ID order; the caller must
+// hold a.mu
+func (a *Arena) thinListLocked() []*ArenaThin {
+	thins := make([]*ArenaThin, 0, len(a.Thins))
+	for _, thin := range a.Thins {
+		thins = append(thins, thin)
+	}
+	sort.Slice(thins, func(i, j int) bool {
+		return thins[i].Thin.ID < thins[j].Thin.ID
+	})
+	return thins
+}
+
+// firstThinOnSegmentLocked returns the nearest standing thin crossed by a sight line
+// between two points, as Grid.TraceSegment traces it; the caller must hold a.mu
+func (a *Arena) firstThinOnSegmentLocked(x1, y1, x2, y2 float64) (*ArenaThin, float64) {
+	if a.Grid == nil {
+		return nil, math.MaxFloat64
+	}
+	p1, p2 := a.Grid.sightLine(x1, y1, x2, y2)
+	var nearest *ArenaThin
+	nearestT := math.MaxFloat64
+	for _, thin := range a.Thins {
+		if !thin.Standing() {
+			continue
+		}
+		box := thin.Thin.Box()
+		if box.ContainsPoint(p1) {
+			continue
+		}
+		if t, hit := box.IntersectSegment(p1, p2); hit && t < nearestT {
+			nearest, nearestT = thin, t
+		}
+	}
+	return nearest, nearestT
+}
+
+// damageThinLocked applies spell damage to a thin and announces its health, clearing it
+// out of the way once destroyed. It returns true when the thin is destroyed; the caller
+// must hold a.mu
+func (a *Arena) damageThinLocked(thin *ArenaThin, casterID, damage int) bool {
+	if !thin.Standing() || damage <= 0 {
+		return false
+	}
+	thin.HitPoints = max(0, thin.HitPoints-damage)
+	a.broadcastLocked(BuildThinDamagePacket(a.ID, thin, casterID))
+	if thin.Standing() {
+		return false
+	}
+	a.applyTriggersLocked()
+	return true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestLoadShippedThins(t *testing.T) {
+	path, err := findContentFile(contentDir(), "Grids", "Grid04", "Misc.dat")
+	if err != nil {
+		t.Fatalf("Misc.dat for grid 4 not found: %v", err)
+	}
+	thins, err := LoadThinFile(path)
+	if err != nil {
+		t.Fatalf("LoadThinFile failed: %v", err)
+	}
+
+	// Thin 16 is the door opened by trigger 18
+	var door *Thin
+	for i := range thins {
+		if thins[i].ID == 16 {
+			door = &thins[i]
+		}
+	}
+	if door == nil || door.X1 != 2944 || door.Y1 != 4615 || door.X2 != 2881 || door.Tall != 128 || door.Z != 1 || door.TriggerID != 18 {
+		t.Fatalf("Unexpected door thin %+v", door)
+	}
+	if door.Length() != 63 {
+		t.Errorf("Expected a 63 unit door, got %.1f", door.Length())
+	}
+}
+
+func TestThinGeometry(t *testing.T) {
+	thin := Thin{X1: 0, Y1: 0, X2: 64, Y2: 0, Tall: 128}
+	if d := thin.DistanceTo(32, 10); math.Abs(d-10) > 1e-9 {
+		t.Errorf("Expected a distance of 10 across the panel, got %.2f", d)
+	}
+	if d := thin.DistanceTo(67, 4); math.Abs(d-5) > 1e-9 {
+		t.Errorf("Expected a distance of 5 past its end, got %.2f", d)
+	}
+	if !thin.Crosses(32, -10, 32, 10) {
+		t.Error("Expected a move across the panel to cross it")
+	}
+	if thin.Crosses(80, -10, 80, 10) || thin.Crosses(0, 10, 64, 10) {
+		t.Error("Expected moves beside the panel not to cross it")
+	}
+}
+
+func TestParseThinsTruncated(t *testing.T) {
+	if _, err := ParseThins(bytes.NewReader(make([]byte, thinBytes*10))); err == nil {
+		t.Error("Expected truncated thin data to fail")
+	}
+}
+
+// withThinHitPoints sets the health of barrier thins for the rest of a test
+func withThinHitPoints(t *testing.T, hitPoints int) {
+	saved := thinHitPoints
+	thinHitPoints = hitPoints
+	t.Cleanup(func() { thinHitPoints = saved })
+}
+
+// newThinArena returns an arena on the test grid with a barrier panel from (400, 64) to
+// (400, 128) with the given health and a door panel, which cannot be destroyed, and a
+// player at (300, 96)
+func newThinArena(t *testing.T, hitPoints int) *Arena {
+	withThinHitPoints(t, hitPoints)
+	grid := newTestGrid()
+	grid.Thins = []Thin{
+		{ID: 7, X1: 400, Y1: 64, X2: 400, Y2: 128, Tall: 128, BlockPlayers: true},
+		{ID: 8, X1: 500, Y1: 64, X2: 500, Y2: 128, Tall: 128, BlockPlayers: true, TriggerID: 1},
+	}
+
+	arena := newTestArena(1)
+	arena.Grid = grid.arenaCopy()
+	arena.Geometry = arena.Grid
+	arena.resetGridLocked()
+	arena.AddPlayer(1, TeamChaos)
+	arena.movePlayerLocked(arena.Players[1], 300, 96)
+	return arena
+}
+
+func TestBarrierThinsStandByDefault(t *testing.T) {
+	arena := newThinArena(t, 0)
+	if len(arena.Thins) != 0 {
+		t.Fatalf("Expected no destructible thins, got %+v", arena.Thins)
+	}
+	if arena.UpdatePlayerPosition(1, 450, 96) {
+		t.Error("Expected the barrier to block the move")
+	}
+	if result := arena.FireBolt(1, testBoltSpell(), 300, 96, 0); result.ThinID != 0 || result.EndX > 400 {
+		t.Errorf("Expected the bolt to stop at the barrier without damaging it, got %+v", result)
+	}
+}
+
+func TestDestructibleThins(t *testing.T) {
+	arena := newThinArena(t, 200)
+	if len(arena.Thins) != 1 || arena.Thins[7].HitPoints != thinHitPoints {
+		t.Fatalf("Expected only thin 7 to be destructible, got %+v", arena.Thins)
+	}
+	if arena.UpdatePlayerPosition(1, 450, 96) {
+		t.Fatal("Expected the standing thin to block the move")
+	}
+
+	bolt := testBoltSpell()
+	result := arena.FireBolt(1, bolt, 300, 96, 0)
+	if result.ThinID != 7 || math.Abs(result.EndX-(400-doorThickness/2)) > 1e-6 {
+		t.Fatalf("Expected the bolt to stop at thin 7, got %+v", result)
+	}
+	if hp := arena.Thins[7].HitPoints; hp != thinHitPoints-bolt.Damage {
+		t.Errorf("Expected the thin to have %d hit points, got %d", thinHitPoints-bolt.Damage, hp)
+	}
+
+	// Projectiles wear it down too until it breaks
+	arena.mu.Lock()
+	for arena.Thins[7].Standing() {
+		if !arena.resolveProjectileLocked(&SpellInstance{CasterID: 1, PrevX: 300, PrevY: 96, X: 420, Y: 96}, bolt) {
+			t.Fatal("Expected the projectile to hit the thin")
+		}
+	}
+	arena.mu.Unlock()
+	if !arena.UpdatePlayerPosition(1, 450, 96) {
+		t.Error("Expected the broken thin to let the player through")
+	}
+	if result := arena.FireBolt(1, bolt, 300, 96, 0); result.ThinID != 0 {
+		t.Errorf("Expected the bolt to pass the broken thin, got %+v", result)
+	}
+}
+
+func TestThinSnapshotAndReset(t *testing.T) {
+	arena := newThinArena(t, 200)
+	arena.mu.Lock()
+	arena.damageThinLocked(arena.Thins[7], 1, thinHitPoints)
+	arena.mu.Unlock()
+
+	// The snapshot ends with the thin count and each thin's ID and hit points
+	data := BuildArenaSnapshotPacket(arena).Data
+	var thins struct {
+		Count     uint16
+		ID        int32
+		HitPoints int32
+	}
+	binary.Read(bytes.NewReader(data[len(data)-10:]), binary.LittleEndian, &thins)
+	if thins.Count != 1 || thins.ID != 7 || thins.HitPoints != 0 {
+		t.Errorf("Unexpected thin state %+v", thins)
+	}
+
+	arena.mu.Lock()
+	arena.resetLocked()
+	arena.mu.Unlock()
+	if arena.Thins[7].HitPoints != thinHitPoints || arena.UpdatePlayerPosition(1, 450, 96) {
+		t.Error("Expected the reset arena's thin to stand again")
+	}
+}
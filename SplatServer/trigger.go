@@ -0,0 +1,486 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	triggerReach    = 96.0            // distance from a switch or door at which a player can use it
+	triggerCooldown = 2 * time.Second // time before a used trigger can be used again, as in MageServer
+	doorThickness   = 8.0             // depth given to doors and other thins, which are flat in Misc.dat
+	defaultDoorRate = 90.0            // units or degrees a second for doors without a rate
+	defaultLiftRate = 100.0           // units a second for elevators without a speed
+)
+
+// TriggerType is what a trigger does, as in MageServer's TriggerType
+type TriggerType int
+
+const (
+	TriggerDoor TriggerType = iota
+	TriggerElevator
+	TriggerTeleport
+	TriggerLever // type=null, a switch that only sets off its next trigger
+)
+
+// triggerTypes maps Trigger.dat type names to trigger types
+var triggerTypes = map[string]TriggerType{
+	"door":     TriggerDoor,
+	"elevator": TriggerElevator,
+	"teleport": TriggerTeleport,
+	"null":     TriggerLever,
+}
+
+// String returns the trigger type's Trigger.dat name
+func (t TriggerType) String() string {
+	for name, kind := range triggerTypes {
+		if kind == t {
+			return name
+		}
+	}
+	return fmt.Sprintf("trigger type %d", int(t))
+}
+
+// Door movements, from a door's slide_axis
+const (
+	doorSwing   = 0 // turns about its first end
+	doorSlideX  = 1 // slides along x
+	doorSlideY  = 2 // slides along y
+	doorSlideUp = 3 // rises
+)
+
+// TriggerDef is a trigger from a grid's Trigger.dat, as MageServer's Grid.LoadTriggers
+// reads it. Doors are the thins that carry the trigger's ID, and those thins are also
+// the switches and pads players use to set it off
+type TriggerDef struct {
+	ID          int
+	Type        TriggerType
+	Enabled     bool
+	InitialOn   bool
+	ResetTimer  time.Duration // time before an activated trigger turns off again, 0 to stay on
+	NextTrigger int           // trigger set off along with this one, 0 for none
+	OnSound     int
+	OffSound    int
+
+	// Doors
+	SlideAxis   int
+	SlideAmount float64 // units a sliding door moves
+	StartAngle  float64 // degrees a swinging door turns from, clockwise from north (-y)
+	EndAngle    float64 // degrees a swinging door turns to
+	Rate        float64 // door units or degrees a second, negative to swing anticlockwise; elevator units a second
+
+	// Elevators
+	Column1, Row1 int // first corner of the platform, in blocks
+	Column2, Row2 int // opposite corner of the platform
+	OffHeight     float64
+	OnHeight      float64
+	MoveCeiling   bool // the ceiling moves with the floor
+
+	// Teleporters
+	Destinations     []SpawnPoint // centres of the destination blocks
+	Random           int          // number of destinations to choose between at random
+	TeamDestinations bool         // destinations are chosen by the player's team
+	FromValhalla     bool         // the pad leads out of Valhalla
+}
+
+// LoadTriggerFile parses a grid's Trigger.dat file
+func LoadTriggerFile(path string) ([]TriggerDef, error) {
+	ini, err := LoadIniFile(path)
+	if err != nil {
+		return nil, err
+	}
+	triggers, err := parseTriggers(ini)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return triggers, nil
+}
+
+// parseTriggers reads the [triggerNN] sections listed by [triggerdefs], numbered from 1
+func parseTriggers(ini *IniFile) ([]TriggerDef, error) {
+	count := ini.Int("triggerdefs", "numtriggers", 0)
+	triggers := make([]TriggerDef, 0, count)
+	for id := 1; id <= count; id++ {
+		section := fmt.Sprintf("trigger%02d", id)
+		if !ini.HasSection(section) {
+			return nil, fmt.Errorf("[%s] is missing", section)
+		}
+		kind, ok := triggerTypes[strings.ToLower(ini.String(section, "type", ""))]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown type %q", section, ini.String(section, "type", ""))
+		}
+
+		def := TriggerDef{
+			ID:          id,
+			Type:        kind,
+			Enabled:     ini.Bool(section, "enabled", false),
+			InitialOn:   ini.Int(section, "initial_state", 0) != 0,
+			ResetTimer:  time.Duration(ini.Int(section, "reset_timer", 0)) * time.Millisecond,
+			NextTrigger: ini.Int(section, "next_trigger", 0),
+			OnSound:     ini.Int(section, "on_sound", 0),
+			OffSound:    ini.Int(section, "off_sound", 0),
+		}
+		switch kind {
+		case TriggerDoor:
+			def.SlideAxis = ini.Int(section, "slide_axis", doorSwing)
+			def.SlideAmount = ini.Float(section, "slide_amount", 0)
+			def.StartAngle = ini.Float(section, "start_angle", 0)
+			def.EndAngle = ini.Float(section, "end_angle", 0)
+			def.Rate = ini.Float(section, "max_rate", 0)
+			if def.SlideAxis == doorSwing {
+				def.Rate = ini.Float(section, "max_angle_rate", 0)
+			}
+		case TriggerElevator:
+			def.Column1, def.Row1 = ini.Int(section, "x1", 0), ini.Int(section, "y1", 0)
+			def.Column2, def.Row2 = ini.Int(section, "x2", 0), ini.Int(section, "y2", 0)
+			def.OffHeight = ini.Float(section, "off_height", 0)
+			def.OnHeight = ini.Float(section, "on_height", 0)
+			def.Rate = ini.Float(section, "speed", 0)
+			def.MoveCeiling = ini.Bool(section, "move_ceiling", false)
+		case TriggerTeleport:
+			for n := 0; ; n++ {
+				x, y := fmt.Sprintf("x%d", n), fmt.Sprintf("y%d", n)
+				if _, ok := ini.Lookup(section, x); !ok {
+					break
+				}
+				def.Destinations = append(def.Destinations, SpawnPoint{
+					X: float64(ini.Int(section, x, 0))*blockSize + blockSize/2,
+					Y: float64(ini.Int(section, y, 0))*blockSize + blockSize/2,
+				})
+			}
+			def.Random = ini.Int(section, "random", 0)
+			def.TeamDestinations = ini.Bool(section, "team", false)
+			def.FromValhalla = ini.Bool(section, "valhalla", false)
+		}
+		triggers = append(triggers, def)
+	}
+	return triggers, nil
+}
+
+// sweep returns the degrees a swinging door turns when it opens, in the direction of its rate
+func (d *TriggerDef) sweep() float64 {
+	delta := math.Mod(math.Mod(d.EndAngle-d.StartAngle, 360)+360, 360)
+	if d.Rate < 0 && delta != 0 {
+		delta -= 360
+	}
+	return delta
+}
+
+// travel returns how far a door or elevator moves between off and on, in units or
+// degrees, or 0 for triggers that do not move
+func (d *TriggerDef) travel() float64 {
+	switch {
+	case d.Type == TriggerElevator:
+		return math.Abs(d.OnHeight - d.OffHeight)
+	case d.Type == TriggerDoor && d.SlideAxis == doorSwing:
+		return math.Abs(d.sweep())
+	case d.Type == TriggerDoor:
+		return math.Abs(d.SlideAmount)
+	}
+	return 0
+}
+
+// speed returns how fast a door or elevator moves, in units or degrees a second
+func (d *TriggerDef) speed() float64 {
+	switch {
+	case d.Rate != 0:
+		return math.Abs(d.Rate)
+	case d.Type == TriggerElevator:
+		return defaultLiftRate
+	}
+	return defaultDoorRate
+}
+
+// Trigger is a grid trigger's state in one arena
+type Trigger struct {
+	Def      *TriggerDef
+	Thins    []*Thin   // doors, switches and pads carrying the trigger's ID
+	On       bool      // set off and not yet reset
+	Position float64   // how far a door or elevator has moved, from 0 off to 1 on
+	ResetAt  time.Time // when an activated trigger turns off again, zero for never
+	ReadyAt  time.Time // when the trigger can next be used
+
+	updatedAt time.Time // when the door or elevator last moved
+}
+
+// newArenaTriggers returns the grid's triggers in their initial states
+func newArenaTriggers(grid *Grid) map[int]*Trigger {
+	triggers := make(map[int]*Trigger, len(grid.Triggers))
+	for i := range grid.Triggers {
+		def := &grid.Triggers[i]
+		trigger := &Trigger{Def: def, On: def.InitialOn}
+		if def.InitialOn {
+			trigger.Position = 1
+		}
+		for j := range grid.Thins {
+			if grid.Thins[j].TriggerID == def.ID {
+				trigger.Thins = append(trigger.Thins, &grid.Thins[j])
+			}
+		}
+		triggers[def.ID] = trigger
+	}
+	return triggers
+}
+
+// reachableFrom reports whether a player at a point can use one of the trigger's thins
+func (t *Trigger) reachableFrom(x, y float64) bool {
+	for _, thin := range t.Thins {
+		if thin.DistanceTo(x, y) <= triggerReach {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the position the trigger is moving toward
+func (t *Trigger) target() float64 {
+	if t.On {
+		return 1
+	}
+	return 0
+}
+
+// set turns the trigger on or off, starting its door or elevator moving from now if it
+// was at rest
+func (t *Trigger) set(on bool, now time.Time) {
+	if t.Position == t.target() {
+		t.updatedAt = now
+	}
+	t.On = on
+}
+
+// move advances a door or elevator toward its target, reporting whether it moved
+func (t *Trigger) move(now time.Time) bool {
+	elapsed := now.Sub(t.updatedAt).Seconds()
+	t.updatedAt = now
+	if t.Position == t.target() {
+		return false
+	}
+
+	step := 1.0
+	if travel := t.Def.travel(); travel > 0 {
+		step = t.Def.speed() * elapsed / travel
+	}
+	if t.On {
+		t.Position = math.Min(1, t.Position+step)
+	} else {
+		t.Position = math.Max(0, t.Position-step)
+	}
+	return true
+}
+
+// doorBox returns the box one of a door's panels fills at its current position
+func (t *Trigger) doorBox(thin *Thin) OrientedBox {
+	x1, y1 := float64(thin.X1), float64(thin.Y1)
+	x2, y2 := float64(thin.X2), float64(thin.Y2)
+	z := float64(thin.Z)
+	slide := t.Def.SlideAmount * t.Position
+	switch t.Def.SlideAxis {
+	case doorSwing:
+		length := thin.Length()
+		angle := math.Atan2(y2-y1, x2-x1) + t.Def.sweep()*t.Position*math.Pi/180
+		x2, y2 = x1+length*math.Cos(angle), y1+length*math.Sin(angle)
+	case doorSlideX:
+		x1, x2 = x1+slide, x2+slide
+	case doorSlideY:
+		y1, y2 = y1+slide, y2+slide
+	case doorSlideUp:
+		z += slide
+	}
+
+	return panelBox(x1, y1, x2, y2, z, float64(thin.Tall))
+}
+
+// liftBlocks moves the floors, and ceilings if the elevator carries them, of the blocks
+// under an elevator to its current height
+func (t *Trigger) liftBlocks(grid *Grid) {
+	def := t.Def
+	height := def.OffHeight + (def.OnHeight-def.OffHeight)*t.Position
+	for row := min(def.Row1, def.Row2); row <= max(def.Row1, def.Row2); row++ {
+		for column := min(def.Column1, def.Column2); column <= max(def.Column1, def.Column2); column++ {
+			block := grid.Block(column, row)
+			if block == nil {
+				continue
+			}
+			index := row*gridWidth + column
+			grid.floors[index] = height
+			if def.MoveCeiling {
+				grid.ceilings[index] = float64(block.CeilingZ()) + height - def.OffHeight
+			}
+		}
+	}
+}
+
+// resetGridLocked returns the arena's triggers and destructible thins to their initial
+// states; the caller must hold a.mu
+func (a *Arena) resetGridLocked() {
+	a.Triggers, a.Thins = nil, nil
+	if a.Grid != nil {
+		a.Triggers = newArenaTriggers(a.Grid)
+		a.Thins = newArenaThins(a.Grid)
+	}
+	a.applyTriggersLocked()
+}
+
+// applyTriggersLocked moves the arena's doors and elevator floors in its grid to their
+// current positions, alongside the barrier thins still standing; the caller must hold a.mu
+func (a *Arena) applyTriggersLocked() {
+	if a.Grid == nil {
+		return
+	}
+	a.Grid.floors, a.Grid.ceilings, a.Grid.obstacles = make(map[int]float64), make(map[int]float64), nil
+	for i := range a.Grid.Thins {
+		thin := &a.Grid.Thins[i]
+		if destructible, exists := a.Thins[thin.ID]; thin.Barrier() && (!exists || destructible.Standing()) {
+			a.Grid.obstacles = append(a.Grid.obstacles, thin.Box())
+		}
+	}
+	for _, trigger := range a.triggerListLocked() {
+		switch trigger.Def.Type {
+		case TriggerDoor:
+			for _, thin := range trigger.Thins {
+				a.Grid.obstacles = append(a.Grid.obstacles, trigger.doorBox(thin))
+			}
+		case TriggerElevator:
+			trigger.liftBlocks(a.Grid)
+		}
+	}
+}
+
+// triggerListLocked returns the arena's triggers in ID order; the caller must hold a.mu
+func (a *Arena) triggerListLocked() []*Trigger {
+	triggers := make([]*Trigger, 0, len(a.Triggers))
+	for _, trigger := range a.Triggers {
+		triggers = append(triggers, trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool {
+		return triggers[i].Def.ID < triggers[j].Def.ID
+	})
+	return triggers
+}
+
+// updateTriggersLocked turns off triggers whose reset timer has run out and moves doors
+// and elevators toward their positions; the caller must hold a.mu
+func (a *Arena) updateTriggersLocked(now time.Time) {
+	moved := false
+	for _, trigger := range a.triggerListLocked() {
+		if trigger.On && !trigger.ResetAt.IsZero() && !now.Before(trigger.ResetAt) {
+			trigger.set(false, now)
+			trigger.ResetAt = time.Time{}
+			a.broadcastLocked(BuildTriggerStatePacket(a.ID, trigger, nil))
+		}
+		moved = trigger.move(now) || moved
+	}
+	if moved {
+		a.applyTriggersLocked()
+	}
+}
+
+// ActivateTrigger has a player use a door, switch or teleporter pad within reach,
+// setting off any triggers chained to it
+func (a *Arena) ActivateTrigger(playerID, triggerID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, exists := a.Players[playerID]
+	if !exists {
+		return fmt.Errorf("player %d is not in arena %d", playerID, a.ID)
+	}
+	if player.Dead {
+		return fmt.Errorf("player %d is dead", playerID)
+	}
+	trigger, exists := a.Triggers[triggerID]
+	now := time.Now()
+	switch {
+	case !exists:
+		return fmt.Errorf("trigger %d does not exist", triggerID)
+	case !trigger.Def.Enabled:
+		return fmt.Errorf("trigger %d is disabled", triggerID)
+	case !trigger.reachableFrom(player.X, player.Y):
+		return fmt.Errorf("trigger %d is out of reach", triggerID)
+	case now.Before(trigger.ReadyAt):
+		return fmt.Errorf("trigger %d was used too recently", triggerID)
+	}
+
+	a.activateTriggerLocked(trigger, player, now, make(map[int]bool))
+	return nil
+}
+
+// TriggerState reports whether a trigger is on and whether its door or elevator is still
+// moving, and false when the arena has no such trigger
+func (a *Arena) TriggerState(triggerID int) (on, moving, exists bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	trigger, exists := a.Triggers[triggerID]
+	if !exists {
+		return false, false, false
+	}
+	return trigger.On, trigger.Position != trigger.target(), true
+}
+
+// activateTriggerLocked sets off a trigger and the chain after it, skipping triggers
+// already set off in this chain; the caller must hold a.mu
+func (a *Arena) activateTriggerLocked(trigger *Trigger, player *ArenaPlayer, now time.Time, seen map[int]bool) {
+	if seen[trigger.Def.ID] || !trigger.Def.Enabled {
+		return
+	}
+	seen[trigger.Def.ID] = true
+
+	if trigger.Def.Type == TriggerTeleport {
+		a.teleportLocked(trigger, player)
+	} else {
+		trigger.set(!trigger.On, now)
+		trigger.ResetAt = time.Time{}
+		if trigger.On && trigger.Def.ResetTimer > 0 {
+			trigger.ResetAt = now.Add(trigger.Def.ResetTimer)
+		}
+		trigger.ReadyAt = now.Add(triggerCooldown)
+		a.broadcastLocked(BuildTriggerStatePacket(a.ID, trigger, player))
+	}
+
+	if next, exists := a.Triggers[trigger.Def.NextTrigger]; exists {
+		a.activateTriggerLocked(next, player, now, seen)
+	}
+}
+
+// teleportLocked moves a player to one of a teleporter's destinations: the one for
+// their team, one of the first Random at random, or the first; the caller must hold a.mu
+func (a *Arena) teleportLocked(trigger *Trigger, player *ArenaPlayer) {
+	destinations := trigger.Def.Destinations
+	if len(destinations) == 0 {
+		return
+	}
+
+	index := 0
+	if trigger.Def.TeamDestinations {
+		index = int(player.Team)
+	} else if trigger.Def.Random > 1 {
+		index = rand.Intn(min(trigger.Def.Random, len(destinations)))
+	}
+	if index >= len(destinations) {
+		index = 0
+	}
+	a.movePlayerLocked(player, destinations[index].X, destinations[index].Y)
+	a.broadcastLocked(BuildTriggerStatePacket(a.ID, trigger, player))
+}
+
+// enterTeleportersLocked teleports a player whose move took them onto or through an
+// enabled teleporter pad; the caller must hold a.mu
+func (a *Arena) enterTeleportersLocked(player *ArenaPlayer, fromX, fromY float64) {
+	for _, trigger := range a.triggerListLocked() {
+		if trigger.Def.Type != TriggerTeleport || !trigger.Def.Enabled {
+			continue
+		}
+		for _, thin := range trigger.Thins {
+			if thin.Crosses(fromX, fromY, player.X, player.Y) || thin.DistanceTo(player.X, player.Y) <= playerRadius {
+				a.teleportLocked(trigger, player)
+				return
+			}
+		}
+	}
+}
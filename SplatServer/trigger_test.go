@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTriggerData is a Trigger.dat with a swinging door that resets after three
+// seconds, an elevator, a teleporter, a lever chained to the door and a disabled door
+const testTriggerData = `[triggerdefs]
+numtriggers=5
+[trigger01]
+type=door
+enabled=1
+reset_timer=3000
+slide_axis=0
+start_angle=180
+end_angle=90
+max_angle_rate=-90
+[trigger02]
+type=elevator
+enabled=1
+off_height=0
+on_height=128
+x1=12
+y1=1
+x2=12
+y2=1
+speed=100
+[trigger03]
+type=teleport
+enabled=1
+random=1
+x0=20
+y0=1
+[trigger04]
+type=null
+enabled=1
+next_trigger=1
+[trigger05]
+type=door
+enabled=0
+`
+
+// newTriggerArena returns an arena on the test grid with the test triggers: the door
+// panel runs from (640, 64) to (640, 128), the elevator lifts block (12, 1), the
+// teleporter pad stands at x=1000 and the lever switch at (600, 40)
+func newTriggerArena(t *testing.T) *Arena {
+	ini, err := ParseIni(strings.NewReader(testTriggerData))
+	if err != nil {
+		t.Fatalf("ParseIni failed: %v", err)
+	}
+	grid := newTestGrid()
+	if grid.Triggers, err = parseTriggers(ini); err != nil {
+		t.Fatalf("parseTriggers failed: %v", err)
+	}
+	grid.Thins = []Thin{
+		{ID: 1, X1: 640, Y1: 64, X2: 640, Y2: 128, Tall: 128, TriggerID: 1},
+		{ID: 2, X1: 760, Y1: 40, X2: 776, Y2: 40, Z: 40, Tall: 32, TriggerID: 2},
+		{ID: 3, X1: 1000, Y1: 64, X2: 1000, Y2: 128, Tall: 128, TriggerID: 3},
+		{ID: 4, X1: 600, Y1: 40, X2: 616, Y2: 40, Z: 40, Tall: 32, TriggerID: 4},
+		{ID: 5, X1: 100, Y1: 64, X2: 100, Y2: 128, Tall: 128, TriggerID: 5},
+	}
+
+	arena := newTestArena(1)
+	arena.Grid = grid.arenaCopy()
+	arena.Geometry = arena.Grid
+	arena.resetGridLocked()
+	arena.AddPlayer(1, TeamChaos)
+	return arena
+}
+
+// placeTriggerPlayer puts the test player at a point without walking them there
+func placeTriggerPlayer(arena *Arena, x, y float64) {
+	arena.mu.Lock()
+	defer arena.mu.Unlock()
+	arena.movePlayerLocked(arena.Players[1], x, y)
+}
+
+func TestLoadShippedTriggers(t *testing.T) {
+	for gridID := 0; gridID < 10; gridID++ {
+		path, err := findContentFile(contentDir(), "Grids", fmt.Sprintf("Grid%02d", gridID), "Trigger.dat")
+		if err != nil {
+			t.Fatalf("Trigger.dat for grid %d not found: %v", gridID, err)
+		}
+		if _, err := LoadTriggerFile(path); err != nil {
+			t.Errorf("LoadTriggerFile failed: %v", err)
+		}
+	}
+
+	grid := loadGrid(4)
+	if grid == nil {
+		t.Fatal("Grid 4 failed to load")
+	}
+	counts := make(map[TriggerType]int)
+	for _, def := range grid.Triggers {
+		counts[def.Type]++
+	}
+	if counts[TriggerDoor] != 71 || counts[TriggerElevator] != 16 || counts[TriggerTeleport] != 1 || counts[TriggerLever] != 3 {
+		t.Errorf("Unexpected trigger counts %v", counts)
+	}
+	if door := grid.Triggers[17]; door.ID != 18 || door.Type != TriggerDoor || door.sweep() != -90 || door.ResetTimer != 6*time.Second {
+		t.Errorf("Unexpected door %+v", door)
+	}
+
+	triggers := newArenaTriggers(grid)
+	if door := triggers[18]; len(door.Thins) != 1 || door.Thins[0].ID != 16 {
+		t.Errorf("Expected thin 16 to be trigger 18's door, got %+v", door.Thins)
+	}
+
+	// Splat Lake's second elevator lifts blocks (21..22, 42..44) from 84 to 321, and its
+	// last teleporter picks one of four destinations
+	grid = loadGrid(9)
+	if lift := grid.Triggers[5]; lift.Type != TriggerElevator || lift.Column1 != 21 || lift.Row2 != 44 || lift.OffHeight != 84 || lift.OnHeight != 321 {
+		t.Errorf("Unexpected elevator %+v", lift)
+	}
+	if pad := grid.Triggers[15]; pad.Type != TriggerTeleport || pad.Random != 4 || len(pad.Destinations) != 4 || !pad.FromValhalla || pad.Destinations[0].X != 60*64+32 {
+		t.Errorf("Unexpected teleporter %+v", pad)
+	}
+}
+
+func TestParseTriggersErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing section", "[triggerdefs]\nnumtriggers=2\n[trigger01]\ntype=door\n"},
+		{"unknown type", "[triggerdefs]\nnumtriggers=1\n[trigger01]\ntype=trapdoor\n"},
+	}
+
+	for _, tt := range tests {
+		ini, err := ParseIni(strings.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: ParseIni failed: %v", tt.name, err)
+		}
+		if _, err := parseTriggers(ini); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestDoorTrigger(t *testing.T) {
+	arena := newTriggerArena(t)
+	placeTriggerPlayer(arena, 600, 96)
+	if arena.UpdatePlayerPosition(1, 680, 96) {
+		t.Fatal("Expected the closed door to block the move")
+	}
+
+	if err := arena.ActivateTrigger(1, 1); err != nil {
+		t.Fatalf("ActivateTrigger failed: %v", err)
+	}
+	if err := arena.ActivateTrigger(1, 1); err == nil {
+		t.Error("Expected the door to be on cooldown")
+	}
+
+	// The door swings 90 degrees at 90 degrees a second, ending along y=64
+	now := time.Now()
+	arena.updateTriggersLocked(now.Add(500 * time.Millisecond))
+	if door := arena.Triggers[1]; !door.On || door.Position <= 0 || door.Position >= 1 {
+		t.Errorf("Expected the door to be half open, got %+v", door)
+	}
+	arena.updateTriggersLocked(now.Add(1100 * time.Millisecond))
+	if !arena.UpdatePlayerPosition(1, 680, 96) {
+		t.Fatal("Expected the open door to let the player through")
+	}
+	if _, hit := arena.Grid.TraceSegment(672, 40, 672, 90); !hit {
+		t.Error("Expected the open door to block sight across it")
+	}
+
+	// After three seconds it closes again
+	arena.updateTriggersLocked(now.Add(3100 * time.Millisecond))
+	arena.updateTriggersLocked(now.Add(4300 * time.Millisecond))
+	if door := arena.Triggers[1]; door.On || door.Position != 0 {
+		t.Errorf("Expected the door to have closed, got %+v", door)
+	}
+	if arena.UpdatePlayerPosition(1, 600, 96) {
+		t.Error("Expected the closed door to block the way back")
+	}
+}
+
+func TestElevatorTrigger(t *testing.T) {
+	arena := newTriggerArena(t)
+	placeTriggerPlayer(arena, 700, 96)
+	if err := arena.ActivateTrigger(1, 2); err != nil {
+		t.Fatalf("ActivateTrigger failed: %v", err)
+	}
+
+	now := time.Now()
+	arena.updateTriggersLocked(now.Add(640 * time.Millisecond))
+	if floor, _ := arena.Grid.FloorHeight(800, 96); floor < 60 || floor > 68 {
+		t.Errorf("Expected the elevator halfway up, got %.1f", floor)
+	}
+	arena.updateTriggersLocked(now.Add(2 * time.Second))
+	if floor, _ := arena.Grid.FloorHeight(800, 96); floor != 128 {
+		t.Errorf("Expected the elevator at the top, got %.1f", floor)
+	}
+	if arena.UpdatePlayerPosition(1, 800, 96) {
+		t.Error("Expected the raised elevator to be too high to step onto")
+	}
+
+	// Other arenas on the same grid are unaffected
+	other := arena.Grid.arenaCopy()
+	if floor, _ := other.FloorHeight(800, 96); floor != 0 {
+		t.Errorf("Expected the shared grid's floor to stay at 0, got %.1f", floor)
+	}
+}
+
+func TestTeleportTrigger(t *testing.T) {
+	arena := newTriggerArena(t)
+	placeTriggerPlayer(arena, 960, 96)
+	if !arena.UpdatePlayerPosition(1, 1010, 96) {
+		t.Fatal("Expected the move onto the pad to succeed")
+	}
+	if player := arena.GetPlayer(1); player.X != 20*64+32 || player.Y != 96 {
+		t.Errorf("Expected the player at block (20, 1), got (%.0f, %.0f)", player.X, player.Y)
+	}
+}
+
+func TestTriggerChainsAndChecks(t *testing.T) {
+	arena := newTriggerArena(t)
+	placeTriggerPlayer(arena, 600, 96)
+
+	// The lever opens the door
+	if err := arena.ActivateTrigger(1, 4); err != nil {
+		t.Fatalf("ActivateTrigger failed: %v", err)
+	}
+	if !arena.Triggers[4].On || !arena.Triggers[1].On {
+		t.Error("Expected the lever and the door it is chained to to be on")
+	}
+
+	if err := arena.ActivateTrigger(1, 2); err == nil {
+		t.Error("Expected the elevator switch to be out of reach")
+	}
+	if err := arena.ActivateTrigger(1, 5); err == nil {
+		t.Error("Expected the disabled door to refuse")
+	}
+	if err := arena.ActivateTrigger(1, 9); err == nil {
+		t.Error("Expected an unknown trigger to fail")
+	}
+	arena.Players[1].Dead = true
+	if err := arena.ActivateTrigger(1, 3); err == nil {
+		t.Error("Expected the dead not to use triggers")
+	}
+}
+
+func TestTriggerSnapshotAndReset(t *testing.T) {
+	arena := newTriggerArena(t)
+	placeTriggerPlayer(arena, 600, 96)
+	arena.ActivateTrigger(1, 1)
+
+	// The snapshot's triggers come last but for the thin count: the trigger count and
+	// each trigger's ID, type, on and enabled flags and position
+	data := BuildArenaSnapshotPacket(arena).Data
+	triggers := data[len(data)-2-5*15-2:]
+	if count := binary.LittleEndian.Uint16(triggers); count != 5 {
+		t.Fatalf("Expected 5 triggers in the snapshot, got %d", count)
+	}
+	var door struct {
+		ID       int32
+		Type     uint8
+		On       bool
+		Enabled  bool
+		Position float64
+	}
+	binary.Read(bytes.NewReader(triggers[2:]), binary.LittleEndian, &door)
+	if door.ID != 1 || door.Type != uint8(TriggerDoor) || !door.On || !door.Enabled {
+		t.Errorf("Unexpected door state %+v", door)
+	}
+
+	arena.mu.Lock()
+	arena.resetLocked()
+	arena.mu.Unlock()
+	if arena.Triggers[1].On || arena.Triggers[1].Position != 0 {
+		t.Error("Expected the reset arena's door to be closed")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// minWallThickness keeps zero-thickness walls (such as Spell Shield) solid
+const minWallThickness = 8.0
+
+// Wall represents a spell-created obstacle placed in an arena
+type Wall struct {
+	ID           int64
+	SpellID      int
+	OwnerID      int
+	Team         Team
+	Element      SpellElementType
+	X, Y         float64 // centre of the wall
+	Angle        float64 // caster facing in radians; the wall runs across it
+	Length       float64
+	Thick        float64
+	Height       float64
+	HitPoints    int
+	MaxHitPoints int
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// NewWall places a wall in front of a caster facing angle, offset by the spell's cast distance
+func NewWall(spell *Spell, ownerID int, team Team, x, y, angle float64) *Wall {
+	thick := math.Max(spell.Thick, minWallThickness)
+	centerX, centerY := wallCenter(spell, x, y, angle)
+	now := time.Now()
+
+	wall := &Wall{
+		ID:           generateSpellID(),
+		SpellID:      spell.ID,
+		OwnerID:      ownerID,
+		Team:         team,
+		Element:      spell.ElementType,
+		X:            centerX,
+		Y:            centerY,
+		Angle:        angle,
+		Length:       spell.Length,
+		Thick:        thick,
+		Height:       spell.Height,
+		HitPoints:    spell.HitPoints,
+		MaxHitPoints: spell.HitPoints,
+		CreatedAt:    now,
+	}
+	if spell.Duration > 0 {
+		wall.ExpiresAt = now.Add(spell.Duration)
+	}
+	return wall
+}
+
+// wallCenter returns where a wall cast from a point facing angle is centred
+func wallCenter(spell *Spell, x, y, angle float64) (float64, float64) {
+	offset := spell.CastDistance + math.Max(spell.Thick, minWallThickness)/2
+	return x + offset*math.Cos(angle), y + offset*math.Sin(angle)
+}
+
+// toLocal converts a world point into the wall's frame: along the facing and across it
+func (w *Wall) toLocal(x, y float64) (float64, float64) {
+	dx, dy := x-w.X, y-w.Y
+	cos, sin := math.Cos(w.Angle), math.Sin(w.Angle)
+	return dx*cos + dy*sin, -dx*sin + dy*cos
+}
+
+// Contains reports whether a circle of the given radius overlaps the wall
+func (w *Wall) Contains(x, y, radius float64) bool {
+	along, across := w.toLocal(x, y)
+	return math.Abs(along) <= w.Thick/2+radius && math.Abs(across) <= w.Length/2+radius
+}
+
+// IntersectSegment returns the fraction along the segment where it first enters the wall
+func (w *Wall) IntersectSegment(x1, y1, x2, y2 float64) (float64, bool) {
+	a1, c1 := w.toLocal(x1, y1)
+	a2, c2 := w.toLocal(x2, y2)
+
+	tMin, tMax := 0.0, 1.0
+	slabs := [][3]float64{
+		{a1, a2 - a1, w.Thick / 2},
+		{c1, c2 - c1, w.Length / 2},
+	}
+	for _, s := range slabs {
+		origin, delta, half := s[0], s[1], s[2]
+		if delta == 0 {
+			if math.Abs(origin) > half {
+				return 0, false
+			}
+			continue
+		}
+		t1 := (-half - origin) / delta
+		t2 := (half - origin) / delta
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+	return tMin, true
+}
+
+// IsExpired checks if the wall's lifetime has run out
+func (w *Wall) IsExpired(now time.Time) bool {
+	return !w.ExpiresAt.IsZero() && now.After(w.ExpiresAt)
+}
+
+// TakeDamage applies spell damage using the element rules from MageServer's DoWallDamage.
+// It returns true when the wall is destroyed.
+func (w *Wall) TakeDamage(spell *Spell, damage int) bool {
+	if spell != nil && spell.ElementType != SpellElementNone {
+		switch {
+		case spell.ElementType == SpellElementVoid && w.Element != SpellElementVoid:
+			damage *= 2
+		case spell.ElementType == w.Element:
+			damage = 0
+		case spell.Type == SpellTypeProjectile:
+			damage = int(math.Ceil(float64(damage) * 0.6))
+		}
+	}
+	if damage <= 0 {
+		return false
+	}
+
+	w.HitPoints -= damage
+	return w.HitPoints <= 0
+}
+
+// AddWall places a wall in the arena
+func (a *Arena) AddWall(wall *Wall) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Walls == nil {
+		a.Walls = make(map[int64]*Wall)
+	}
+	a.Walls[wall.ID] = wall
+	a.spatialLocked().Update(EntityKey{EntityWall, wall.ID}, wall.X, wall.Y, wall.boundingRadius())
+}
+
+// boundingRadius returns the radius of the circle around the wall's centre that holds it
+func (w *Wall) boundingRadius() float64 {
+	return math.Hypot(w.Length/2, w.Thick/2)
+}
+
+// ValidateWall checks that a wall cast from a point facing angle lands where the caster
+// can see, clear of solid geometry
+func (a *Arena) ValidateWall(spell *Spell, x, y, angle float64) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.Geometry == nil {
+		return nil
+	}
+	centerX, centerY := wallCenter(spell, x, y, angle)
+	if _, hit := a.Geometry.TraceSegment(x, y, centerX, centerY); hit {
+		return fmt.Errorf("wall at (%.0f, %.0f) is behind solid geometry", centerX, centerY)
+	}
+	if a.Geometry.IsSolid(centerX, centerY, 0) {
+		return fmt.Errorf("wall at (%.0f, %.0f) is inside solid geometry", centerX, centerY)
+	}
+	return nil
+}
+
+// RemoveWall removes a wall from the arena
+func (a *Arena) RemoveWall(id int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.removeWallLocked(id)
+}
+
+// removeWallLocked removes a wall from the arena and its spatial index; the caller must
+// hold a.mu
+func (a *Arena) removeWallLocked(id int64) {
+	delete(a.Walls, id)
+	a.spatialLocked().Remove(EntityKey{EntityWall, id})
+}
+
+// GetWalls returns the walls currently standing in the arena
+func (a *Arena) GetWalls() []*Wall {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	walls := make([]*Wall, 0, len(a.Walls))
+	for _, wall := range a.Walls {
+		walls = append(walls, wall)
+	}
+	return walls
+}
+
+// DamageWall applies spell damage to a wall, removing it once destroyed
+func (a *Arena) DamageWall(id int64, spell *Spell, damage int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.damageWallLocked(id, spell, damage)
+}
+
+// damageWallLocked damages a wall; the caller must hold a.mu
+func (a *Arena) damageWallLocked(id int64, spell *Spell, damage int) bool {
+	wall, exists := a.Walls[id]
+	if !exists {
+		return false
+	}
+	if wall.TakeDamage(spell, damage) {
+		a.removeWallLocked(id)
+		return true
+	}
+	return false
+}
+
+// expireWallsLocked removes walls whose duration has elapsed; the caller must hold a.mu
+func (a *Arena) expireWallsLocked(now time.Time) {
+	for id, wall := range a.Walls {
+		if wall.IsExpired(now) {
+			a.removeWallLocked(id)
+		}
+	}
+}
+
+// wallAtLocked returns a wall overlapping the circle; the caller must hold a.mu
+func (a *Arena) wallAtLocked(x, y, radius float64) *Wall {
+	var found *Wall
+	a.spatialLocked().QueryRadius(EntityWall, x, y, radius, func(key EntityKey) {
+		if wall, exists := a.Walls[key.ID]; exists && found == nil && wall.Contains(x, y, radius) {
+			found = wall
+		}
+	})
+	return found
+}
+
+// firstWallOnSegmentLocked returns the nearest wall crossed by a segment; the caller must hold a.mu
+func (a *Arena) firstWallOnSegmentLocked(x1, y1, x2, y2 float64) (*Wall, float64) {
+	var nearest *Wall
+	nearestT := math.MaxFloat64
+	a.spatialLocked().QuerySegment(EntityWall, x1, y1, x2, y2, 0, func(key EntityKey) {
+		wall, exists := a.Walls[key.ID]
+		if !exists {
+			return
+		}
+		if t, hit := wall.IntersectSegment(x1, y1, x2, y2); hit && t < nearestT {
+			nearest, nearestT = wall, t
+		}
+	})
+	return nearest, nearestT
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func testWallSpell() *Spell {
+	return &Spell{
+		ID:           100,
+		Name:         "Test Wall",
+		Type:         SpellTypeWall,
+		ElementType:  SpellElementEarth,
+		HitPoints:    50,
+		Length:       128,
+		Thick:        16,
+		Height:       64,
+		CastDistance: 32,
+		Duration:     10 * time.Second,
+		Cooldown:     time.Second,
+	}
+}
+
+func newTestArena(id int) *Arena {
+	return &Arena{
+		ID:         id,
+		Name:       "Test Arena",
+		MaxPlayers: 8,
+		Players:    make(map[int]*ArenaPlayer),
+		State:      ArenaStateWaiting,
+	}
+}
+
+func TestNewWallPlacement(t *testing.T) {
+	wall := NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0)
+
+	// Offset along the facing by cast distance plus half the thickness
+	if math.Abs(wall.X-40) > 1e-9 || math.Abs(wall.Y) > 1e-9 {
+		t.Errorf("Expected wall centre at (40, 0), got (%.2f, %.2f)", wall.X, wall.Y)
+	}
+	if !wall.Contains(40, 60, 0) {
+		t.Error("Expected wall to extend across the facing")
+	}
+	if wall.Contains(80, 0, 0) {
+		t.Error("Expected wall to be thin along the facing")
+	}
+	if wall.HitPoints != 50 || wall.ExpiresAt.IsZero() {
+		t.Errorf("Expected health and lifetime from spell, got %+v", wall)
+	}
+}
+
+func TestWallSegmentIntersection(t *testing.T) {
+	wall := NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0)
+
+	if tHit, hit := wall.IntersectSegment(0, 0, 100, 0); !hit || math.Abs(tHit-0.32) > 1e-9 {
+		t.Errorf("Expected hit at t=0.32, got %v %.3f", hit, tHit)
+	}
+	if _, hit := wall.IntersectSegment(0, 100, 100, 100); hit {
+		t.Error("Expected segment past the wall end to miss")
+	}
+}
+
+func TestWallDamageElements(t *testing.T) {
+	fire := &Spell{Type: SpellTypeProjectile, ElementType: SpellElementFire}
+	earth := &Spell{Type: SpellTypeProjectile, ElementType: SpellElementEarth}
+	void := &Spell{Type: SpellTypeBolt, ElementType: SpellElementVoid}
+
+	wall := NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0)
+	if wall.TakeDamage(earth, 100) || wall.HitPoints != 50 {
+		t.Errorf("Expected same element to do no damage, health %d", wall.HitPoints)
+	}
+	if wall.TakeDamage(fire, 10) || wall.HitPoints != 44 {
+		t.Errorf("Expected projectiles to do 60%% damage, health %d", wall.HitPoints)
+	}
+	if !wall.TakeDamage(void, 22) {
+		t.Errorf("Expected void to double damage and destroy wall, health %d", wall.HitPoints)
+	}
+}
+
+func TestWallBlocksMovement(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddWall(NewWall(testWallSpell(), 2, TeamOrder, 0, 0, 0))
+
+	if arena.UpdatePlayerPosition(1, 40, 0) {
+		t.Error("Expected move into wall to be blocked")
+	}
+	if p := arena.GetPlayer(1); p.X != 0 || p.Y != 0 {
+		t.Errorf("Expected player to stay at origin, got (%.2f, %.2f)", p.X, p.Y)
+	}
+	if !arena.UpdatePlayerPosition(1, -50, 0) {
+		t.Error("Expected move away from wall to succeed")
+	}
+}
+
+func TestWallExpiry(t *testing.T) {
+	arena := newTestArena(1)
+	wall := NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0)
+	wall.ExpiresAt = time.Now().Add(-time.Second)
+	arena.AddWall(wall)
+
+	UpdateArena(arena)
+
+	if len(arena.GetWalls()) != 0 {
+		t.Error("Expected expired wall to be removed")
+	}
+}
+
+func TestCastWallAndProjectileHit(t *testing.T) {
+	gs := NewGameState()
+	gs.SpellSystem.SpellManager.LoadSpells([]*Spell{testWallSpell()})
+	arena := gs.ArenaManager.CreateArena(1, "Test Arena", 8, 1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.UpdatePlayerPosition(2, 200, 0)
+	addTestCaster(gs, 1, 100, 4)
+
+	if _, err := gs.CastSpell(1, 100, 200, 0, 0); err != nil {
+		t.Fatalf("Failed to cast wall: %v", err)
+	}
+	walls := arena.GetWalls()
+	if len(walls) != 1 {
+		t.Fatalf("Expected 1 wall, got %d", len(walls))
+	}
+
+	// Ice Blast (built-in spell 4) travels toward player 2 and is stopped by the wall
+	if _, err := gs.CastSpell(1, 4, 200, 0, 2); err != nil {
+		t.Fatalf("Failed to cast ice blast: %v", err)
+	}
+	for i := 0; i < 60; i++ {
+		UpdateArenas(gs)
+	}
+
+	if p := arena.GetPlayer(2); p.Health != 100 {
+		t.Errorf("Expected wall to absorb the projectile, player health %d", p.Health)
+	}
+	if walls[0].HitPoints >= 50 {
+		t.Errorf("Expected wall to take damage, health %d", walls[0].HitPoints)
+	}
+}
+
+func TestArenaSnapshotIncludesWalls(t *testing.T) {
+	arena := newTestArena(1)
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddWall(NewWall(testWallSpell(), 1, TeamChaos, 0, 0, 0))
+
+	packet := BuildArenaSnapshotPacket(arena)
+	if packet.Type != PacketArenaSnapshot {
+		t.Errorf("Expected snapshot packet type, got %d", packet.Type)
+	}
+	// header 5 + player count 2 + player 33 + wall count 2 + wall 48 + rune count 2 + trigger count 2 + thin count 2
+	if len(packet.Data) != 96 {
+		t.Errorf("Expected 96 bytes of snapshot data, got %d", len(packet.Data))
+	}
+}
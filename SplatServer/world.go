@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// blockSize is the width of one grid block in world units; World.dat positions are in blocks
+const blockSize = 64.0
+
+// worldTeamSections maps the team sections in World.dat to teams
+var worldTeamSections = map[string]Team{
+	"noteam":  TeamNone,
+	"chaos":   TeamChaos,
+	"balance": TeamBalance,
+	"order":   TeamOrder,
+}
+
+// SpawnPoint is a position and facing in world units and radians
+type SpawnPoint struct {
+	X, Y  float64
+	Angle float64
+}
+
+// TeamSpawn holds where a team enters the grid and where its dead are raised
+type TeamSpawn struct {
+	Start SpawnPoint
+	Raise SpawnPoint
+}
+
+// worldAlignments maps shrine alignments in World.dat to teams
+var worldAlignments = map[string]Team{
+	"chaos":   TeamChaos,
+	"balance": TeamBalance,
+	"order":   TeamOrder,
+}
+
+// World holds the per-grid settings from a grid's World.dat
+type World struct {
+	Spawns  map[Team]TeamSpawn
+	Shrines []Shrine // one per team, positioned from Grid.dat when it is loaded
+	Pools   []Pool   // earthblood pools, positioned from Grid.dat when it is loaded
+}
+
+// LoadWorldFile parses a grid's World.dat file
+func LoadWorldFile(path string) (*World, error) {
+	ini, err := LoadIniFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseWorld(ini)
+}
+
+// parseWorld reads the team sections of a World.dat file
+func parseWorld(ini *IniFile) (*World, error) {
+	world := &World{Spawns: make(map[Team]TeamSpawn)}
+	for section, team := range worldTeamSections {
+		if !ini.HasSection(section) {
+			continue
+		}
+		world.Spawns[team] = TeamSpawn{
+			Start: worldSpawnPoint(ini, section, "start"),
+			Raise: worldSpawnPoint(ini, section, "raise"),
+		}
+	}
+	if len(world.Spawns) == 0 {
+		return nil, fmt.Errorf("no team sections defined")
+	}
+
+	shrines, err := worldShrines(ini)
+	if err != nil {
+		return nil, err
+	}
+	world.Shrines = shrines
+	world.Pools = worldPools(ini)
+	return world, nil
+}
+
+// worldShrines reads the [shrineNN] sections listed by [shrinedefs]
+func worldShrines(ini *IniFile) ([]Shrine, error) {
+	count := ini.Int("shrinedefs", "numshrines", 0)
+	shrines := make([]Shrine, 0, count)
+	for id := 0; id < count; id++ {
+		section := fmt.Sprintf("shrine%02d", id)
+		alignment := strings.ToLower(ini.String(section, "alignment", ""))
+		team, ok := worldAlignments[alignment]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown alignment %q", section, alignment)
+		}
+		shrines = append(shrines, Shrine{
+			ID:      id,
+			Team:    team,
+			Power:   ini.Int(section, "power", 0),
+			MaxBias: defaultObjectiveBias,
+			Bias:    clampBias(ini.Int(section, "bias", defaultObjectiveBias), defaultObjectiveBias),
+		})
+	}
+	return shrines, nil
+}
+
+// worldPools reads the [earthbloodNN] sections listed by [earthblooddefs]
+func worldPools(ini *IniFile) []Pool {
+	count := ini.Int("earthblooddefs", "numearthblood", 0)
+	pools := make([]Pool, 0, count)
+	for id := 0; id < count; id++ {
+		pools = append(pools, Pool{
+			ID:      id,
+			Power:   ini.Int(fmt.Sprintf("earthblood%02d", id), "power", 0),
+			MaxBias: defaultObjectiveBias,
+		})
+	}
+	return pools
+}
+
+// worldSpawnPoint reads a prefix's x, y and a keys, placing the point at the centre of its block
+func worldSpawnPoint(ini *IniFile, section, prefix string) SpawnPoint {
+	return SpawnPoint{
+		X:     float64(ini.Int(section, prefix+"x", 0))*blockSize + blockSize/2,
+		Y:     float64(ini.Int(section, prefix+"y", 0))*blockSize + blockSize/2,
+		Angle: ini.Float(section, prefix+"a", 0) * math.Pi / 180,
+	}
+}
+
+// teamSpawn returns a team's spawn, falling back to the unaligned one
+func (w *World) teamSpawn(team Team) (TeamSpawn, bool) {
+	if spawn, ok := w.Spawns[team]; ok {
+		return spawn, true
+	}
+	spawn, ok := w.Spawns[TeamNone]
+	return spawn, ok
+}
+
+// StartPoint returns where a team's players enter the grid
+func (w *World) StartPoint(team Team) (SpawnPoint, bool) {
+	spawn, ok := w.teamSpawn(team)
+	return spawn.Start, ok
+}
+
+// RaisePoint returns where a team's dead players come back to life
+func (w *World) RaisePoint(team Team) (SpawnPoint, bool) {
+	spawn, ok := w.teamSpawn(team)
+	return spawn.Raise, ok
+}
+
+// loadGridWorld loads the World.dat for a grid, returning nil when it is unavailable
+func loadGridWorld(gridID int) *World {
+	path, err := findContentFile(contentDir(), "Grids", fmt.Sprintf("Grid%02d", gridID), "World.dat")
+	if err != nil {
+		fmt.Printf("SplatServer: World.dat for grid %d not found: %v\n", gridID, err)
+		return nil
+	}
+
+	world, err := LoadWorldFile(path)
+	if err != nil {
+		fmt.Printf("SplatServer: Failed to load %s: %v\n", path, err)
+		return nil
+	}
+	return world
+}
+
+// spawnLocked returns the spawn for a team in this arena, or false when the arena has
+// no World.dat; the caller must hold a.mu
+func (a *Arena) spawnLocked(team Team) (TeamSpawn, bool) {
+	if a.World == nil {
+		return TeamSpawn{}, false
+	}
+	return a.World.teamSpawn(team)
+}
+
+// loadArenaWorlds loads each arena's grid settings and block map, once per grid, giving
+// each arena its own copy of the grid for its doors, elevators and breakable thins. Arenas without a
+// World.dat start players at the origin and raise their dead where they fell
+func loadArenaWorlds(gs *GameState) {
+	gs.ArenaManager.mu.RLock()
+	defer gs.ArenaManager.mu.RUnlock()
+
+	worlds := make(map[int]*World)
+	grids := make(map[int]*Grid)
+	for _, arena := range gs.ArenaManager.Arenas {
+		world, loaded := worlds[arena.GridID]
+		if !loaded {
+			world = loadGridWorld(arena.GridID)
+			grids[arena.GridID] = loadGrid(arena.GridID)
+			if world != nil && grids[arena.GridID] != nil {
+				world.placeObjectives(grids[arena.GridID])
+			}
+			worlds[arena.GridID] = world
+		}
+		arena.mu.Lock()
+		arena.World = world
+		if grid := grids[arena.GridID]; grid != nil {
+			arena.Grid = grid.arenaCopy()
+			arena.Geometry = arena.Grid
+			arena.resetGridLocked()
+		}
+		arena.mu.Unlock()
+	}
+}
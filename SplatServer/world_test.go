@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoadWorldFile(t *testing.T) {
+	path, err := findContentFile(contentDir(), "Grids", "Grid09", "World.dat")
+	if err != nil {
+		t.Fatalf("World.dat not found: %v", err)
+	}
+	world, err := LoadWorldFile(path)
+	if err != nil {
+		t.Fatalf("LoadWorldFile failed: %v", err)
+	}
+	if len(world.Spawns) != 4 {
+		t.Fatalf("Expected 4 team spawns, got %d", len(world.Spawns))
+	}
+
+	// [order] raisex=48 raisey=115 raisea=90, in blocks of 64 measured to the centre
+	raise, ok := world.RaisePoint(TeamOrder)
+	if !ok || raise.X != 48*64+32 || raise.Y != 115*64+32 || math.Abs(raise.Angle-math.Pi/2) > 1e-9 {
+		t.Errorf("Unexpected order raise point %+v", raise)
+	}
+	if start := world.Spawns[TeamChaos].Start; start.X != 77*64+32 || start.Y != 35*64+32 {
+		t.Errorf("Unexpected chaos start point %+v", start)
+	}
+
+	// Teams without a section use the unaligned spawn
+	delete(world.Spawns, TeamBalance)
+	if raise, _ := world.RaisePoint(TeamBalance); raise.X != 50*64+32 {
+		t.Errorf("Expected the noteam raise point, got %+v", raise)
+	}
+}
+
+func TestPlayersJoinAtTeamStart(t *testing.T) {
+	arena := newTestArena(1)
+	arena.World = &World{Spawns: map[Team]TeamSpawn{
+		TeamNone:  {Start: SpawnPoint{X: 96, Y: 96}, Raise: SpawnPoint{X: 160, Y: 160}},
+		TeamChaos: {Start: SpawnPoint{X: 4960, Y: 2272, Angle: -math.Pi / 2}, Raise: SpawnPoint{X: 3232, Y: 6944, Angle: math.Pi / 2}},
+	}}
+	arena.AddPlayer(1, TeamChaos)
+	arena.AddPlayer(2, TeamOrder)
+	arena.AddPlayer(3, TeamNone)
+
+	chaos := arena.GetPlayer(1)
+	if chaos.X != 4960 || chaos.Y != 2272 || chaos.Angle != -math.Pi/2 {
+		t.Errorf("Expected chaos to start at (4960, 2272) facing up, got %+v", chaos)
+	}
+	if order, unaligned := arena.GetPlayer(2), arena.GetPlayer(3); order.X != 96 || unaligned.X != 96 {
+		t.Errorf("Expected the noteam start for order and unaligned players, got %.0f and %.0f", order.X, unaligned.X)
+	}
+
+	arena.mu.Lock()
+	arena.killPlayerLocked(chaos, 0)
+	arena.updateDeadLocked(chaos.RespawnAt)
+	arena.mu.Unlock()
+	if chaos.X != 3232 || chaos.Y != 6944 || chaos.Angle != math.Pi/2 {
+		t.Errorf("Expected chaos to respawn at its raise point, got %+v", chaos)
+	}
+}